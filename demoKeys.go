@@ -0,0 +1,70 @@
+package main
+
+import (
+	"DMBLOCK_GO/third_faza"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// demoKeysFile keeps the demo key pairs next to the block store, so the coins of a chain
+// reopened from chainDataDir stay spendable after a restart.
+const demoKeysFile = "keys.pem"
+
+// loadKeyPairs reads the key pairs written by saveKeyPairs.
+func loadKeyPairs(path string) ([]KeyPair, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pairs := make([]KeyPair, 0)
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "PRIVATE KEY" {
+			return nil, errors.New("unexpected PEM block " + block.Type)
+		}
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		priv, ok := key.(third_faza.PrivateKey)
+		if !ok || third_faza.SchemeOf(third_faza.PublicKeyOf(priv)) == nil {
+			return nil, third_faza.ErrUnknownKeyType
+		}
+		pairs = append(pairs, KeyPair{
+			Name:       block.Headers["Name"],
+			PrivateKey: priv,
+			PublicKey:  third_faza.PublicKeyOf(priv),
+		})
+	}
+	return pairs, nil
+}
+
+// saveKeyPairs writes the key pairs as PKCS #8 PEM blocks carrying their names.
+// The file holds private keys, so only the owner may read it.
+func saveKeyPairs(path string, pairs []KeyPair) error {
+	data := make([]byte, 0)
+	for _, pair := range pairs {
+		der, err := x509.MarshalPKCS8PrivateKey(pair.PrivateKey)
+		if err != nil {
+			return err
+		}
+		data = append(data, pem.EncodeToMemory(&pem.Block{
+			Type:    "PRIVATE KEY",
+			Headers: map[string]string{"Name": pair.Name},
+			Bytes:   der,
+		})...)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
@@ -2,8 +2,7 @@ package main
 
 import (
 	"DMBLOCK_GO/third_faza"
-	"crypto/rand"
-	"crypto/rsa"
+	"errors"
 	"fmt"
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
@@ -13,20 +12,33 @@ import (
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/widget"
 	"image/color"
+	"io/fs"
+	"log"
+	"path/filepath"
 	"strconv"
 	"strings"
 )
 
 type KeyPair struct {
 	Name       string
-	PrivateKey *rsa.PrivateKey
-	PublicKey  *rsa.PublicKey
+	PrivateKey third_faza.PrivateKey
+	PublicKey  third_faza.PublicKey
 }
 
+// chainDataDir is where the block store keeps the chain between runs.
+const chainDataDir = "chaindata"
+
+// demoCoinbaseMaturity replaces the default coinbase maturity, because blocks are mined by hand
+// here and a reward should become spendable after a few clicks rather than a hundred.
+const demoCoinbaseMaturity = 3
+
 // Global variables
 var (
 	blockchain *third_faza.Blockchain
 	keyPairs   []KeyPair
+	// demoKeysUnreadable is set when the saved keys could not be loaded; the file is then
+	// never overwritten.
+	demoKeysUnreadable bool
 
 	txInputs  []TxInputData
 	txOutputs []TxOutputData
@@ -49,8 +61,8 @@ var (
 )
 
 func createNewUserPopup(w fyne.Window) {
-	priv, _ := rsa.GenerateKey(rand.Reader, 1024)
-	pub := &priv.PublicKey
+	priv, _ := third_faza.GenerateKey()
+	pub := third_faza.PublicKeyOf(priv)
 
 	newUser := KeyPair{
 		Name:       fmt.Sprintf("User%d", userCounter),
@@ -60,11 +72,16 @@ func createNewUserPopup(w fyne.Window) {
 
 	keyPairs = append(keyPairs, newUser)
 	userCounter++
+	if !demoKeysUnreadable {
+		if err := saveKeyPairs(filepath.Join(chainDataDir, demoKeysFile), keyPairs); err != nil {
+			log.Printf("demo keys not saved, coins cannot be spent after a restart: %v", err)
+		}
+	}
 
 	rebuildAddTxScreen()
 	rebuildMineBlockScreen()
 
-	dialog.ShowInformation("✅ Success", fmt.Sprintf("Created new user: %s", newUser.Name), w)
+	dialog.ShowInformation("✅ Success", fmt.Sprintf("Created new user: %s\nAddress: %s", newUser.Name, third_faza.AddressOf(pub)), w)
 }
 
 // ===================== STYLED BLOCKS / TREE VIEW =====================
@@ -211,10 +228,10 @@ func getGenesisNode() *third_faza.BlockNode {
 type UTXOInfo struct {
 	TxHashHex string
 	Index     int
-	Value     float64
+	Value     third_faza.Amount
 }
 
-func getUTXOsForKey(pubKey *rsa.PublicKey) []UTXOInfo {
+func getUTXOsForKey(pubKey third_faza.PublicKey) []UTXOInfo {
 	results := []UTXOInfo{}
 
 	utxoPool := blockchain.GetUTXOPoolAtMaxHeight()
@@ -237,8 +254,8 @@ func getUTXOsForKey(pubKey *rsa.PublicKey) []UTXOInfo {
 			continue // or handle error
 		}
 
-		// If it matches the user's pubKey
-		if txOut.Address == pubKey {
+		// If the user's key can spend it (paid to the key or to its address) or cosign it
+		if txOut.SignableBy(pubKey) {
 			results = append(results, UTXOInfo{
 				TxHashHex: hashHex,
 				Index:     index,
@@ -255,12 +272,24 @@ type TxInputData struct {
 	Index  int
 }
 
+// TxOutputData describes an output to a single recipient address, or a multisig output when
+// MultiSigNames is set.
 type TxOutputData struct {
 	RecipientName string
-	Amount        float64
+	Address       string
+	MultiSigNames []string
+	RequiredSigs  int
+	Amount        third_faza.Amount
+}
+
+func (outp TxOutputData) String() string {
+	if len(outp.MultiSigNames) > 0 {
+		return fmt.Sprintf("Output: %d-of-%d [%s] => %s", outp.RequiredSigs, len(outp.MultiSigNames), strings.Join(outp.MultiSigNames, ", "), outp.Amount)
+	}
+	return fmt.Sprintf("Output: %s => %s", outp.RecipientName, outp.Amount)
 }
 
-func parseUTXOString(sel string) (hashHex string, index int, amount float64, err error) {
+func parseUTXOString(sel string) (hashHex string, index int, amount third_faza.Amount, err error) {
 	// Expected format: "Tx:abcd12[1] => 10.0 coins"
 	// 1. Remove the "Tx:" prefix.
 	if !strings.HasPrefix(sel, "Tx:") {
@@ -294,7 +323,7 @@ func parseUTXOString(sel string) (hashHex string, index int, amount float64, err
 	amountStr := s[arrowIndex+2:]
 	// Remove "coins" suffix and trim spaces.
 	amountStr = strings.TrimSpace(strings.TrimSuffix(amountStr, "coins"))
-	amount, err = strconv.ParseFloat(amountStr, 64)
+	amount, err = third_faza.ParseAmount(amountStr)
 	if err != nil {
 		return "", 0, 0, fmt.Errorf("invalid amount: %w", err)
 	}
@@ -304,6 +333,13 @@ func parseUTXOString(sel string) (hashHex string, index int, amount float64, err
 func buildAddTransactionScreen() fyne.CanvasObject {
 	inputsListContainer := container.NewVBox()
 	outputsListContainer := container.NewVBox()
+	refreshOutputs := func() {
+		outputsListContainer.Objects = nil
+		for _, outp := range txOutputs {
+			outputsListContainer.Add(widget.NewLabel(outp.String()))
+		}
+		outputsListContainer.Refresh()
+	}
 
 	// --------------------- FROM KEY SELECT ---------------------
 	var fromKeySelect *widget.Select
@@ -314,6 +350,14 @@ func buildAddTransactionScreen() fyne.CanvasObject {
 		keyNames[i] = kp.Name
 	}
 
+	fromAddressLabel := widget.NewLabel("")
+	fromAddressLabel.Wrapping = fyne.TextWrapBreak
+	copyAddressBtn := widget.NewButton("Copy Address", func() {
+		if fromAddressLabel.Text != "" {
+			mainWindow.Clipboard().SetContent(fromAddressLabel.Text)
+		}
+	})
+
 	fromKeySelect = widget.NewSelect(keyNames, func(chosen string) {
 		// When a sender is selected, find its key pair.
 		var fromKP *KeyPair
@@ -326,12 +370,13 @@ func buildAddTransactionScreen() fyne.CanvasObject {
 		if fromKP == nil {
 			return
 		}
+		fromAddressLabel.SetText(third_faza.AddressOf(fromKP.PublicKey))
 		// Get UTXOs for this key.
 		utxos := getUTXOsForKey(fromKP.PublicKey)
 		utxoNames := []string{}
 		for _, u := range utxos {
 			// Example. "Tx:abcd12[1] => 10.0 coins"
-			s := fmt.Sprintf("Tx:%s[%d] => %.2f coins", u.TxHashHex[:6], u.Index, u.Value)
+			s := fmt.Sprintf("Tx:%s[%d] => %s coins", u.TxHashHex[:6], u.Index, u.Value)
 			utxoNames = append(utxoNames, s)
 			shortTxHashToLongTxHash[u.TxHashHex[:6]] = u.TxHashHex
 		}
@@ -354,7 +399,7 @@ func buildAddTransactionScreen() fyne.CanvasObject {
 
 		var hashHex string
 		var index int
-		var amount float64
+		var amount third_faza.Amount
 		fmt.Println(utxoSelect.Selected)
 		hashHex, index, amount, err := parseUTXOString(sel)
 		if err != nil {
@@ -365,7 +410,7 @@ func buildAddTransactionScreen() fyne.CanvasObject {
 			TxHash: shortTxHashToLongTxHash[hashHex], // У вас буде повнаhash
 			Index:  index,
 		})
-		utxoAddStatus.SetText(fmt.Sprintf("Added input: %s[%d] (%.2f coins)", hashHex, index, amount))
+		utxoAddStatus.SetText(fmt.Sprintf("Added input: %s[%d] (%s coins)", hashHex, index, amount))
 
 		inputsListContainer.Objects = nil
 		for _, inp := range txInputs {
@@ -375,72 +420,125 @@ func buildAddTransactionScreen() fyne.CanvasObject {
 	})
 
 	// --------------------- OUTPUT SECTION ---------------------
-	// Recipient Key
-	toKeySelect := widget.NewSelect(keyNames, func(chosen string) {})
-	toKeySelect.PlaceHolder = "Recipient Key"
+	// Recipient address, pasted or filled in from one of our keys
+	toAddressEntry := widget.NewEntry()
+	toAddressEntry.SetPlaceHolder("Recipient Address")
+	toKeySelect := widget.NewSelect(keyNames, func(chosen string) {
+		for i := range keyPairs {
+			if keyPairs[i].Name == chosen {
+				toAddressEntry.SetText(third_faza.AddressOf(keyPairs[i].PublicKey))
+			}
+		}
+	})
+	toKeySelect.PlaceHolder = "Or pick a Recipient Key"
 
 	outputAmountEntry := widget.NewEntry()
 	outputAmountEntry.SetPlaceHolder("Amount (e.g. 5.0)")
 
 	addOutputStatus := widget.NewLabel("")
 	addOutputBtn := widget.NewButton("Add Output", func() {
-		if toKeySelect.Selected == "" {
-			addOutputStatus.SetText("Error: select recipient key")
+		address := strings.TrimSpace(toAddressEntry.Text)
+		if _, err := third_faza.DecodeAddress(address); err != nil {
+			addOutputStatus.SetText("Error: invalid recipient address")
 			return
 		}
+		recipientName := address[:8] + "…"
+		for i := range keyPairs {
+			if third_faza.AddressOf(keyPairs[i].PublicKey) == address {
+				recipientName = keyPairs[i].Name
+			}
+		}
 		amtStr := outputAmountEntry.Text
 		if amtStr == "" {
 			addOutputStatus.SetText("Error: enter amount")
 			return
 		}
-		amt, err := strconv.ParseFloat(amtStr, 64)
+		amt, err := third_faza.ParseAmount(amtStr)
 		if err != nil || amt <= 0 {
 			addOutputStatus.SetText("Error: invalid amount")
 			return
 		}
 		txOutputs = append(txOutputs, TxOutputData{
-			RecipientName: toKeySelect.Selected,
+			RecipientName: recipientName,
+			Address:       address,
 			Amount:        amt,
 		})
-		addOutputStatus.SetText(fmt.Sprintf("Added Output: %s => %.2f", toKeySelect.Selected, amt))
-
-		outputsListContainer.Objects = nil
-		for _, outp := range txOutputs {
-			outputsListContainer.Add(widget.NewLabel(fmt.Sprintf("Output: %s => %.2f", outp.RecipientName, outp.Amount)))
-		}
-		outputsListContainer.Refresh()
+		addOutputStatus.SetText(fmt.Sprintf("Added Output: %s => %s", recipientName, amt))
+		refreshOutputs()
 
 		// clear
 		outputAmountEntry.SetText("")
+		toAddressEntry.SetText("")
 		toKeySelect.ClearSelected()
 	})
 
-	// --------------------- CREATE & SIGN BUTTON ---------------------
-	statusLabel := widget.NewLabel("")
-	createTxBtn := widget.NewButton("Create & Sign TX", func() {
-		if fromKeySelect.Selected == "" {
-			statusLabel.SetText("No From Key selected!")
+	// Multisig output: any M of the checked keys can spend it.
+	multiSigKeysCheck := widget.NewCheckGroup(keyNames, func(chosen []string) {})
+	requiredSigsEntry := widget.NewEntry()
+	requiredSigsEntry.SetPlaceHolder("Required signatures (e.g. 2)")
+	multiSigAmountEntry := widget.NewEntry()
+	multiSigAmountEntry.SetPlaceHolder("Amount (e.g. 5.0)")
+
+	addMultiSigStatus := widget.NewLabel("")
+	addMultiSigBtn := widget.NewButton("Add Multisig Output", func() {
+		names := multiSigKeysCheck.Selected
+		if len(names) == 0 {
+			addMultiSigStatus.SetText("Error: check at least one key")
 			return
 		}
-		if len(txInputs) == 0 {
-			statusLabel.SetText("No inputs added!")
+		required, err := strconv.Atoi(strings.TrimSpace(requiredSigsEntry.Text))
+		if err != nil || required < 1 || required > len(names) {
+			addMultiSigStatus.SetText(fmt.Sprintf("Error: required signatures must be 1..%d", len(names)))
 			return
 		}
-		if len(txOutputs) == 0 {
-			statusLabel.SetText("No outputs added!")
+		amt, err := third_faza.ParseAmount(multiSigAmountEntry.Text)
+		if err != nil || amt <= 0 {
+			addMultiSigStatus.SetText("Error: invalid amount")
 			return
 		}
+		outp := TxOutputData{
+			MultiSigNames: append([]string(nil), names...),
+			RequiredSigs:  required,
+			Amount:        amt,
+		}
+		txOutputs = append(txOutputs, outp)
+		addMultiSigStatus.SetText("Added " + outp.String())
+		refreshOutputs()
 
-		// 1) Знайти fromKeyPair
-		var fromKP *KeyPair
+		// clear
+		multiSigAmountEntry.SetText("")
+		requiredSigsEntry.SetText("")
+		multiSigKeysCheck.SetSelected(nil)
+	})
+
+	// --------------------- CREATE & SIGN BUTTON ---------------------
+	statusLabel := widget.NewLabel("")
+	selectedFromKey := func() *KeyPair {
 		for i := range keyPairs {
 			if keyPairs[i].Name == fromKeySelect.Selected {
-				fromKP = &keyPairs[i]
+				return &keyPairs[i]
 			}
 		}
-		if fromKP == nil {
-			statusLabel.SetText("FromKey not found.")
-			return
+		return nil
+	}
+	clearInputsAndOutputs := func() {
+		txInputs = nil
+		txOutputs = nil
+		// Clear the lists.
+		inputsListContainer.Objects = nil
+		outputsListContainer.Objects = nil
+		inputsListContainer.Refresh()
+		outputsListContainer.Refresh()
+	}
+	// buildTx creates the unsigned transaction from the added inputs and outputs.
+	buildTx := func() *third_faza.Transaction {
+		if len(txInputs) == 0 {
+			statusLabel.SetText("No inputs added!")
+			return nil
+		}
+		if len(txOutputs) == 0 {
+			statusLabel.SetText("No outputs added!")
+			return nil
 		}
 
 		// 2) Створити транзакцію
@@ -456,35 +554,193 @@ func buildAddTransactionScreen() fyne.CanvasObject {
 
 		// 4) Додати outputs
 		for _, outp := range txOutputs {
-			var rKP *KeyPair
-			for i := range keyPairs {
-				if keyPairs[i].Name == outp.RecipientName {
-					rKP = &keyPairs[i]
+			if len(outp.MultiSigNames) > 0 {
+				addresses := make([]third_faza.PublicKey, 0, len(outp.MultiSigNames))
+				for _, name := range outp.MultiSigNames {
+					for i := range keyPairs {
+						if keyPairs[i].Name == name {
+							addresses = append(addresses, keyPairs[i].PublicKey)
+						}
+					}
 				}
+				if len(addresses) != len(outp.MultiSigNames) {
+					statusLabel.SetText("Multisig key not found.")
+					return nil
+				}
+				tx.AddMultisigOutput(third_faza.NewMOfNMultiSigOutput(outp.Amount, outp.RequiredSigs, addresses))
+				continue
 			}
-			if rKP == nil {
-				statusLabel.SetText("Output recipient not found: " + outp.RecipientName)
-				return
+			if err := tx.AddAddressOutput(outp.Amount, outp.Address); err != nil {
+				statusLabel.SetText("Invalid output address: " + outp.Address)
+				return nil
 			}
-			tx.AddOutput(outp.Amount, rKP.PublicKey)
 		}
+		tx.Finalize()
+		return tx
+	}
 
-		// 5) Підписуємо всі інпути
-		for i := range tx.Inputs {
-			tx.SignTx(fromKP.PrivateKey, i)
+	createTxBtn := widget.NewButton("Create & Sign TX", func() {
+		if fromKeySelect.Selected == "" {
+			statusLabel.SetText("No From Key selected!")
+			return
+		}
+
+		// 1) Знайти fromKeyPair
+		fromKP := selectedFromKey()
+		if fromKP == nil {
+			statusLabel.SetText("FromKey not found.")
+			return
+		}
+
+		tx := buildTx()
+		if tx == nil {
+			return
+		}
+
+		// 5) Підписуємо всі інпути; address outputs also need the public key
+		pool := blockchain.GetUTXOPoolAtMaxHeight()
+		for i, in := range tx.Inputs {
+			spent := pool.GetTxOutput(*third_faza.NewUTXO(in.PrevTxHash, in.OutputIndex))
+			if spent != nil && spent.IsMultiSig() {
+				tx.SignMultiSigTx(fromKP.PrivateKey, i)
+			} else if spent != nil && third_faza.ExtractPubKeyHash(spent.LockScript) != nil {
+				tx.SignPubKeyHashTx(fromKP.PrivateKey, i)
+			} else {
+				tx.SignTx(fromKP.PrivateKey, i)
+			}
 		}
 
 		// 6) Додаємо в пул
-		third_faza.TxProcess(tx)
+		// A transaction spending pending outputs has to outbid them.
+		replaced, ok := third_faza.TxProcess(tx)
+		if !ok {
+			statusLabel.SetText("Transaction rejected: invalid, or its fee is too low to replace pending transactions.")
+			return
+		}
+		if len(replaced) > 0 {
+			statusLabel.SetText(fmt.Sprintf("Transaction created & signed, replacing %d pending transaction(s). Inputs/Outputs cleared.", len(replaced)))
+		} else {
+			statusLabel.SetText("Transaction created & signed. Inputs/Outputs cleared.")
+		}
+		clearInputsAndOutputs()
+	})
 
-		statusLabel.SetText("Transaction created & signed. Inputs/Outputs cleared.")
-		txInputs = nil
-		txOutputs = nil
-		// Clear the lists.
-		inputsListContainer.Objects = nil
-		outputsListContainer.Objects = nil
-		inputsListContainer.Refresh()
-		outputsListContainer.Refresh()
+	// --------------------- PARTIALLY SIGNED TRANSACTIONS ---------------------
+	// A PSBT is exported to a file, signed by every cosigner on their own machine and merged back.
+	var loadedPSBT *third_faza.PartiallySignedTransaction
+	psbtStatusLabel := widget.NewLabel("No PSBT loaded.")
+	psbtStatusLabel.Wrapping = fyne.TextWrapWord
+	showPSBT := func(message string) {
+		if loadedPSBT == nil {
+			psbtStatusLabel.SetText(message)
+			return
+		}
+		sigCount := 0
+		for _, in := range loadedPSBT.Inputs {
+			sigCount += len(in.Signatures)
+		}
+		state := "needs more signatures"
+		if _, err := loadedPSBT.Finalize(); err == nil {
+			state = "ready to finalize"
+		}
+		psbtStatusLabel.SetText(fmt.Sprintf("%s\nPSBT %.6x: %d input(s), %d signature(s), %s",
+			message, loadedPSBT.Tx.GetHash(), len(loadedPSBT.Inputs), sigCount, state))
+	}
+	exportPSBT := func(psbt *third_faza.PartiallySignedTransaction) {
+		dialog.ShowFileSave(func(writer fyne.URIWriteCloser, err error) {
+			if err != nil || writer == nil {
+				return
+			}
+			defer writer.Close()
+			if err := third_faza.EncodePSBT(writer, psbt); err != nil {
+				dialog.ShowError(err, mainWindow)
+				return
+			}
+			loadedPSBT = psbt
+			showPSBT("Exported to " + writer.URI().Name())
+		}, mainWindow)
+	}
+	readPSBT := func(onRead func(*third_faza.PartiallySignedTransaction)) {
+		dialog.ShowFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil || reader == nil {
+				return
+			}
+			defer reader.Close()
+			psbt, err := third_faza.DecodePSBT(reader)
+			if err != nil {
+				dialog.ShowError(err, mainWindow)
+				return
+			}
+			onRead(psbt)
+		}, mainWindow)
+	}
+
+	createPSBTBtn := widget.NewButton("Create PSBT & Export", func() {
+		tx := buildTx()
+		if tx == nil {
+			return
+		}
+		psbt, err := third_faza.NewPSBT(tx, blockchain.GetUTXOPoolAtMaxHeight())
+		if err != nil {
+			statusLabel.SetText("Cannot create PSBT: " + err.Error())
+			return
+		}
+		if fromKP := selectedFromKey(); fromKP != nil {
+			psbt.Sign(fromKP.PrivateKey)
+		}
+		clearInputsAndOutputs()
+		exportPSBT(psbt)
+	})
+	importPSBTBtn := widget.NewButton("Import PSBT", func() {
+		readPSBT(func(psbt *third_faza.PartiallySignedTransaction) {
+			loadedPSBT = psbt
+			showPSBT("Imported.")
+		})
+	})
+	signPSBTBtn := widget.NewButton("Sign PSBT with From Key", func() {
+		fromKP := selectedFromKey()
+		if loadedPSBT == nil || fromKP == nil {
+			showPSBT("Load a PSBT and select a From Key first.")
+			return
+		}
+		showPSBT(fmt.Sprintf("%s signed %d input(s).", fromKP.Name, loadedPSBT.Sign(fromKP.PrivateKey)))
+	})
+	mergePSBTBtn := widget.NewButton("Merge PSBT", func() {
+		if loadedPSBT == nil {
+			showPSBT("Load a PSBT first.")
+			return
+		}
+		readPSBT(func(psbt *third_faza.PartiallySignedTransaction) {
+			if err := loadedPSBT.Merge(psbt); err != nil {
+				showPSBT("Cannot merge: " + err.Error())
+				return
+			}
+			showPSBT("Merged.")
+		})
+	})
+	exportPSBTBtn := widget.NewButton("Export PSBT", func() {
+		if loadedPSBT == nil {
+			showPSBT("Load a PSBT first.")
+			return
+		}
+		exportPSBT(loadedPSBT)
+	})
+	finalizePSBTBtn := widget.NewButton("Finalize & Submit PSBT", func() {
+		if loadedPSBT == nil {
+			showPSBT("Load a PSBT first.")
+			return
+		}
+		tx, err := loadedPSBT.Finalize()
+		if err != nil {
+			showPSBT("Cannot finalize: " + err.Error())
+			return
+		}
+		if _, ok := third_faza.TxProcess(tx); !ok {
+			showPSBT("Finalized transaction was rejected.")
+			return
+		}
+		loadedPSBT = nil
+		psbtStatusLabel.SetText(fmt.Sprintf("Transaction %.6x added to the pool.", tx.GetHash()))
 	})
 
 	// --------------------- Layout ---------------------
@@ -492,6 +748,8 @@ func buildAddTransactionScreen() fyne.CanvasObject {
 	fromKeyBox := container.NewVBox(
 		widget.NewLabelWithStyle("From Key (UTXO owner)", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
 		fromKeySelect,
+		fromAddressLabel,
+		copyAddressBtn,
 	)
 	utxoBox := container.NewVBox(
 		widget.NewLabelWithStyle("Pick UTXO", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
@@ -507,10 +765,17 @@ func buildAddTransactionScreen() fyne.CanvasObject {
 	// Layout the output section.
 	outputBox := container.NewVBox(
 		widget.NewLabelWithStyle("Add Output", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+		toAddressEntry,
 		toKeySelect,
 		outputAmountEntry,
 		addOutputBtn,
 		addOutputStatus,
+		widget.NewLabelWithStyle("Add Multisig Output", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+		multiSigKeysCheck,
+		requiredSigsEntry,
+		multiSigAmountEntry,
+		addMultiSigBtn,
+		addMultiSigStatus,
 		// Display the list of added outputs.
 		widget.NewLabelWithStyle("Added Outputs:", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
 		outputsListContainer,
@@ -521,6 +786,9 @@ func buildAddTransactionScreen() fyne.CanvasObject {
 		container.NewHBox(inputSection, layout.NewSpacer(), outputBox),
 		createTxBtn,
 		statusLabel,
+		widget.NewLabelWithStyle("Partially Signed Transactions", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+		container.NewHBox(createPSBTBtn, importPSBTBtn, signPSBTBtn, mergePSBTBtn, exportPSBTBtn, finalizePSBTBtn),
+		psbtStatusLabel,
 	)
 	return form
 }
@@ -537,15 +805,32 @@ func parseHexString(str string) []byte {
 // ===================== MAIN & INIT =====================
 
 func init() {
-	// Generate 3 sample keys (User1, User2, User3)
-	for i := 1; i <= 3; i++ {
-		priv, _ := rsa.GenerateKey(rand.Reader, 1024)
-		pub := &priv.PublicKey
-		keyPairs = append(keyPairs, KeyPair{
-			Name:       fmt.Sprintf("User%d", i),
-			PrivateKey: priv,
-			PublicKey:  pub,
-		})
+	third_faza.CoinbaseMaturity = demoCoinbaseMaturity
+
+	// Reuse the keys of the previous run, the stored chain pays to them
+	keysPath := filepath.Join(chainDataDir, demoKeysFile)
+	pairs, err := loadKeyPairs(keysPath)
+	if err == nil && len(pairs) > 0 {
+		keyPairs = pairs
+		userCounter = len(keyPairs) + 1
+	} else {
+		// Generate 3 sample keys (User1, User2, User3)
+		for i := 1; i <= 3; i++ {
+			priv, _ := third_faza.GenerateKey()
+			pub := third_faza.PublicKeyOf(priv)
+			keyPairs = append(keyPairs, KeyPair{
+				Name:       fmt.Sprintf("User%d", i),
+				PrivateKey: priv,
+				PublicKey:  pub,
+			})
+		}
+		// An unreadable file is left alone rather than replaced by keys it never held.
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			demoKeysUnreadable = true
+			log.Printf("demo keys unreadable, coins of the stored chain cannot be spent this run: %v", err)
+		} else if err := saveKeyPairs(keysPath, keyPairs); err != nil {
+			log.Printf("demo keys not saved, coins cannot be spent after a restart: %v", err)
+		}
 	}
 	shortTxHashToLongTxHash = make(map[string]string)
 }
@@ -555,11 +840,30 @@ func main() {
 	mainWindow = myApp.NewWindow("Blockchain Visualizer")
 	mainWindow.Resize(fyne.NewSize(800, 600))
 
-	// 1) Create genesis block
+	// 1) Create genesis block, or reopen the chain stored by a previous run
 	genesis := third_faza.NewBlock(nil, keyPairs[0].PublicKey)
 	genesis.Finalizee()
-	blockchain = third_faza.NewBlockchain(genesis)
+	store, err := third_faza.OpenBlockStore(chainDataDir)
+	if err == nil {
+		blockchain, err = third_faza.NewBlockchainWithStore(genesis, store)
+		if err != nil {
+			store.Close()
+		}
+	}
+	if err != nil {
+		log.Printf("block store unavailable, keeping the chain in memory: %v", err)
+		blockchain = third_faza.NewBlockchain(genesis)
+	}
 	third_faza.HandleBlocks(blockchain)
+	blockchain.OnReorg(func(event third_faza.ReorgEvent) {
+		log.Printf("chain reorganized at height %d: %d block(s) disconnected, %d transaction(s) returned to the pool, %d evicted",
+			event.Fork.Height, event.Depth, len(event.Resurrected), len(event.Evicted))
+	})
+	mainWindow.SetOnClosed(func() {
+		if blockchain.Store != nil {
+			blockchain.Store.Close()
+		}
+	})
 
 	// ============== UI: Blockchain screen with Refresh ==============
 	var updateBlockchainScreen func()
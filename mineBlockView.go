@@ -101,6 +101,11 @@ func showBlockProcessPopup(parentWindow fyne.Window) {
 			}
 		}
 
+		// Let the miner claim the fees of the selected transactions.
+		if _, fees, ok := third_faza.ConnectBlock(blockchain.GetUTXOPoolAt(parentNode), newBlock); ok && fees > 0 {
+			newBlock.SetCoinbase(third_faza.NewCoinbaseTransaction(third_faza.BlockSubsidy(parentNode.Height+1)+fees, minerAddress))
+		}
+
 		// Finalize the block (e.g., compute its hash).
 		newBlock.Finalizee()
 
@@ -0,0 +1,153 @@
+package third_faza
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+	"strings"
+)
+
+// ADDRESS_VERSION is the version byte in front of the public key hash of an address.
+const ADDRESS_VERSION byte = 0x00
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+var (
+	ErrInvalidAddress  = errors.New("invalid address")
+	ErrAddressChecksum = errors.New("address checksum mismatch")
+)
+
+// EncodeAddress returns the text form of a public key hash: the Base58Check encoding of
+// ADDRESS_VERSION and the hash, followed by the first four bytes of their double SHA-256.
+// Outputs paid to an address are locked by PayToPubKeyHashScript; the spender reveals the
+// public key next to the signature.
+func EncodeAddress(pubKeyHash []byte) string {
+	payload := append([]byte{ADDRESS_VERSION}, pubKeyHash...)
+	return base58Encode(append(payload, addressChecksum(payload)...))
+}
+
+// DecodeAddress returns the public key hash of an address produced by EncodeAddress.
+func DecodeAddress(address string) ([]byte, error) {
+	data, ok := base58Decode(address)
+	if !ok || len(data) != 1+sha256.Size+4 || data[0] != ADDRESS_VERSION {
+		return nil, ErrInvalidAddress
+	}
+	payload, checksum := data[:len(data)-4], data[len(data)-4:]
+	if !bytes.Equal(checksum, addressChecksum(payload)) {
+		return nil, ErrAddressChecksum
+	}
+	return payload[1:], nil
+}
+
+// AddressOf returns the address of a public key.
+func AddressOf(pubKey PublicKey) string {
+	return EncodeAddress(PubKeyHash(pubKey))
+}
+
+func addressChecksum(payload []byte) []byte {
+	first := sha256.Sum256(payload)
+	second := sha256.Sum256(first[:])
+	return second[:4]
+}
+
+func base58Encode(data []byte) string {
+	n := new(big.Int).SetBytes(data)
+	radix := big.NewInt(58)
+	mod := new(big.Int)
+
+	encoded := make([]byte, 0, len(data)*138/100+1)
+	for n.Sign() > 0 {
+		n.DivMod(n, radix, mod)
+		encoded = append(encoded, base58Alphabet[mod.Int64()])
+	}
+	// Every leading zero byte is written as the first character of the alphabet.
+	for _, b := range data {
+		if b != 0 {
+			break
+		}
+		encoded = append(encoded, base58Alphabet[0])
+	}
+
+	for i, j := 0, len(encoded)-1; i < j; i, j = i+1, j-1 {
+		encoded[i], encoded[j] = encoded[j], encoded[i]
+	}
+	return string(encoded)
+}
+
+func base58Decode(text string) ([]byte, bool) {
+	n := new(big.Int)
+	radix := big.NewInt(58)
+	for i := 0; i < len(text); i++ {
+		digit := strings.IndexByte(base58Alphabet, text[i])
+		if digit < 0 {
+			return nil, false
+		}
+		n.Mul(n, radix)
+		n.Add(n, big.NewInt(int64(digit)))
+	}
+
+	zeros := 0
+	for zeros < len(text) && text[zeros] == base58Alphabet[0] {
+		zeros++
+	}
+	return append(make([]byte, zeros), n.Bytes()...), true
+}
+
+// ExtractPubKeyHash returns the public key hash of a script built by PayToPubKeyHashScript,
+// or nil for any other script.
+func ExtractPubKeyHash(script Script) []byte {
+	ops, err := ParseScript(script)
+	if err != nil || len(ops) != 5 {
+		return nil
+	}
+	if ops[0].Opcode != OP_DUP || ops[1].Opcode != OP_SHA256 || len(ops[2].Data) != sha256.Size ||
+		ops[3].Opcode != OP_EQUALVERIFY || ops[4].Opcode != OP_CHECKSIG {
+		return nil
+	}
+	return ops[2].Data
+}
+
+// NewAddressOutput creates an output paying to an address.
+func NewAddressOutput(value Amount, address string) (*Output, error) {
+	pubKeyHash, err := DecodeAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	return NewScriptOutput(value, PayToPubKeyHashScript(pubKeyHash)), nil
+}
+
+// PubKeyHash returns the hash of the single key that can spend the output: the hash locked
+// by a pay-to-public-key-hash script or the hash of Address. It is nil for other outputs.
+func (out *Output) PubKeyHash() []byte {
+	if len(out.LockScript) > 0 {
+		return ExtractPubKeyHash(out.LockScript)
+	}
+	if len(out.MultiSigAddresses) == 0 && out.Address != nil {
+		return PubKeyHash(out.Address)
+	}
+	return nil
+}
+
+// PaysTo reports whether pubKey alone can spend the output.
+func (out *Output) PaysTo(pubKey PublicKey) bool {
+	hash := out.PubKeyHash()
+	return hash != nil && bytes.Equal(hash, PubKeyHash(pubKey))
+}
+
+// AddAddressOutput appends a new output paying value to the given address.
+func (tx *Transaction) AddAddressOutput(value Amount, address string) error {
+	out, err := NewAddressOutput(value, address)
+	if err != nil {
+		return err
+	}
+	tx.Outputs = append(tx.Outputs, out)
+	return nil
+}
+
+// SignPubKeyHashTx signs the input for an output paid to the address of sk, revealing the
+// public key alongside the signature.
+func (tx *Transaction) SignPubKeyHashTx(sk PrivateKey, input int) {
+	sig := tx.CreateSignature(sk, input)
+	tx.SetUnlockScript(input, SignatureScript(sig, EncodePublicKey(PublicKeyOf(sk))))
+}
@@ -0,0 +1,102 @@
+package third_faza
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddress_EncodeDecode(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKey := &privateKey.PublicKey
+
+	address := AddressOf(pubKey)
+	assert.Equal(t, byte('1'), address[0], "Version 0 should encode as a leading 1")
+	decoded, err := DecodeAddress(address)
+	assert.NoError(t, err)
+	assert.Equal(t, PubKeyHash(pubKey), decoded)
+
+	assert.Equal(t, "1111111111111111111111111111111112m1s9K", EncodeAddress(make([]byte, 32)))
+	assert.Equal(t, []byte{0, 0, 1, 2}, mustBase58Decode(t, base58Encode([]byte{0, 0, 1, 2})))
+
+	// Changing any character breaks the checksum.
+	typo := []byte(address)
+	if typo[10] == 'a' {
+		typo[10] = 'b'
+	} else {
+		typo[10] = 'a'
+	}
+	_, err = DecodeAddress(string(typo))
+	assert.ErrorIs(t, err, ErrAddressChecksum)
+
+	for _, invalid := range []string{"", "0OIl", address[:20], address + "11111"} {
+		_, err = DecodeAddress(invalid)
+		assert.ErrorIs(t, err, ErrInvalidAddress, invalid)
+	}
+}
+
+func mustBase58Decode(t *testing.T, text string) []byte {
+	data, ok := base58Decode(text)
+	assert.True(t, ok)
+	return data
+}
+
+func TestAddress_PayToAddress(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
+	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyBob := &privateKeyBob.PublicKey
+
+	privateKeyAlice, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyAlice := &privateKeyAlice.PublicKey
+
+	genesisBlock := NewBlock(nil, pubKeyBob)
+	genesisBlock.Finalizee()
+	localBlockchain := NewBlockchain(genesisBlock)
+	HandleBlocks(localBlockchain)
+
+	tx1 := NewTransaction()
+	tx1.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
+	assert.Error(t, tx1.AddAddressOutput(1*COIN, "not an address"))
+	assert.NoError(t, tx1.AddAddressOutput(3*COIN, AddressOf(pubKeyAlice)))
+	tx1.SignTx(privateKeyBob, 0)
+	assert.Less(t, len(tx1.Outputs[0].LockScript), 80, "Address outputs should not embed the public key")
+	assert.True(t, tx1.Outputs[0].PaysTo(pubKeyAlice))
+	assert.False(t, tx1.Outputs[0].PaysTo(pubKeyBob))
+	assert.True(t, genesisBlock.GetCoinbase().Outputs[0].PaysTo(pubKeyBob))
+
+	block1 := NewBlock(genesisBlock.GetHash(), pubKeyBob)
+	block1.TransactionAdd(tx1)
+	block1.Finalizee()
+	assert.True(t, BlockProcess(block1))
+
+	withoutKey := NewTransaction()
+	withoutKey.AddInput(tx1.GetHash(), 0)
+	withoutKey.AddOutput(3*COIN, pubKeyBob)
+	withoutKey.SignTx(privateKeyAlice, 0)
+	assert.False(t, TxIsValid(*withoutKey, localBlockchain.UTXOSet), "Spending an address output should reveal the public key")
+
+	wrongKey := NewTransaction()
+	wrongKey.AddInput(tx1.GetHash(), 0)
+	wrongKey.AddOutput(3*COIN, pubKeyBob)
+	wrongKey.SignPubKeyHashTx(privateKeyBob, 0)
+	assert.False(t, TxIsValid(*wrongKey, localBlockchain.UTXOSet), "Only the key behind the address may spend")
+
+	spend := NewTransaction()
+	spend.AddInput(tx1.GetHash(), 0)
+	spend.AddOutput(3*COIN, pubKeyBob)
+	spend.SignPubKeyHashTx(privateKeyAlice, 0)
+	assert.True(t, TxIsValid(*spend, localBlockchain.UTXOSet))
+}
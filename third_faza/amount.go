@@ -0,0 +1,137 @@
+package third_faza
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Amount is a number of coins in base units. COIN base units make one coin.
+type Amount int64
+
+const (
+	// COIN is the number of base units in one coin. It is untyped, so amounts
+	// can be written as constants such as 1.5 * COIN.
+	COIN = 100000000
+	// AMOUNT_DECIMALS is the number of decimal places of a coin amount.
+	AMOUNT_DECIMALS = 8
+	// MAX_AMOUNT is the largest value an Amount can hold.
+	MAX_AMOUNT Amount = math.MaxInt64
+)
+
+var (
+	ErrAmountOverflow = errors.New("amount overflow")
+	ErrInvalidAmount  = errors.New("invalid amount")
+)
+
+// MaxMoney returns the largest amount a single output or a sum of outputs may carry:
+// the total supply of the Issuance schedule, or MAX_AMOUNT when the supply is unbounded.
+func MaxMoney() Amount {
+	return Issuance.MaxSupply()
+}
+
+// MoneyRange reports whether amount is non-negative and does not exceed MaxMoney.
+func MoneyRange(amount Amount) bool {
+	return amount >= 0 && amount <= MaxMoney()
+}
+
+// Add returns amount + other and false if the sum overflows.
+func (amount Amount) Add(other Amount) (Amount, bool) {
+	sum := amount + other
+	if (other > 0 && sum < amount) || (other < 0 && sum > amount) {
+		return 0, false
+	}
+	return sum, true
+}
+
+// Sub returns amount - other and false if the difference overflows.
+func (amount Amount) Sub(other Amount) (Amount, bool) {
+	if other == math.MinInt64 {
+		return 0, false
+	}
+	return amount.Add(-other)
+}
+
+// MulInt returns amount * n and false if the product overflows.
+func (amount Amount) MulInt(n int64) (Amount, bool) {
+	if amount == 0 || n == 0 {
+		return 0, true
+	}
+	product := amount * Amount(n)
+	if product/Amount(n) != amount || (amount == -1 && n == math.MinInt64) || (n == -1 && amount == math.MinInt64) {
+		return 0, false
+	}
+	return product, true
+}
+
+// SumAmounts adds up amounts and fails if any of them or the running sum leaves MoneyRange.
+func SumAmounts(amounts ...Amount) (Amount, error) {
+	total := Amount(0)
+	for _, amount := range amounts {
+		if !MoneyRange(amount) {
+			return 0, ErrInvalidAmount
+		}
+		sum, ok := total.Add(amount)
+		if !ok || !MoneyRange(sum) {
+			return 0, ErrAmountOverflow
+		}
+		total = sum
+	}
+	return total, nil
+}
+
+// String formats the amount in coins with all AMOUNT_DECIMALS decimal places, e.g. "3.12500000".
+func (amount Amount) String() string {
+	sign := ""
+	units := uint64(amount)
+	if amount < 0 {
+		sign = "-"
+		units = uint64(-amount)
+	}
+	coins := strconv.FormatUint(units/uint64(COIN), 10)
+	fraction := strconv.FormatUint(units%uint64(COIN), 10)
+	return sign + coins + "." + strings.Repeat("0", AMOUNT_DECIMALS-len(fraction)) + fraction
+}
+
+// ParseAmount parses a non-negative decimal number of coins with at most AMOUNT_DECIMALS
+// decimal places, such as "1", "0.5" or "3.125", into base units.
+func ParseAmount(value string) (Amount, error) {
+	value = strings.TrimSpace(value)
+	whole, fraction, _ := strings.Cut(value, ".")
+	if whole == "" && fraction == "" || len(fraction) > AMOUNT_DECIMALS {
+		return 0, ErrInvalidAmount
+	}
+	for _, part := range []string{whole, fraction} {
+		if strings.Trim(part, "0123456789") != "" {
+			return 0, ErrInvalidAmount
+		}
+	}
+
+	coins := int64(0)
+	if whole != "" {
+		parsed, err := strconv.ParseInt(whole, 10, 64)
+		if err != nil {
+			return 0, ErrAmountOverflow
+		}
+		coins = parsed
+	}
+	units := int64(0)
+	if fraction != "" {
+		parsed, err := strconv.ParseInt(fraction+strings.Repeat("0", AMOUNT_DECIMALS-len(fraction)), 10, 64)
+		if err != nil {
+			return 0, ErrInvalidAmount
+		}
+		units = parsed
+	}
+
+	amount, ok := Amount(COIN).MulInt(coins)
+	if !ok {
+		return 0, ErrAmountOverflow
+	}
+	amount, ok = amount.Add(Amount(units))
+	if !ok || !MoneyRange(amount) {
+		return 0, ErrAmountOverflow
+	}
+	return amount, nil
+}
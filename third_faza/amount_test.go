@@ -0,0 +1,87 @@
+package third_faza
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAmount_ParseAndFormat(t *testing.T) {
+	for text, expected := range map[string]Amount{
+		"1":          COIN,
+		"0.5":        0.5 * COIN,
+		"3.125":      COINBASE,
+		".00000001":  1,
+		"1.12000000": 1.12 * COIN,
+	} {
+		amount, err := ParseAmount(text)
+		assert.NoError(t, err, text)
+		assert.Equal(t, expected, amount, text)
+	}
+	assert.Equal(t, "3.12500000", COINBASE.String())
+	assert.Equal(t, "-0.00000001", Amount(-1).String())
+
+	for _, text := range []string{"", ".", "-1", "1e3", "0.000000001", "1.2.3", "abc"} {
+		_, err := ParseAmount(text)
+		assert.ErrorIs(t, err, ErrInvalidAmount, text)
+	}
+	_, err := ParseAmount("99999999999")
+	assert.ErrorIs(t, err, ErrAmountOverflow, "Amounts above the max supply should be rejected")
+}
+
+func TestAmount_CheckedArithmetic(t *testing.T) {
+	_, ok := MAX_AMOUNT.Add(1)
+	assert.False(t, ok)
+	_, ok = (-MAX_AMOUNT - 1).Sub(1)
+	assert.False(t, ok)
+	_, ok = MAX_AMOUNT.MulInt(2)
+	assert.False(t, ok)
+
+	sum, err := SumAmounts(COIN, 2*COIN)
+	assert.NoError(t, err)
+	assert.Equal(t, Amount(3*COIN), sum)
+	_, err = SumAmounts(MaxMoney(), 1)
+	assert.ErrorIs(t, err, ErrAmountOverflow)
+	_, err = SumAmounts(-1)
+	assert.ErrorIs(t, err, ErrInvalidAmount)
+}
+
+func TestAmount_TransactionsCannotCreateValueThroughOverflow(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
+	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyBob := &privateKeyBob.PublicKey
+
+	genesisBlock := NewBlock(nil, pubKeyBob)
+	genesisBlock.Finalizee()
+	localBlockchain := NewBlockchain(genesisBlock)
+	HandleBlocks(localBlockchain)
+
+	// A huge positive and a huge negative output would sum to less than the input with wrapping arithmetic.
+	overflowTx := NewTransaction()
+	overflowTx.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
+	overflowTx.AddOutput(MAX_AMOUNT, pubKeyBob)
+	overflowTx.AddOutput(-MAX_AMOUNT, pubKeyBob)
+	overflowTx.SignTx(privateKeyBob, 0)
+	assert.False(t, TxIsValid(*overflowTx, localBlockchain.UTXOSet))
+
+	aboveSupply := NewTransaction()
+	aboveSupply.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
+	aboveSupply.AddOutput(MaxMoney()+1, pubKeyBob)
+	aboveSupply.SignTx(privateKeyBob, 0)
+	assert.False(t, TxIsValid(*aboveSupply, localBlockchain.UTXOSet), "Output above the max supply should be rejected")
+
+	exact := NewTransaction()
+	exact.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
+	exact.AddOutput(1.125*COIN, pubKeyBob)
+	exact.AddOutput(2*COIN, pubKeyBob)
+	exact.SignTx(privateKeyBob, 0)
+	assert.True(t, TxIsValid(*exact, localBlockchain.UTXOSet), "Outputs adding up to the input exactly should be valid")
+	assert.Equal(t, Amount(0), GetFee(exact, localBlockchain.UTXOSet))
+}
@@ -1,39 +1,170 @@
 package third_faza
 
 import (
-	"crypto/rsa"
 	"crypto/sha256"
+	"encoding/binary"
+	"time"
 )
 
-const COINBASE = 3.125
+// COINBASE is the initial block subsidy of the default issuance schedule.
+const COINBASE Amount = 3.125 * COIN
+
+const (
+	// BLOCK_VERSION is the header version produced by NewBlock.
+	BLOCK_VERSION = 1
+	// MEDIAN_TIME_SPAN is the number of ancestors whose median timestamp bounds a new block from below.
+	MEDIAN_TIME_SPAN = 11
+	// MAX_FUTURE_BLOCK_TIME is how many seconds a block timestamp may run ahead of the local clock.
+	MAX_FUTURE_BLOCK_TIME = 2 * 60 * 60
+	// MAX_BLOCK_SIZE is how many bytes of transactions BlockCreate puts into a block.
+	MAX_BLOCK_SIZE = 1000000
+)
+
+// BlockHeader holds the fields that are hashed to identify a block.
+// The transactions are committed to through MerkleRoot, built over their IDs, and their
+// signatures through WitnessRoot, built over their witness hashes.
+type BlockHeader struct {
+	Version       uint32
+	PrevBlockHash []byte
+	MerkleRoot    []byte
+	WitnessRoot   []byte
+	Timestamp     int64
+	Bits          uint32
+	Nonce         uint64
+}
+
+// Serialize returns the fixed-size byte representation of the header that is hashed during mining.
+// Hashes are written as 32 bytes, so a missing previous hash (genesis) is encoded as zeros.
+func (header *BlockHeader) Serialize() []byte {
+	data := make([]byte, 0, 4+3*sha256.Size+8+4+8)
+	data = binary.BigEndian.AppendUint32(data, header.Version)
+	data = append(data, fixedHash(header.PrevBlockHash)...)
+	data = append(data, fixedHash(header.MerkleRoot)...)
+	data = append(data, fixedHash(header.WitnessRoot)...)
+	data = binary.BigEndian.AppendUint64(data, uint64(header.Timestamp))
+	data = binary.BigEndian.AppendUint32(data, header.Bits)
+	data = binary.BigEndian.AppendUint64(data, header.Nonce)
+	return data
+}
+
+// Hash returns the SHA-256 hash of the serialized header.
+func (header *BlockHeader) Hash() []byte {
+	hash := sha256.Sum256(header.Serialize())
+	return hash[:]
+}
+
+// fixedHash pads or truncates a hash to exactly sha256.Size bytes.
+func fixedHash(hash []byte) []byte {
+	fixed := make([]byte, sha256.Size)
+	copy(fixed, hash)
+	return fixed
+}
 
 type Block struct {
-	hash          []byte
-	prevBlockHash []byte
-	coinbase      *Transaction
-	txs           []*Transaction
+	header   BlockHeader
+	hash     []byte
+	coinbase *Transaction
+	txs      []*Transaction
 }
 
-func NewBlock(prevHash []byte, address *rsa.PublicKey) *Block {
-	coinbase := NewCoinbaseTransaction(COINBASE, address)
+// NewBlock creates a block on top of prevHash paying the block subsidy to address.
+// The difficulty and the height of the subsidy are taken from the chain registered by
+// HandleBlocks, so the block only needs to be finalized (mined) before it can be processed.
+func NewBlock(prevHash []byte, address PublicKey) *Block {
 	newBlock := &Block{
-		prevBlockHash: append([]byte{}, prevHash...),
-		coinbase:      coinbase,
-		txs:           []*Transaction{coinbase},
+		header: BlockHeader{
+			Version:       BLOCK_VERSION,
+			PrevBlockHash: append([]byte{}, prevHash...),
+			Timestamp:     time.Now().Unix(),
+			Bits:          POW_LIMIT_BITS,
+		},
+	}
+	height := uint(1)
+	if blockchain != nil && prevHash != nil {
+		if parent := blockchain.Get(prevHash); parent != nil {
+			height = parent.Height + 1
+			newBlock.header.Bits = NextWorkRequired(parent)
+			if medianTime := MedianTimePast(parent); newBlock.header.Timestamp < medianTime {
+				newBlock.header.Timestamp = medianTime
+			}
+		}
 	}
+
+	coinbase := NewCoinbaseTransaction(BlockSubsidy(height), address)
+	newBlock.coinbase = coinbase
+	newBlock.txs = []*Transaction{coinbase}
 	return newBlock
 }
 
+// GetHeader returns a copy of the block header.
+func (block *Block) GetHeader() BlockHeader {
+	header := block.header
+	header.PrevBlockHash = append([]byte(nil), block.header.PrevBlockHash...)
+	header.MerkleRoot = append([]byte(nil), block.header.MerkleRoot...)
+	header.WitnessRoot = append([]byte(nil), block.header.WitnessRoot...)
+	return header
+}
+
 func (block *Block) GetCoinbase() *Transaction {
 	return block.coinbase
 }
 
+// SetCoinbase replaces the coinbase transaction. The block has to be finalized again afterwards.
+func (block *Block) SetCoinbase(coinbase *Transaction) {
+	block.coinbase = coinbase
+	if len(block.txs) > 0 && block.txs[0].IsCoinbase() {
+		block.txs[0] = coinbase
+	} else {
+		block.txs = append([]*Transaction{coinbase}, block.txs...)
+	}
+}
+
 func (block *Block) GetHash() []byte {
 	return block.hash
 }
 
 func (block *Block) GetPrevBlockHash() []byte {
-	return block.prevBlockHash
+	return block.header.PrevBlockHash
+}
+
+// GetVersion returns the header version.
+func (block *Block) GetVersion() uint32 {
+	return block.header.Version
+}
+
+// GetMerkleRoot returns the Merkle root stored in the header.
+func (block *Block) GetMerkleRoot() []byte {
+	return block.header.MerkleRoot
+}
+
+// GetWitnessRoot returns the witness root stored in the header.
+func (block *Block) GetWitnessRoot() []byte {
+	return block.header.WitnessRoot
+}
+
+// GetTimestamp returns the UNIX time at which the block was created.
+func (block *Block) GetTimestamp() int64 {
+	return block.header.Timestamp
+}
+
+// SetTimestamp overrides the block timestamp. The block has to be finalized again afterwards.
+func (block *Block) SetTimestamp(timestamp int64) {
+	block.header.Timestamp = timestamp
+}
+
+// GetBits returns the compact difficulty target the block is mined against.
+func (block *Block) GetBits() uint32 {
+	return block.header.Bits
+}
+
+// SetBits overrides the difficulty target. The block has to be finalized again afterwards.
+func (block *Block) SetBits(bits uint32) {
+	block.header.Bits = bits
+}
+
+// GetNonce returns the nonce found while mining the block.
+func (block *Block) GetNonce() uint64 {
+	return block.header.Nonce
 }
 
 func (block *Block) GetTransactions() []*Transaction {
@@ -50,20 +181,62 @@ func (block *Block) TransactionAdd(tx *Transaction) {
 	block.txs = append(block.txs, tx)
 }
 
+// GetBlock returns the serialized header followed by the raw bytes of every transaction,
+// witness data included.
 func (block *Block) GetBlock() []byte {
-	rawBlock := make([]byte, 0)
+	rawBlock := block.header.Serialize()
+	for _, tx := range block.txs {
+		rawBlock = append(rawBlock, tx.GetWitnessTx()...)
+	}
+	return rawBlock
+}
 
-	if block.prevBlockHash != nil {
-		rawBlock = append(rawBlock, block.prevBlockHash...)
+// ComputeMerkleRoot returns the Merkle root of the hashes of the block's transactions.
+func (block *Block) ComputeMerkleRoot() []byte {
+	hashes := make([][]byte, len(block.txs))
+	for i, tx := range block.txs {
+		hashes[i] = tx.GetHash()
 	}
-	for _, tx := range block.txs {
-		rawBlock = append(rawBlock, tx.GetTx()...)
+	return MerkleRoot(hashes)
+}
+
+// ComputeWitnessRoot returns the Merkle root of the witness hashes of the block's transactions.
+func (block *Block) ComputeWitnessRoot() []byte {
+	hashes := make([][]byte, len(block.txs))
+	for i, tx := range block.txs {
+		hashes[i] = tx.WitnessHash()
 	}
+	return MerkleRoot(hashes)
+}
 
-	return rawBlock
+// CalculateHash hashes the current header.
+func (block *Block) CalculateHash() []byte {
+	return block.header.Hash()
 }
 
+// Finalizee commits to the transactions through the Merkle and witness roots and mines the block:
+// it searches for a nonce whose header hash meets the block's target and stores that hash.
+// A block with a target outside the allowed range is only hashed, as no nonce could make it valid.
 func (block *Block) Finalizee() {
-	hash := sha256.Sum256(block.GetBlock())
-	block.hash = hash[:]
+	block.header.MerkleRoot = block.ComputeMerkleRoot()
+	block.header.WitnessRoot = block.ComputeWitnessRoot()
+
+	target := CompactToBig(block.header.Bits)
+	if target.Sign() <= 0 || target.Cmp(PowLimit()) > 0 {
+		block.header.Nonce = 0
+		block.hash = block.CalculateHash()
+		return
+	}
+
+	data := block.header.Serialize()
+	nonceOffset := len(data) - 8
+	for nonce := uint64(0); ; nonce++ {
+		binary.BigEndian.PutUint64(data[nonceOffset:], nonce)
+		hash := sha256.Sum256(data)
+		if HashMeetsTarget(hash[:], block.header.Bits) {
+			block.header.Nonce = nonce
+			block.hash = hash[:]
+			return
+		}
+	}
 }
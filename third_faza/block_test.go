@@ -0,0 +1,120 @@
+package third_faza
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"fmt"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlock_MerkleRoot(t *testing.T) {
+	a := sha256.Sum256([]byte("a"))
+	b := sha256.Sum256([]byte("b"))
+	c := sha256.Sum256([]byte("c"))
+
+	assert.Equal(t, make([]byte, sha256.Size), MerkleRoot(nil), "Empty tree should have a zero root")
+	assert.Equal(t, a[:], MerkleRoot([][]byte{a[:]}), "Single leaf should be its own root")
+
+	ab := merkleHash(a[:], b[:])
+	cc := merkleHash(c[:], c[:])
+	assert.Equal(t, merkleHash(ab, cc), MerkleRoot([][]byte{a[:], b[:], c[:]}), "Odd leaf should be paired with itself")
+}
+
+func TestBlock_HeaderCommitsToTransactions(t *testing.T) {
+	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyBob := &privateKeyBob.PublicKey
+
+	genesisBlock := NewBlock(nil, pubKeyBob)
+	genesisBlock.Finalizee()
+
+	assert.Equal(t, uint32(BLOCK_VERSION), genesisBlock.GetVersion())
+	assert.Equal(t, genesisBlock.GetCoinbase().GetHash(), genesisBlock.GetMerkleRoot(), "Root of a coinbase-only block is the coinbase hash")
+	header := genesisBlock.GetHeader()
+	assert.Equal(t, genesisBlock.GetHash(), header.Hash(), "Block hash should be the hash of its header")
+}
+
+func TestBlock_BlockWithWrongMerkleRootIsRejected(t *testing.T) {
+	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyBob := &privateKeyBob.PublicKey
+
+	genesisBlock := NewBlock(nil, pubKeyBob)
+	genesisBlock.Finalizee()
+	localBlockchain := NewBlockchain(genesisBlock)
+	HandleBlocks(localBlockchain)
+
+	block1 := NewBlock(genesisBlock.GetHash(), pubKeyBob)
+	block1.Finalizee()
+
+	// Swap the body for another coinbase while keeping the mined header.
+	otherCoinbase := NewCoinbaseTransaction(COINBASE, pubKeyBob)
+	otherCoinbase.Timestamp++
+	otherCoinbase.Finalize()
+	block1.coinbase = otherCoinbase
+	block1.txs = []*Transaction{otherCoinbase}
+
+	assert.False(t, BlockProcess(block1), "Block whose Merkle root does not match its transactions should be rejected")
+}
+
+func TestBlock_TimestampBeforeMedianTimePastIsRejected(t *testing.T) {
+	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyBob := &privateKeyBob.PublicKey
+
+	genesisBlock := NewBlock(nil, pubKeyBob)
+	genesisBlock.Finalizee()
+	localBlockchain := NewBlockchain(genesisBlock)
+	HandleBlocks(localBlockchain)
+
+	prev := genesisBlock
+	for i := 1; i <= MEDIAN_TIME_SPAN; i++ {
+		block := NewBlock(prev.GetHash(), pubKeyBob)
+		block.SetTimestamp(genesisBlock.GetTimestamp() + int64(i*TARGET_BLOCK_SPACING))
+		block.Finalizee()
+		assert.True(t, BlockProcess(block), fmt.Sprintf("Block #%d should be accepted", i))
+		prev = block
+	}
+
+	medianTime := MedianTimePast(localBlockchain.Get(prev.GetHash()))
+
+	early := NewBlock(prev.GetHash(), pubKeyBob)
+	early.SetTimestamp(medianTime - 1)
+	early.Finalizee()
+	assert.False(t, BlockProcess(early), "Block older than the median of its ancestors should be rejected")
+
+	onMedian := NewBlock(prev.GetHash(), pubKeyBob)
+	onMedian.SetTimestamp(medianTime)
+	onMedian.Finalizee()
+	assert.True(t, BlockProcess(onMedian), "Block at the median of its ancestors should be accepted")
+}
+
+func TestBlock_TimestampTooFarInTheFutureIsRejected(t *testing.T) {
+	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyBob := &privateKeyBob.PublicKey
+
+	genesisBlock := NewBlock(nil, pubKeyBob)
+	genesisBlock.Finalizee()
+	localBlockchain := NewBlockchain(genesisBlock)
+	HandleBlocks(localBlockchain)
+
+	block1 := NewBlock(genesisBlock.GetHash(), pubKeyBob)
+	block1.SetTimestamp(time.Now().Unix() + MAX_FUTURE_BLOCK_TIME + 60)
+	block1.Finalizee()
+
+	assert.False(t, BlockProcess(block1), "Block from the far future should be rejected")
+}
@@ -1,8 +1,12 @@
 package third_faza
 
 import (
+	"bytes"
 	"encoding/hex"
-	"math"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
 )
 
 const (
@@ -10,51 +14,68 @@ const (
 	MAX_BLOCKS_INT_MEMORY = 100
 )
 
+// BlockNode is a block in the tree of known blocks. ChainWork is the total work of the
+// block and all its ancestors and Supply the number of coins their coinbases issued on top
+// of the collected fees. Undo holds the data needed to disconnect the block from the
+// UTXO set when the chain switches to another branch.
 type BlockNode struct {
-	B        *Block
-	Parent   *BlockNode
-	Children []*BlockNode
-	Height   uint
-	Pool     *UTXOPool
+	B         *Block
+	Parent    *BlockNode
+	Children  []*BlockNode
+	Height    uint
+	ChainWork *big.Int
+	Supply    Amount
+	Undo      *BlockUndo
 }
 
-func NewBlockNode(b *Block, parent *BlockNode, uPool *UTXOPool) *BlockNode {
+func NewBlockNode(b *Block, parent *BlockNode) *BlockNode {
 	newBlockNode := &BlockNode{
 		B:        b,
 		Parent:   parent,
 		Children: []*BlockNode{},
-		Pool:     uPool,
 	}
 
 	height := uint(1)
+	chainWork := BlockWork(b.GetBits())
 	if parent != nil {
 		height = parent.Height + 1
+		chainWork.Add(chainWork, parent.ChainWork)
 	}
 	newBlockNode.Height = height
+	newBlockNode.ChainWork = chainWork
 
 	return newBlockNode
 }
 
-func (blockNode *BlockNode) GetUTXOPoolCopy() *UTXOPool {
-	return NewUTXOPoolWithPool(blockNode.Pool)
-}
-
+// Blockchain keeps a single UTXO set, UTXOSet, which always reflects the block at
+// MaxHeightNode[0]. MaxHeightNode holds the nodes with the greatest chain work; the first of
+// them is the tip. Ties go to the lower block hash, so every node picks the same tip whatever
+// order the blocks arrived in. Other branches are validated by temporarily moving the set to
+// them with the per-block undo data. When another branch becomes the tip, the transaction
+// pool is reorganized and the functions registered with OnReorg are notified.
 type Blockchain struct {
 	BlockChain            map[string]*BlockNode
 	MaxHeightNode         []*BlockNode
+	UTXOSet               *UTXOPool
 	GlobalTransactionPool *TransactionPool
 	LatestBlocks          []string
+	Store                 *BlockStore
+	reorgListeners        []func(event ReorgEvent)
 }
 
 func NewBlockchain(genesisBlock *Block) *Blockchain {
 	blockchainF := new(Blockchain)
 	blockchainF.BlockChain = make(map[string]*BlockNode)
 
+	genesisNode := NewBlockNode(genesisBlock, nil)
 	genesisUTXOPool := NewUTXOPool()
-	genesisUTXOPool.Put(*NewUTXO(genesisBlock.GetCoinbase().GetHash(),
-		0), *genesisBlock.GetCoinbase().GetOutput(0))
+	genesisUTXOPool.Height = 1
+	genesisUTXOPool.MedianTime = MedianTimePast(genesisNode)
+	genesisUTXOPool.PutEntry(*NewUTXO(genesisBlock.GetCoinbase().GetHash(), 0),
+		UTXOEntry{Output: *genesisBlock.GetCoinbase().GetOutput(0), Height: 1, Coinbase: true, Time: genesisUTXOPool.MedianTime})
 
-	genesisNode := NewBlockNode(genesisBlock, nil, genesisUTXOPool)
+	genesisNode.Supply, _ = transactionValue(genesisBlock.GetCoinbase())
+	blockchainF.UTXOSet = genesisUTXOPool
 
 	blockchainF.BlockChain[keyFoBlock(genesisBlock.GetHash())] = genesisNode
 
@@ -69,6 +90,40 @@ func NewBlockchain(genesisBlock *Block) *Blockchain {
 	return blockchainF
 }
 
+// NewBlockchainWithStore creates a blockchain backed by an on-disk block store.
+// An empty store is initialised with genesisBlock. Otherwise the first stored block is used as
+// the genesis and every following block is replayed through BlockAdd, which rebuilds the
+// BlockNode tree and the UTXO pools. Blocks accepted afterwards are appended to the store.
+// If BlockAdd rejects a stored block, an error wrapping ErrStoredBlockRejected is returned
+// instead of a chain silently missing that block and its descendants.
+func NewBlockchainWithStore(genesisBlock *Block, store *BlockStore) (*Blockchain, error) {
+	if store.Len() == 0 {
+		if err := store.Append(genesisBlock); err != nil {
+			return nil, err
+		}
+		blockchainF := NewBlockchain(genesisBlock)
+		blockchainF.Store = store
+		return blockchainF, nil
+	}
+
+	blocks, err := store.Blocks()
+	if err != nil {
+		return nil, err
+	}
+	blockchainF := NewBlockchain(blocks[0])
+	rejected := 0
+	for _, block := range blocks[1:] {
+		if !blockchainF.BlockAdd(block) {
+			rejected++
+		}
+	}
+	if rejected > 0 {
+		return nil, fmt.Errorf("%w: %d of %d blocks", ErrStoredBlockRejected, rejected, len(blocks)-1)
+	}
+	blockchainF.Store = store
+	return blockchainF, nil
+}
+
 func keyFoBlock(blockHash []byte) string {
 	wrapper := NewByteArrayWrapper(blockHash)
 	return hex.EncodeToString(wrapper.contents)
@@ -78,12 +133,70 @@ func (blockChain *Blockchain) GetBlockAtMaxHeight() *Block {
 	return blockChain.MaxHeightNode[0].B
 }
 
+// GetBlockNodeAtMaxHeight returns the tip, the node with the greatest chain work and, among
+// those, the lowest block hash.
 func (blockChain *Blockchain) GetBlockNodeAtMaxHeight() *BlockNode {
 	return blockChain.MaxHeightNode[0]
 }
 
+// GetUTXOPoolAtMaxHeight returns a snapshot of the UTXO set at the tip.
 func (blockChain *Blockchain) GetUTXOPoolAtMaxHeight() *UTXOPool {
-	return blockChain.MaxHeightNode[0].GetUTXOPoolCopy()
+	return NewUTXOPoolWithPool(blockChain.UTXOSet)
+}
+
+// GetUTXOPoolAt returns a snapshot of the UTXO set as it was right after node.
+// The tip set is copied and moved to node with the undo data of the blocks in between.
+func (blockChain *Blockchain) GetUTXOPoolAt(node *BlockNode) *UTXOPool {
+	pool := NewUTXOPoolWithPool(blockChain.UTXOSet)
+	moveUTXOPool(pool, blockChain.MaxHeightNode[0], node)
+	return pool
+}
+
+// FindFork returns the most recent common ancestor of the two nodes.
+func FindFork(a *BlockNode, b *BlockNode) *BlockNode {
+	for a != nil && b != nil && a != b {
+		if a.Height > b.Height {
+			a = a.Parent
+		} else if b.Height > a.Height {
+			b = b.Parent
+		} else {
+			a = a.Parent
+			b = b.Parent
+		}
+	}
+	if a != b {
+		return nil
+	}
+	return a
+}
+
+// moveUTXOPool turns a pool that reflects from into one that reflects to, disconnecting
+// blocks back to their fork and reconnecting the blocks of the other branch.
+func moveUTXOPool(pool *UTXOPool, from *BlockNode, to *BlockNode) {
+	fork := FindFork(from, to)
+	for node := from; node != fork; node = node.Parent {
+		DisconnectBlock(pool, node.Undo)
+	}
+
+	path := make([]*BlockNode, 0)
+	for node := to; node != fork; node = node.Parent {
+		path = append(path, node)
+	}
+	for i := len(path) - 1; i >= 0; i-- {
+		pool.MedianTime = MedianTimePast(path[i].Parent)
+		reconnectBlock(pool, path[i].B)
+	}
+	pool.MedianTime = MedianTimePast(to)
+}
+
+// GetIssuedSupplyAt returns the number of coins in existence right after node.
+func (blockChain *Blockchain) GetIssuedSupplyAt(node *BlockNode) Amount {
+	return node.Supply
+}
+
+// GetIssuedSupplyAtMaxHeight returns the number of coins in existence at the tip.
+func (blockChain *Blockchain) GetIssuedSupplyAtMaxHeight() Amount {
+	return blockChain.MaxHeightNode[0].Supply
 }
 
 func (blockChain *Blockchain) GetTransactionPool() *TransactionPool {
@@ -100,44 +213,84 @@ func (blockChain *Blockchain) BlockAdd(block *Block) bool {
 		return false
 	}
 
+	if block.GetVersion() < 1 || !bytes.Equal(block.GetHash(), block.CalculateHash()) {
+		return false
+	}
+	if !bytes.Equal(block.GetMerkleRoot(), block.ComputeMerkleRoot()) ||
+		!bytes.Equal(block.GetWitnessRoot(), block.ComputeWitnessRoot()) {
+		return false
+	}
+	if block.GetTimestamp() < MedianTimePast(parentBlock) ||
+		block.GetTimestamp() > time.Now().Unix()+MAX_FUTURE_BLOCK_TIME {
+		return false
+	}
+	if block.GetBits() != NextWorkRequired(parentBlock) || !HashMeetsTarget(block.GetHash(), block.GetBits()) {
+		return false
+	}
+
 	newHeight := int(parentBlock.Height + 1)
 	maxValidHeight := int(blockChain.MaxHeightNode[0].Height) - CUT_OFF_AGE
 	if newHeight <= maxValidHeight {
 		return false
 	}
 
-	utxo := parentBlock.GetUTXOPoolCopy()
-	HandleTxs(utxo)
-
-	blockTxs := block.GetTransactions()
-	validTxs := Handler(blockTxs)
-
-	if len(validTxs) != len(blockTxs) {
+	// The coinbase has to be the first transaction and the only one.
+	transactions := block.GetTransactions()
+	coinbaseTransaction := block.GetCoinbase()
+	if coinbaseTransaction == nil || len(transactions) == 0 || transactions[0] != coinbaseTransaction {
 		return false
 	}
+	for i, transaction := range transactions {
+		if transaction.IsCoinbase() != (i == 0) {
+			return false
+		}
+	}
 
-	utxo = UTXOPoolGet()
-
-	coinbaseTransaction := block.GetCoinbase()
-	if coinbaseTransaction == nil || !CheckCoinbaseTransaction(coinbaseTransaction) {
+	// Validate against the UTXO set of the parent, moving the tip set there if the
+	// block extends another branch.
+	tip := blockChain.MaxHeightNode[0]
+	moveUTXOPool(blockChain.UTXOSet, tip, parentBlock)
+	newNode := NewBlockNode(block, parentBlock)
+	undo, fees, ok := ConnectBlock(blockChain.UTXOSet, block)
+	if ok && !CheckCoinbaseTransaction(coinbaseTransaction, uint(newHeight), fees) {
+		DisconnectBlock(blockChain.UTXOSet, undo)
+		ok = false
+	}
+	if !ok {
+		moveUTXOPool(blockChain.UTXOSet, parentBlock, tip)
 		return false
 	}
 
-	for i, output := range coinbaseTransaction.Outputs {
-		utxo.Put(UTXO{txHash: coinbaseTransaction.GetHash(), index: i}, *output)
+	if blockChain.Store != nil {
+		if err := blockChain.Store.Append(block); err != nil {
+			DisconnectBlock(blockChain.UTXOSet, undo)
+			moveUTXOPool(blockChain.UTXOSet, parentBlock, tip)
+			return false
+		}
 	}
 
-	newNode := NewBlockNode(block, parentBlock, utxo)
+	blockChain.UTXOSet.MedianTime = MedianTimePast(newNode)
+	newNode.Undo = undo
+	coinbaseValue, _ := transactionValue(coinbaseTransaction)
+	newNode.Supply = parentBlock.Supply + coinbaseValue - fees
 	blochHash := keyFoBlock(block.GetHash())
 
 	blockChain.BlockChain[blochHash] = newNode
 	parentBlock.Children = append(parentBlock.Children, newNode)
 	blockChain.LatestBlocks = append(blockChain.LatestBlocks, blochHash)
 
-	if newNode.Height > blockChain.MaxHeightNode[0].Height {
+	switch newNode.ChainWork.Cmp(blockChain.MaxHeightNode[0].ChainWork) {
+	case 1:
 		blockChain.MaxHeightNode = []*BlockNode{newNode}
-	} else if newNode.Height == blockChain.MaxHeightNode[0].Height {
-		blockChain.MaxHeightNode = append(blockChain.MaxHeightNode, newNode)
+	case 0:
+		if bytes.Compare(block.GetHash(), blockChain.MaxHeightNode[0].B.GetHash()) < 0 {
+			blockChain.MaxHeightNode = append([]*BlockNode{newNode}, blockChain.MaxHeightNode...)
+		} else {
+			blockChain.MaxHeightNode = append(blockChain.MaxHeightNode, newNode)
+		}
+	}
+	if blockChain.MaxHeightNode[0] != newNode {
+		moveUTXOPool(blockChain.UTXOSet, newNode, tip)
 	}
 
 	if len(blockChain.LatestBlocks) > MAX_BLOCKS_INT_MEMORY {
@@ -146,32 +299,127 @@ func (blockChain *Blockchain) BlockAdd(block *Block) bool {
 		blockChain.LatestBlocks = blockChain.LatestBlocks[1:]
 	}
 
-	for _, transaction := range blockTxs {
-		blockChain.GlobalTransactionPool.RemoveTransaction(transaction.Hash)
+	// The pool only changes when the block becomes the new tip.
+	if blockChain.MaxHeightNode[0] != newNode {
+		return true
+	}
+	if parentBlock == tip {
+		for _, transaction := range block.GetTransactions() {
+			blockChain.GlobalTransactionPool.RemoveTransaction(transaction.Hash)
+		}
+		blockChain.evictConflicts()
+		return true
+	}
+
+	event := blockChain.reorganize(tip, newNode)
+	for _, listener := range blockChain.reorgListeners {
+		listener(event)
 	}
 	return true
 }
 
-func CheckCoinbaseTransaction(tx *Transaction) bool {
-	if tx == nil {
+// MedianTimePast returns the median timestamp of node and up to MEDIAN_TIME_SPAN-1 of its ancestors.
+func MedianTimePast(node *BlockNode) int64 {
+	timestamps := make([]int64, 0, MEDIAN_TIME_SPAN)
+	for current := node; current != nil && len(timestamps) < MEDIAN_TIME_SPAN; current = current.Parent {
+		timestamps = append(timestamps, current.B.GetTimestamp())
+	}
+	if len(timestamps) == 0 {
+		return 0
+	}
+
+	sort.Slice(timestamps, func(i, j int) bool {
+		return timestamps[i] < timestamps[j]
+	})
+	return timestamps[len(timestamps)/2]
+}
+
+// CheckCoinbaseTransaction reports whether the coinbase creates coins only from nothing and claims
+// at most the block reward, the subsidy at height plus the fees of the other transactions in the block.
+func CheckCoinbaseTransaction(tx *Transaction, height uint, fees Amount) bool {
+	if tx == nil || len(tx.GetInputs()) > 0 {
 		return false
 	}
-	coins := 0.0
-	for _, output := range tx.GetOutputs() {
-		coins += output.Value
+	coins, err := transactionValue(tx)
+	if err != nil {
+		return false
 	}
+	reward, ok := BlockSubsidy(height).Add(fees)
+	return ok && coins <= reward
+}
 
-	const tolerance = 0.00001
-	return math.Abs(coins-COINBASE) <= tolerance
+// transactionValue returns the sum of the output values of tx.
+func transactionValue(tx *Transaction) (Amount, error) {
+	value := Amount(0)
+	for _, output := range tx.GetOutputs() {
+		sum, err := SumAmounts(value, output.Value)
+		if err != nil {
+			return 0, err
+		}
+		value = sum
+	}
+	return value, nil
 }
 
 func (blockChain *Blockchain) Get(parentHash []byte) *BlockNode {
 	return blockChain.BlockChain[keyFoBlock(parentHash)]
 }
 
-func (blockChain *Blockchain) TransactionAdd(tx *Transaction) {
-	utxo := blockChain.GetUTXOPoolAtMaxHeight()
-	if TxIsValid(*tx, utxo) {
-		blockChain.GlobalTransactionPool.AddTransaction(tx)
+// TransactionAdd adds tx to the transaction pool if it is valid on top of the UTXO set and
+// the pooled transactions, so it may spend outputs that are not confirmed yet.
+// A transaction spending outputs already spent in the pool replaces the conflicting ones if
+// the pool allows it; the replaced transactions are returned.
+func (blockChain *Blockchain) TransactionAdd(tx *Transaction) ([]*Transaction, bool) {
+	blockChain.GlobalTransactionPool.Expire(time.Now().Unix())
+	replaced, _, ok := blockChain.acceptToPool(tx)
+	return replaced, ok
+}
+
+// acceptToPool adds tx to the transaction pool if it is valid against mempoolView without the
+// transactions it replaces and stays in the pool once added. It returns the replaced transactions
+// and those evicted to make room.
+func (blockChain *Blockchain) acceptToPool(tx *Transaction) ([]*Transaction, []*Transaction, bool) {
+	txPool := blockChain.GlobalTransactionPool
+	// A coinbase only belongs at the start of the block that creates it.
+	if tx.IsCoinbase() || txPool.GetEntry(tx.GetHash()) != nil {
+		return nil, nil, false
+	}
+	replaced := txPool.Conflicts(tx)
+	view := blockChain.mempoolView(tx, replaced)
+	if !TxIsValid(*tx, view) {
+		return nil, nil, false
+	}
+	fee := GetFee(tx, view)
+	if !txPool.CanReplace(fee, tx.Size(), replaced) {
+		return nil, nil, false
+	}
+	evicted, ok := txPool.Replace(tx, fee, replaced)
+	if !ok {
+		return nil, nil, false
+	}
+	return replaced, evicted, true
+}
+
+// mempoolView returns the outputs tx spends, taken from the UTXO set or, as if they were in
+// the next block, from the pooled transactions except those in exclude.
+func (blockChain *Blockchain) mempoolView(tx *Transaction, exclude []*Transaction) *UTXOPool {
+	excluded := make(map[string]bool, len(exclude))
+	for _, replaced := range exclude {
+		excluded[keyFor(replaced.GetHash())] = true
+	}
+	view := NewUTXOPool()
+	view.Height, view.MedianTime = blockChain.UTXOSet.Height, blockChain.UTXOSet.MedianTime
+	for _, in := range tx.Inputs {
+		utxo := NewUTXO(in.PrevTxHash, in.OutputIndex)
+		if entry := blockChain.UTXOSet.GetEntry(*utxo); entry != nil {
+			view.PutEntry(*utxo, *entry)
+			continue
+		}
+		parent := blockChain.GlobalTransactionPool.GetTransaction(in.PrevTxHash)
+		if parent == nil || excluded[keyFor(in.PrevTxHash)] || in.OutputIndex < 0 || in.OutputIndex >= len(parent.Outputs) {
+			continue
+		}
+		view.PutEntry(*utxo, UTXOEntry{Output: *parent.Outputs[in.OutputIndex], Height: view.Height + 1, Time: view.MedianTime})
 	}
+	return view
 }
@@ -1,6 +1,7 @@
 package third_faza
 
 import (
+	"bytes"
 	"crypto/rand"
 	"crypto/rsa"
 	"fmt"
@@ -40,6 +41,8 @@ func TestBlockchain_processBlock_Without_Transaction(t *testing.T) {
 }
 
 func TestBlockchain_processBlock_With_One_Transaction(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
 	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
 	if err != nil {
 		log.Fatal(err)
@@ -61,9 +64,9 @@ func TestBlockchain_processBlock_With_One_Transaction(t *testing.T) {
 	tx1 := NewTransaction()
 	tx1.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
 
-	tx1.AddOutput(1, pubKeyBob)
-	tx1.AddOutput(1, pubKeyAlice)
-	tx1.AddOutput(1.120, pubKeyBob)
+	tx1.AddOutput(1*COIN, pubKeyBob)
+	tx1.AddOutput(1*COIN, pubKeyAlice)
+	tx1.AddOutput(1.120*COIN, pubKeyBob)
 
 	tx1.SignTx(privateKeyBob, 0)
 
@@ -76,6 +79,8 @@ func TestBlockchain_processBlock_With_One_Transaction(t *testing.T) {
 }
 
 func TestBlockchain_processBlock_With_A_Lot_Transaction(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
 	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
 	if err != nil {
 		log.Fatal(err)
@@ -97,14 +102,14 @@ func TestBlockchain_processBlock_With_A_Lot_Transaction(t *testing.T) {
 
 	tx1 := NewTransaction()
 	tx1.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
-	tx1.AddOutput(1, pubKeyAlice)
-	tx1.AddOutput(2.125, pubKeyBob)
+	tx1.AddOutput(1*COIN, pubKeyAlice)
+	tx1.AddOutput(2.125*COIN, pubKeyBob)
 	tx1.SignTx(privateKeyBob, 0)
 
 	tx2 := NewTransaction()
 	tx2.AddInput(tx1.GetHash(), 1)
-	tx2.AddOutput(1, pubKeyBob)
-	tx2.AddOutput(1.125, pubKeyAlice)
+	tx2.AddOutput(1*COIN, pubKeyBob)
+	tx2.AddOutput(1.125*COIN, pubKeyAlice)
 	tx2.SignTx(privateKeyBob, 0)
 
 	block1.TransactionAdd(tx1)
@@ -117,8 +122,8 @@ func TestBlockchain_processBlock_With_A_Lot_Transaction(t *testing.T) {
 	block2 := NewBlock(block1.GetHash(), pubKeyBob)
 	tx1 = NewTransaction()
 	tx1.AddInput(block1.GetCoinbase().GetHash(), 0)
-	tx1.AddOutput(1, pubKeyBob)
-	tx1.AddOutput(1.125, pubKeyBob)
+	tx1.AddOutput(1*COIN, pubKeyBob)
+	tx1.AddOutput(1.125*COIN, pubKeyBob)
 	tx1.SignTx(privateKeyAlice, 0)
 
 	block2.TransactionAdd(tx1)
@@ -129,6 +134,8 @@ func TestBlockchain_processBlock_With_A_Lot_Transaction(t *testing.T) {
 }
 
 func TestBlockchain_processBlock_With_Some_DoubleSpend(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
 	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
 	if err != nil {
 		log.Fatal(err)
@@ -150,14 +157,14 @@ func TestBlockchain_processBlock_With_Some_DoubleSpend(t *testing.T) {
 
 	tx1 := NewTransaction()
 	tx1.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
-	tx1.AddOutput(1, pubKeyAlice)
-	tx1.AddOutput(2.125, pubKeyBob)
+	tx1.AddOutput(1*COIN, pubKeyAlice)
+	tx1.AddOutput(2.125*COIN, pubKeyBob)
 	tx1.SignTx(privateKeyBob, 0)
 
 	tx2 := NewTransaction()
 	tx2.AddInput(tx1.GetHash(), 1)
-	tx2.AddOutput(1, pubKeyBob)
-	tx2.AddOutput(1.125, pubKeyAlice)
+	tx2.AddOutput(1*COIN, pubKeyBob)
+	tx2.AddOutput(1.125*COIN, pubKeyAlice)
 	tx2.SignTx(privateKeyBob, 0)
 
 	block1.TransactionAdd(tx1)
@@ -170,14 +177,14 @@ func TestBlockchain_processBlock_With_Some_DoubleSpend(t *testing.T) {
 	block2 := NewBlock(block1.GetHash(), pubKeyBob)
 	tx1 = NewTransaction()
 	tx1.AddInput(block1.GetCoinbase().GetHash(), 0)
-	tx1.AddOutput(1, pubKeyBob)
-	tx1.AddOutput(1.125, pubKeyBob)
+	tx1.AddOutput(1*COIN, pubKeyBob)
+	tx1.AddOutput(1.125*COIN, pubKeyBob)
 	tx1.SignTx(privateKeyAlice, 0)
 	block2.TransactionAdd(tx1)
 
 	tx2 = NewTransaction()
 	tx2.AddInput(block1.GetCoinbase().GetHash(), 0)
-	tx2.AddOutput(1, pubKeyBob)
+	tx2.AddOutput(1*COIN, pubKeyBob)
 	tx2.SignTx(privateKeyAlice, 0)
 	block2.TransactionAdd(tx2)
 
@@ -214,6 +221,8 @@ func TestBlockchain_processBlock_With_New_GenesisBlock(t *testing.T) {
 }
 
 func TestBlockchain_processBlock_With_Incorrect_PrevBlockHash(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
 	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
 	if err != nil {
 		log.Fatal(err)
@@ -235,14 +244,14 @@ func TestBlockchain_processBlock_With_Incorrect_PrevBlockHash(t *testing.T) {
 
 	tx1 := NewTransaction()
 	tx1.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
-	tx1.AddOutput(1, pubKeyAlice)
-	tx1.AddOutput(2.125, pubKeyBob)
+	tx1.AddOutput(1*COIN, pubKeyAlice)
+	tx1.AddOutput(2.125*COIN, pubKeyBob)
 	tx1.SignTx(privateKeyBob, 0)
 
 	tx2 := NewTransaction()
 	tx2.AddInput(tx1.GetHash(), 1)
-	tx2.AddOutput(1, pubKeyBob)
-	tx2.AddOutput(1.125, pubKeyAlice)
+	tx2.AddOutput(1*COIN, pubKeyBob)
+	tx2.AddOutput(1.125*COIN, pubKeyAlice)
 	tx2.SignTx(privateKeyBob, 0)
 
 	block1.TransactionAdd(tx1)
@@ -266,8 +275,8 @@ func TestBlockchain_processBlock_With_Incorrect_PrevBlockHash(t *testing.T) {
 	block2 := NewBlock(invalidHash, pubKeyBob)
 	tx1 = NewTransaction()
 	tx1.AddInput(block1.GetCoinbase().GetHash(), 0)
-	tx1.AddOutput(1, pubKeyBob)
-	tx1.AddOutput(1.125, pubKeyBob)
+	tx1.AddOutput(1*COIN, pubKeyBob)
+	tx1.AddOutput(1.125*COIN, pubKeyBob)
 	tx1.SignTx(privateKeyAlice, 0)
 
 	block2.TransactionAdd(tx1)
@@ -278,6 +287,8 @@ func TestBlockchain_processBlock_With_Incorrect_PrevBlockHash(t *testing.T) {
 }
 
 func TestBlockchain_processBlock_With_DifferentTypeInvalidTransactions(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
 	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
 	if err != nil {
 		log.Fatal(err)
@@ -301,14 +312,14 @@ func TestBlockchain_processBlock_With_DifferentTypeInvalidTransactions(t *testin
 
 	tx1 := NewTransaction()
 	tx1.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
-	tx1.AddOutput(1, pubKeyAlice)
-	tx1.AddOutput(2.125, pubKeyBob)
+	tx1.AddOutput(1*COIN, pubKeyAlice)
+	tx1.AddOutput(2.125*COIN, pubKeyBob)
 	tx1.SignTx(privateKeyBob, 0)
 
 	tx2 := NewTransaction()
 	tx2.AddInput(tx1.GetHash(), 1)
-	tx2.AddOutput(1, pubKeyBob)
-	tx2.AddOutput(1.125, pubKeyAlice)
+	tx2.AddOutput(1*COIN, pubKeyBob)
+	tx2.AddOutput(1.125*COIN, pubKeyAlice)
 	tx2.SignTx(privateKeyBob, 0)
 
 	block1.TransactionAdd(tx1)
@@ -324,27 +335,27 @@ func TestBlockchain_processBlock_With_DifferentTypeInvalidTransactions(t *testin
 	// 1. Double spending the same input as tx1
 	doubleSpendTx := NewTransaction()
 	doubleSpendTx.AddInput(block1.GetCoinbase().GetHash(), 0)
-	doubleSpendTx.AddOutput(1, pubKeyBob)
+	doubleSpendTx.AddOutput(1*COIN, pubKeyBob)
 	doubleSpendTx.SignTx(privateKeyAlice, 0)
 	block2.TransactionAdd(doubleSpendTx)
 
 	doubleSpendTx2 := NewTransaction()
 	doubleSpendTx2.AddInput(block1.GetCoinbase().GetHash(), 0) // same input reused
-	doubleSpendTx2.AddOutput(1, pubKeyBob)
+	doubleSpendTx2.AddOutput(1*COIN, pubKeyBob)
 	doubleSpendTx2.SignTx(privateKeyAlice, 0)
 	block2.TransactionAdd(doubleSpendTx2)
 
 	// 2. Invalid signature
 	invalidSigTx := NewTransaction()
 	invalidSigTx.AddInput(tx1.GetHash(), 0)
-	invalidSigTx.AddOutput(1, pubKeyBob)
+	invalidSigTx.AddOutput(1*COIN, pubKeyBob)
 	invalidSigTx.SignTx(privateKeyAlice, 0) // should be signed by Bob
 	block2.TransactionAdd(invalidSigTx)
 
 	// 3. Input < Output (overdraft)
 	overdraftTx := NewTransaction()
 	overdraftTx.AddInput(tx2.GetHash(), 0)
-	overdraftTx.AddOutput(5, pubKeyAlice) // more than available
+	overdraftTx.AddOutput(5*COIN, pubKeyAlice) // more than available
 	overdraftTx.SignTx(privateKeyBob, 0)
 	block2.TransactionAdd(overdraftTx)
 
@@ -357,7 +368,7 @@ func TestBlockchain_processBlock_With_DifferentTypeInvalidTransactions(t *testin
 	block2 = NewBlock(block1.GetHash(), pubKeyBob)
 	wrongTx := NewTransaction()
 	wrongTx.AddInput(tx2.GetHash(), 0)
-	wrongTx.AddOutput(10, pubKeyBob)
+	wrongTx.AddOutput(10*COIN, pubKeyBob)
 	wrongTx.SignTx(privateKeyBob, 0)
 	block2.TransactionAdd(wrongTx)
 	block2.Finalizee()
@@ -367,6 +378,8 @@ func TestBlockchain_processBlock_With_DifferentTypeInvalidTransactions(t *testin
 }
 
 func TestBlockchain_processBlock_WithFewBlocksAboveGenesisBlock(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
 	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
 	if err != nil {
 		log.Fatal(err)
@@ -388,7 +401,7 @@ func TestBlockchain_processBlock_WithFewBlocksAboveGenesisBlock(t *testing.T) {
 
 	tx1 := NewTransaction()
 	tx1.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
-	tx1.AddOutput(1, pubKeyAlice)
+	tx1.AddOutput(1*COIN, pubKeyAlice)
 	tx1.SignTx(privateKeyBob, 0)
 
 	block1.TransactionAdd(tx1)
@@ -400,14 +413,14 @@ func TestBlockchain_processBlock_WithFewBlocksAboveGenesisBlock(t *testing.T) {
 	block2 := NewBlock(block1.GetHash(), pubKeyBob)
 	tx1 = NewTransaction()
 	tx1.AddInput(block1.GetCoinbase().GetHash(), 0)
-	tx1.AddOutput(1, pubKeyBob)
-	tx1.AddOutput(1.125, pubKeyBob)
+	tx1.AddOutput(1*COIN, pubKeyBob)
+	tx1.AddOutput(1.125*COIN, pubKeyBob)
 	tx1.SignTx(privateKeyAlice, 0)
 	block2.TransactionAdd(tx1)
 
 	tx2 := NewTransaction()
 	tx2.AddInput(block1.GetCoinbase().GetHash(), 0)
-	tx2.AddOutput(1, pubKeyBob)
+	tx2.AddOutput(1*COIN, pubKeyBob)
 	tx2.SignTx(privateKeyAlice, 0)
 	block2.TransactionAdd(tx2)
 
@@ -420,7 +433,7 @@ func TestBlockchain_processBlock_WithFewBlocksAboveGenesisBlock(t *testing.T) {
 
 	tx3_1 := NewTransaction()
 	tx3_1.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
-	tx3_1.AddOutput(1, pubKeyAlice)
+	tx3_1.AddOutput(1*COIN, pubKeyAlice)
 	tx3_1.SignTx(privateKeyBob, 0)
 
 	block3.TransactionAdd(tx3_1)
@@ -433,7 +446,7 @@ func TestBlockchain_processBlock_WithFewBlocksAboveGenesisBlock(t *testing.T) {
 
 	tx4_1 := NewTransaction()
 	tx4_1.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
-	tx4_1.AddOutput(2, pubKeyAlice)
+	tx4_1.AddOutput(2*COIN, pubKeyAlice)
 	tx4_1.SignTx(privateKeyBob, 0)
 
 	block4.TransactionAdd(tx4_1)
@@ -447,6 +460,8 @@ func TestBlockchain_processBlock_WithFewBlocksAboveGenesisBlock(t *testing.T) {
 }
 
 func TestBlockchain_processBlock_ThatClaimsUTXO_whichHasAlreadyBeenClaimedByTransactionInTheParentBlock(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
 	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
 	if err != nil {
 		log.Fatal(err)
@@ -468,7 +483,7 @@ func TestBlockchain_processBlock_ThatClaimsUTXO_whichHasAlreadyBeenClaimedByTran
 
 	tx1 := NewTransaction()
 	tx1.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
-	tx1.AddOutput(1, pubKeyAlice)
+	tx1.AddOutput(1*COIN, pubKeyAlice)
 	tx1.SignTx(privateKeyBob, 0)
 
 	block1.TransactionAdd(tx1)
@@ -480,7 +495,7 @@ func TestBlockchain_processBlock_ThatClaimsUTXO_whichHasAlreadyBeenClaimedByTran
 	block2 := NewBlock(block1.GetHash(), pubKeyAlice)
 	tx1 = NewTransaction()
 	tx1.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
-	tx1.AddOutput(2, pubKeyAlice)
+	tx1.AddOutput(2*COIN, pubKeyAlice)
 	tx1.SignTx(privateKeyBob, 0)
 
 	block2.TransactionAdd(tx1)
@@ -491,6 +506,8 @@ func TestBlockchain_processBlock_ThatClaimsUTXO_whichHasAlreadyBeenClaimedByTran
 }
 
 func TestBlockchain_processBlock_WhichContaining_Transaction_ThatClaims_UTXO_From_Outside_Its_Branch(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
 	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
 	if err != nil {
 		log.Fatal(err)
@@ -512,7 +529,7 @@ func TestBlockchain_processBlock_WhichContaining_Transaction_ThatClaims_UTXO_Fro
 
 	txA_1 := NewTransaction()
 	txA_1.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
-	txA_1.AddOutput(1, pubKeyAlice)
+	txA_1.AddOutput(1*COIN, pubKeyAlice)
 	txA_1.SignTx(privateKeyBob, 0)
 
 	blockA.TransactionAdd(txA_1)
@@ -524,8 +541,8 @@ func TestBlockchain_processBlock_WhichContaining_Transaction_ThatClaims_UTXO_Fro
 	blockB_1 := NewBlock(blockA.GetHash(), pubKeyBob)
 	txB_1_1 := NewTransaction()
 	txB_1_1.AddInput(blockA.GetCoinbase().GetHash(), 0)
-	txB_1_1.AddOutput(1, pubKeyBob)
-	txB_1_1.AddOutput(1.125, pubKeyBob)
+	txB_1_1.AddOutput(1*COIN, pubKeyBob)
+	txB_1_1.AddOutput(1.125*COIN, pubKeyBob)
 	txB_1_1.SignTx(privateKeyAlice, 0)
 	blockB_1.TransactionAdd(txB_1_1)
 
@@ -538,8 +555,8 @@ func TestBlockchain_processBlock_WhichContaining_Transaction_ThatClaims_UTXO_Fro
 
 	txB_2_1 := NewTransaction()
 	txB_2_1.AddInput(blockA.GetCoinbase().GetHash(), 0)
-	txB_2_1.AddOutput(1, pubKeyAlice)
-	txB_2_1.AddOutput(1, pubKeyBob)
+	txB_2_1.AddOutput(1*COIN, pubKeyAlice)
+	txB_2_1.AddOutput(1*COIN, pubKeyBob)
 	txB_2_1.SignTx(privateKeyAlice, 0)
 
 	blockB_2.TransactionAdd(txB_2_1)
@@ -555,7 +572,7 @@ func TestBlockchain_processBlock_WhichContaining_Transaction_ThatClaims_UTXO_Fro
 
 	txC1_1 := NewTransaction()
 	txC1_1.AddInput(txB_2_1.GetHash(), 1)
-	txC1_1.AddOutput(1, pubKeyAlice)
+	txC1_1.AddOutput(1*COIN, pubKeyAlice)
 	txC1_1.SignTx(privateKeyBob, 0)
 
 	blockC1.TransactionAdd(txC1_1)
@@ -567,6 +584,8 @@ func TestBlockchain_processBlock_WhichContaining_Transaction_ThatClaims_UTXO_Fro
 }
 
 func TestBlockchain_processBlock_WhichContaining_Transaction_ThatClaimsAnOlderUTXO_WithinTheSameBranch_ThatHasNotYetBeenClaimed(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
 	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
 	if err != nil {
 		log.Fatal(err)
@@ -588,8 +607,8 @@ func TestBlockchain_processBlock_WhichContaining_Transaction_ThatClaimsAnOlderUT
 
 	txA_1 := NewTransaction()
 	txA_1.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
-	txA_1.AddOutput(1, pubKeyAlice)
-	txA_1.AddOutput(2.125, pubKeyBob)
+	txA_1.AddOutput(1*COIN, pubKeyAlice)
+	txA_1.AddOutput(2.125*COIN, pubKeyBob)
 	txA_1.SignTx(privateKeyBob, 0)
 
 	blockA.TransactionAdd(txA_1)
@@ -601,14 +620,14 @@ func TestBlockchain_processBlock_WhichContaining_Transaction_ThatClaimsAnOlderUT
 	blockB := NewBlock(blockA.GetHash(), pubKeyBob)
 	txB_1 := NewTransaction()
 	txB_1.AddInput(blockA.GetCoinbase().GetHash(), 0)
-	txB_1.AddOutput(1, pubKeyBob)
-	txB_1.AddOutput(2.125, pubKeyAlice)
+	txB_1.AddOutput(1*COIN, pubKeyBob)
+	txB_1.AddOutput(2.125*COIN, pubKeyAlice)
 	txB_1.SignTx(privateKeyAlice, 0)
 
 	txB_2 := NewTransaction()
 	txB_2.AddInput(txA_1.GetHash(), 1)
-	txB_2.AddOutput(1, pubKeyBob)
-	txB_2.AddOutput(1.125, pubKeyAlice)
+	txB_2.AddOutput(1*COIN, pubKeyBob)
+	txB_2.AddOutput(1.125*COIN, pubKeyAlice)
 	txB_2.SignTx(privateKeyBob, 0)
 
 	blockB.TransactionAdd(txB_2)
@@ -622,7 +641,7 @@ func TestBlockchain_processBlock_WhichContaining_Transaction_ThatClaimsAnOlderUT
 	blockC := NewBlock(blockB.GetHash(), pubKeyBob)
 	txC_1 := NewTransaction()
 	txC_1.AddInput(txA_1.GetHash(), 0)
-	txC_1.AddOutput(1, pubKeyBob)
+	txC_1.AddOutput(1*COIN, pubKeyBob)
 	txC_1.SignTx(privateKeyAlice, 0)
 
 	blockC.TransactionAdd(txC_1)
@@ -633,6 +652,8 @@ func TestBlockchain_processBlock_WhichContaining_Transaction_ThatClaimsAnOlderUT
 }
 
 func TestBlockchain_processBlock_LinearChainOfBlocks(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
 	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
 	if err != nil {
 		log.Fatal(err)
@@ -654,8 +675,8 @@ func TestBlockchain_processBlock_LinearChainOfBlocks(t *testing.T) {
 
 	txA_1 := NewTransaction()
 	txA_1.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
-	txA_1.AddOutput(1, pubKeyAlice)
-	txA_1.AddOutput(2.125, pubKeyBob)
+	txA_1.AddOutput(1*COIN, pubKeyAlice)
+	txA_1.AddOutput(2.125*COIN, pubKeyBob)
 	txA_1.SignTx(privateKeyBob, 0)
 
 	blockA.TransactionAdd(txA_1)
@@ -667,14 +688,14 @@ func TestBlockchain_processBlock_LinearChainOfBlocks(t *testing.T) {
 	blockB := NewBlock(blockA.GetHash(), pubKeyBob)
 	txB_1 := NewTransaction()
 	txB_1.AddInput(blockA.GetCoinbase().GetHash(), 0)
-	txB_1.AddOutput(1, pubKeyBob)
-	txB_1.AddOutput(2.125, pubKeyAlice)
+	txB_1.AddOutput(1*COIN, pubKeyBob)
+	txB_1.AddOutput(2.125*COIN, pubKeyAlice)
 	txB_1.SignTx(privateKeyAlice, 0)
 
 	txB_2 := NewTransaction()
 	txB_2.AddInput(txA_1.GetHash(), 1)
-	txB_2.AddOutput(1, pubKeyBob)
-	txB_2.AddOutput(1.125, pubKeyAlice)
+	txB_2.AddOutput(1*COIN, pubKeyBob)
+	txB_2.AddOutput(1.125*COIN, pubKeyAlice)
 	txB_2.SignTx(privateKeyBob, 0)
 
 	blockB.TransactionAdd(txB_2)
@@ -688,7 +709,7 @@ func TestBlockchain_processBlock_LinearChainOfBlocks(t *testing.T) {
 	blockC := NewBlock(blockB.GetHash(), pubKeyBob)
 	txC_1 := NewTransaction()
 	txC_1.AddInput(txA_1.GetHash(), 0)
-	txC_1.AddOutput(1, pubKeyBob)
+	txC_1.AddOutput(1*COIN, pubKeyBob)
 	txC_1.SignTx(privateKeyAlice, 0)
 
 	blockC.TransactionAdd(txC_1)
@@ -700,14 +721,14 @@ func TestBlockchain_processBlock_LinearChainOfBlocks(t *testing.T) {
 	blockD := NewBlock(blockC.GetHash(), pubKeyBob)
 	txD_1 := NewTransaction()
 	txD_1.AddInput(blockC.GetCoinbase().GetHash(), 0)
-	txD_1.AddOutput(1, pubKeyBob)
-	txD_1.AddOutput(2.125, pubKeyAlice)
+	txD_1.AddOutput(1*COIN, pubKeyBob)
+	txD_1.AddOutput(2.125*COIN, pubKeyAlice)
 	txD_1.SignTx(privateKeyBob, 0)
 
 	txD_2 := NewTransaction()
 	txD_2.AddInput(txB_2.GetHash(), 1)
-	txD_2.AddOutput(1, pubKeyBob)
-	txD_2.AddOutput(0.125, pubKeyAlice)
+	txD_2.AddOutput(1*COIN, pubKeyBob)
+	txD_2.AddOutput(0.125*COIN, pubKeyAlice)
 	txD_2.SignTx(privateKeyAlice, 0)
 
 	blockD.TransactionAdd(txD_1)
@@ -837,8 +858,8 @@ func TestBlockchain_createBlock_AfterProcessingOneValidTransaction(t *testing.T)
 
 	tx := NewTransaction()
 	tx.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
-	tx.AddOutput(1, pubKeyBob)
-	tx.AddOutput(2.125, pubKeyAlice)
+	tx.AddOutput(1*COIN, pubKeyBob)
+	tx.AddOutput(2.125*COIN, pubKeyAlice)
 	tx.SignTx(privateKeyBob, 0)
 
 	TxProcess(tx)
@@ -868,8 +889,8 @@ func TestBlockchain_createBlock_AfterProcessingValidTransaction_AndThenCreateSec
 
 	tx := NewTransaction()
 	tx.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
-	tx.AddOutput(1, pubKeyBob)
-	tx.AddOutput(2.125, pubKeyAlice)
+	tx.AddOutput(1*COIN, pubKeyBob)
+	tx.AddOutput(2.125*COIN, pubKeyAlice)
 	tx.SignTx(privateKeyBob, 0)
 
 	TxProcess(tx)
@@ -879,15 +900,15 @@ func TestBlockchain_createBlock_AfterProcessingValidTransaction_AndThenCreateSec
 
 	tx2 := NewTransaction()
 	tx2.AddInput(tx.GetHash(), 1)
-	tx2.AddOutput(1, pubKeyAlice)
-	tx2.AddOutput(1.125, pubKeyBob)
+	tx2.AddOutput(1*COIN, pubKeyAlice)
+	tx2.AddOutput(1.125*COIN, pubKeyBob)
 	tx2.SignTx(privateKeyAlice, 0)
 
 	TxProcess(tx2)
 
 	tx3 := NewTransaction()
 	tx3.AddInput(tx2.GetHash(), 1)
-	tx3.AddOutput(0.125, pubKeyAlice)
+	tx3.AddOutput(0.125*COIN, pubKeyAlice)
 	tx3.SignTx(privateKeyBob, 0)
 
 	TxProcess(tx3)
@@ -897,6 +918,8 @@ func TestBlockchain_createBlock_AfterProcessingValidTransaction_AndThenCreateSec
 }
 
 func TestBlockchain_createBlock_AfterProcessingValidTransaction_WhichIsAlreadyInABlockInTheLongestValidBranch(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
 	privateKeyBob, _ := rsa.GenerateKey(rand.Reader, 1024)
 	pubKeyBob := &privateKeyBob.PublicKey
 
@@ -913,8 +936,8 @@ func TestBlockchain_createBlock_AfterProcessingValidTransaction_WhichIsAlreadyIn
 	// 2. Create a valid transaction and process it into the global pool
 	tx := NewTransaction()
 	tx.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
-	tx.AddOutput(1, pubKeyBob)
-	tx.AddOutput(2.125, pubKeyAlice)
+	tx.AddOutput(1*COIN, pubKeyBob)
+	tx.AddOutput(2.125*COIN, pubKeyAlice)
 	tx.SignTx(privateKeyBob, 0)
 
 	TxProcess(tx)
@@ -936,6 +959,8 @@ func TestBlockchain_createBlock_AfterProcessingValidTransaction_WhichIsAlreadyIn
 }
 
 func TestBlockchain_createBlock_AfterProcessingValidTransaction_WhichUsesAUTXOThatHasAlreadyBeenClaimedByATransactionInTheLongestValidChain(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
 	privateKeyBob, _ := rsa.GenerateKey(rand.Reader, 1024)
 	pubKeyBob := &privateKeyBob.PublicKey
 
@@ -952,8 +977,8 @@ func TestBlockchain_createBlock_AfterProcessingValidTransaction_WhichUsesAUTXOTh
 	// 2. Create a valid transaction and process it into the global pool
 	tx := NewTransaction()
 	tx.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
-	tx.AddOutput(1, pubKeyBob)
-	tx.AddOutput(2.125, pubKeyAlice)
+	tx.AddOutput(1*COIN, pubKeyBob)
+	tx.AddOutput(2.125*COIN, pubKeyAlice)
 	tx.SignTx(privateKeyBob, 0)
 
 	TxProcess(tx)
@@ -966,8 +991,8 @@ func TestBlockchain_createBlock_AfterProcessingValidTransaction_WhichUsesAUTXOTh
 	// 4. Try to process the same transaction again
 	tx2 := NewTransaction()
 	tx2.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
-	tx2.AddOutput(1, pubKeyBob)
-	tx2.AddOutput(2.125, pubKeyAlice)
+	tx2.AddOutput(1*COIN, pubKeyBob)
+	tx2.AddOutput(2.125*COIN, pubKeyAlice)
 	tx2.SignTx(privateKeyBob, 0)
 
 	// 5. Create Block B (should not include tx again)
@@ -979,6 +1004,8 @@ func TestBlockchain_createBlock_AfterProcessingValidTransaction_WhichUsesAUTXOTh
 }
 
 func TestBlockchain_createBlock_AfterProcessingAValidTransactionThatIsNotADoubleSpendInTheLongestValidBranch_AndHasntBeenUsedInAnyOtherBlockYet(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
 	privateKeyBob, _ := rsa.GenerateKey(rand.Reader, 1024)
 	pubKeyBob := &privateKeyBob.PublicKey
 
@@ -995,8 +1022,8 @@ func TestBlockchain_createBlock_AfterProcessingAValidTransactionThatIsNotADouble
 	// 2. Create a valid transaction and process it into the global pool
 	tx := NewTransaction()
 	tx.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
-	tx.AddOutput(1, pubKeyBob)
-	tx.AddOutput(2.125, pubKeyAlice)
+	tx.AddOutput(1*COIN, pubKeyBob)
+	tx.AddOutput(2.125*COIN, pubKeyAlice)
 	tx.SignTx(privateKeyBob, 0)
 
 	TxProcess(tx)
@@ -1009,8 +1036,8 @@ func TestBlockchain_createBlock_AfterProcessingAValidTransactionThatIsNotADouble
 	// 4. Try to process the same transaction again
 	tx2 := NewTransaction()
 	tx2.AddInput(blockA.GetCoinbase().GetHash(), 0)
-	tx2.AddOutput(1, pubKeyBob)
-	tx2.AddOutput(2.125, pubKeyAlice)
+	tx2.AddOutput(1*COIN, pubKeyBob)
+	tx2.AddOutput(2.125*COIN, pubKeyAlice)
 	tx2.SignTx(privateKeyAlice, 0)
 
 	TxProcess(tx2)
@@ -1023,16 +1050,16 @@ func TestBlockchain_createBlock_AfterProcessingAValidTransactionThatIsNotADouble
 
 	tx3 := NewTransaction()
 	tx3.AddInput(tx2.GetHash(), 1)
-	tx3.AddOutput(1, pubKeyBob)
-	tx3.AddOutput(1.125, pubKeyAlice)
+	tx3.AddOutput(1*COIN, pubKeyBob)
+	tx3.AddOutput(1.125*COIN, pubKeyAlice)
 	tx3.SignTx(privateKeyAlice, 0)
 
 	TxProcess(tx3)
 
 	tx4 := NewTransaction()
 	tx4.AddInput(tx.GetHash(), 1)
-	tx4.AddOutput(1, pubKeyBob)
-	tx4.AddOutput(1.125, pubKeyAlice)
+	tx4.AddOutput(1*COIN, pubKeyBob)
+	tx4.AddOutput(1.125*COIN, pubKeyAlice)
 	tx4.SignTx(privateKeyAlice, 0)
 
 	TxProcess(tx4)
@@ -1043,6 +1070,8 @@ func TestBlockchain_createBlock_AfterProcessingAValidTransactionThatIsNotADouble
 }
 
 func TestBlockchain_createBlock_AfterProcessingOnlyInvalidTransactions(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
 	privateKeyBob, _ := rsa.GenerateKey(rand.Reader, 1024)
 	pubKeyBob := &privateKeyBob.PublicKey
 
@@ -1059,8 +1088,8 @@ func TestBlockchain_createBlock_AfterProcessingOnlyInvalidTransactions(t *testin
 	// 2. Create a valid transaction and process it into the global pool
 	tx := NewTransaction()
 	tx.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
-	tx.AddOutput(1, pubKeyBob)
-	tx.AddOutput(2.125, pubKeyAlice)
+	tx.AddOutput(1*COIN, pubKeyBob)
+	tx.AddOutput(2.125*COIN, pubKeyAlice)
 	tx.SignTx(privateKeyBob, 0)
 
 	TxProcess(tx)
@@ -1081,16 +1110,16 @@ func TestBlockchain_createBlock_AfterProcessingOnlyInvalidTransactions(t *testin
 	}
 
 	tx3.AddInput(fakeHash, 0)
-	tx3.AddOutput(1, pubKeyBob)
-	tx3.AddOutput(2.125, pubKeyAlice)
+	tx3.AddOutput(1*COIN, pubKeyBob)
+	tx3.AddOutput(2.125*COIN, pubKeyAlice)
 	tx3.SignTx(privateKeyAlice, 0)
 
 	TxProcess(tx3)
 
 	tx4 := NewTransaction()
 	tx4.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
-	tx4.AddOutput(1, pubKeyBob)
-	tx4.AddOutput(2.125, pubKeyAlice)
+	tx4.AddOutput(1*COIN, pubKeyBob)
+	tx4.AddOutput(2.125*COIN, pubKeyAlice)
 	tx4.SignTx(privateKeyBob, 0)
 
 	TxProcess(tx4)
@@ -1103,6 +1132,8 @@ func TestBlockchain_createBlock_AfterProcessingOnlyInvalidTransactions(t *testin
 }
 
 func TestBlockchain_processTransaction_CreateBlock_ProcessAnotherTransaction_CreateBlock(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
 	privateKeyBob, _ := rsa.GenerateKey(rand.Reader, 1024)
 	pubKeyBob := &privateKeyBob.PublicKey
 
@@ -1121,8 +1152,8 @@ func TestBlockchain_processTransaction_CreateBlock_ProcessAnotherTransaction_Cre
 
 	tx := NewTransaction()
 	tx.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
-	tx.AddOutput(2, pubKeyBob)
-	tx.AddOutput(1.125, pubKeyAlice)
+	tx.AddOutput(2*COIN, pubKeyBob)
+	tx.AddOutput(1.125*COIN, pubKeyAlice)
 	tx.SignTx(privateKeyBob, 0)
 
 	TxProcess(tx)
@@ -1133,8 +1164,8 @@ func TestBlockchain_processTransaction_CreateBlock_ProcessAnotherTransaction_Cre
 
 	tx2 := NewTransaction()
 	tx2.AddInput(tx.GetHash(), 0)
-	tx2.AddOutput(1, pubKeyBob)
-	tx2.AddOutput(1, pubKeyCyril)
+	tx2.AddOutput(1*COIN, pubKeyBob)
+	tx2.AddOutput(1*COIN, pubKeyCyril)
 	tx2.SignTx(privateKeyBob, 0)
 
 	TxProcess(tx2)
@@ -1145,7 +1176,7 @@ func TestBlockchain_processTransaction_CreateBlock_ProcessAnotherTransaction_Cre
 
 	tx3 := NewTransaction()
 	tx3.AddInput(tx2.GetHash(), 1)
-	tx3.AddOutput(1, pubKeyBob)
+	tx3.AddOutput(1*COIN, pubKeyBob)
 	tx3.SignTx(privateKeyCyril, 0)
 
 	TxProcess(tx3)
@@ -1156,6 +1187,8 @@ func TestBlockchain_processTransaction_CreateBlock_ProcessAnotherTransaction_Cre
 }
 
 func TestBlockchain_processTransaction_CreateBlock_ProcessBlockOnTopOfThatBlockWithATransactionClaimingTheUTXOFromThatPreviousTransaction(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
 	privateKeyBob, _ := rsa.GenerateKey(rand.Reader, 1024)
 	pubKeyBob := &privateKeyBob.PublicKey
 
@@ -1174,8 +1207,8 @@ func TestBlockchain_processTransaction_CreateBlock_ProcessBlockOnTopOfThatBlockW
 
 	tx := NewTransaction()
 	tx.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
-	tx.AddOutput(2, pubKeyBob)
-	tx.AddOutput(1.125, pubKeyAlice)
+	tx.AddOutput(2*COIN, pubKeyBob)
+	tx.AddOutput(1.125*COIN, pubKeyAlice)
 	tx.SignTx(privateKeyAlice, 0)
 
 	TxProcess(tx)
@@ -1187,14 +1220,14 @@ func TestBlockchain_processTransaction_CreateBlock_ProcessBlockOnTopOfThatBlockW
 	blockB := NewBlock(blockA.GetHash(), pubKeyCyril)
 	txB_1 := NewTransaction()
 	txB_1.AddInput(blockA.GetCoinbase().GetHash(), 0)
-	txB_1.AddOutput(1, pubKeyBob)
-	txB_1.AddOutput(2.125, pubKeyAlice)
+	txB_1.AddOutput(1*COIN, pubKeyBob)
+	txB_1.AddOutput(2.125*COIN, pubKeyAlice)
 	txB_1.SignTx(privateKeyBob, 0)
 
 	txB_2 := NewTransaction()
 	txB_2.AddInput(tx.GetHash(), 0)
-	txB_2.AddOutput(1, pubKeyBob)
-	txB_2.AddOutput(1, pubKeyAlice)
+	txB_2.AddOutput(1*COIN, pubKeyBob)
+	txB_2.AddOutput(1*COIN, pubKeyAlice)
 	txB_2.SignTx(privateKeyBob, 0)
 
 	blockB.TransactionAdd(txB_2)
@@ -1207,6 +1240,8 @@ func TestBlockchain_processTransaction_CreateBlock_ProcessBlockOnTopOfThatBlockW
 }
 
 func TestBlockchain_processTransaction_CreateBlock_ProcessBlockOnTopOfTheGenesisBlockWithATransactionClaimingTheUTXOFromThatPreviousTransaction(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
 	privateKeyBob, _ := rsa.GenerateKey(rand.Reader, 1024)
 	pubKeyBob := &privateKeyBob.PublicKey
 
@@ -1225,9 +1260,9 @@ func TestBlockchain_processTransaction_CreateBlock_ProcessBlockOnTopOfTheGenesis
 
 	tx := NewTransaction()
 	tx.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
-	tx.AddOutput(1, pubKeyBob)
-	tx.AddOutput(1.125, pubKeyAlice)
-	tx.AddOutput(1, pubKeyCyril)
+	tx.AddOutput(1*COIN, pubKeyBob)
+	tx.AddOutput(1.125*COIN, pubKeyAlice)
+	tx.AddOutput(1*COIN, pubKeyCyril)
 	tx.SignTx(privateKeyAlice, 0)
 
 	TxProcess(tx)
@@ -1239,13 +1274,13 @@ func TestBlockchain_processTransaction_CreateBlock_ProcessBlockOnTopOfTheGenesis
 	blockB := NewBlock(genesisBlock.GetHash(), pubKeyCyril)
 	txB_1 := NewTransaction()
 	txB_1.AddInput(tx.GetHash(), 0)
-	txB_1.AddOutput(1, pubKeyBob)
+	txB_1.AddOutput(1*COIN, pubKeyBob)
 	txB_1.SignTx(privateKeyBob, 0)
 
 	txB_2 := NewTransaction()
 	txB_1.AddInput(tx.GetHash(), 1)
-	txB_1.AddOutput(1, pubKeyBob)
-	txB_1.AddOutput(0.125, pubKeyAlice)
+	txB_1.AddOutput(1*COIN, pubKeyBob)
+	txB_1.AddOutput(0.125*COIN, pubKeyAlice)
 	txB_1.SignTx(privateKeyAlice, 0)
 
 	blockB.TransactionAdd(txB_2)
@@ -1257,7 +1292,9 @@ func TestBlockchain_processTransaction_CreateBlock_ProcessBlockOnTopOfTheGenesis
 	assert.False(t, result, "Blockchain must reject Block B, because its trying to spend a UTXO that wa never created in its branch")
 }
 
-func TestBlockchain_processMultipleBlocksDirectlyOnTopOfTheGenesiBlock_ThenCreateAnotherBlock_TheOldestBlockAtTheSameHeightAsTheCurrentMaxHeightBlockShouldBecomeTheMaxHeightBlock(t *testing.T) {
+func TestBlockchain_processMultipleBlocksDirectlyOnTopOfTheGenesiBlock_ThenCreateAnotherBlock_TheLowestHashBlockAtTheSameHeightAsTheCurrentMaxHeightBlockShouldBecomeTheMaxHeightBlock(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
 	privateKeyBob, _ := rsa.GenerateKey(rand.Reader, 1024)
 	pubKeyBob := &privateKeyBob.PublicKey
 
@@ -1295,7 +1332,13 @@ func TestBlockchain_processMultipleBlocksDirectlyOnTopOfTheGenesiBlock_ThenCreat
 	blockD := BlockCreate(pubKeyAlice)
 	assert.NotNil(t, blockD, "Block D should be created with zero transaction")
 	assert.Equal(t, 1, len(blockA.GetTransactions()), "Block D should contain only coinbase transaction")
-	assert.Equal(t, blockA.GetHash(), blockD.GetPrevBlockHash(), "Block D should be created on top of blockA, because it the older one")
+	lowest := blockA
+	for _, block := range []*Block{blockB, blockC} {
+		if bytes.Compare(block.GetHash(), lowest.GetHash()) < 0 {
+			lowest = block
+		}
+	}
+	assert.Equal(t, lowest.GetHash(), blockD.GetPrevBlockHash(), "Block D should be created on top of the block with the lowest hash")
 
 }
 
@@ -1454,6 +1497,8 @@ func TestBlockchain_similarToThePreviousTestButThenTryToProcessBlocksWhoseParent
 }
 
 func TestBlockchain_CreateMultisig(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
 	privateKey1, err := rsa.GenerateKey(rand.Reader, 1024)
 	if err != nil {
 		log.Fatal(err)
@@ -1482,8 +1527,8 @@ func TestBlockchain_CreateMultisig(t *testing.T) {
 
 	tx1 := NewTransaction()
 	tx1.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
-	addresses := []*rsa.PublicKey{pubKey1, pubKey2, pubKey3}
-	multiSigOut := NewMultiSigOutput(3.0, addresses)
+	addresses := []PublicKey{pubKey1, pubKey2, pubKey3}
+	multiSigOut := NewMultiSigOutput(3.0*COIN, addresses)
 	tx1.AddMultisigOutput(multiSigOut)
 	tx1.Finalize()
 
@@ -1498,6 +1543,8 @@ func TestBlockchain_CreateMultisig(t *testing.T) {
 }
 
 func TestBlockchain_CreateTransactionUsingMultisigAndSignByOneUser(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
 	privateKey1, err := rsa.GenerateKey(rand.Reader, 1024)
 	if err != nil {
 		log.Fatal(err)
@@ -1526,8 +1573,8 @@ func TestBlockchain_CreateTransactionUsingMultisigAndSignByOneUser(t *testing.T)
 
 	tx1 := NewTransaction()
 	tx1.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
-	addresses := []*rsa.PublicKey{pubKey1, pubKey2, pubKey3}
-	multiSigOut := NewMultiSigOutput(3.0, addresses)
+	addresses := []PublicKey{pubKey1, pubKey2, pubKey3}
+	multiSigOut := NewMultiSigOutput(3.0*COIN, addresses)
 	tx1.AddMultisigOutput(multiSigOut)
 	tx1.Finalize()
 
@@ -1542,7 +1589,7 @@ func TestBlockchain_CreateTransactionUsingMultisigAndSignByOneUser(t *testing.T)
 
 	tx2 := NewTransaction()
 	tx2.AddInput(tx1.GetHash(), 0)
-	tx2.AddOutput(1.0, pubKey1)
+	tx2.AddOutput(1.0*COIN, pubKey1)
 	tx2.SignMultiSigTx(privateKey1, 0)
 	tx2.Finalize()
 
@@ -1554,6 +1601,8 @@ func TestBlockchain_CreateTransactionUsingMultisigAndSignByOneUser(t *testing.T)
 }
 
 func TestBlockchain_CreateTransactionUsingMultisigAndSignByMinimumNumberOfUser(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
 	privateKey1, err := rsa.GenerateKey(rand.Reader, 1024)
 	if err != nil {
 		log.Fatal(err)
@@ -1582,8 +1631,8 @@ func TestBlockchain_CreateTransactionUsingMultisigAndSignByMinimumNumberOfUser(t
 
 	tx1 := NewTransaction()
 	tx1.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
-	addresses := []*rsa.PublicKey{pubKey1, pubKey2, pubKey3}
-	multiSigOut := NewMultiSigOutput(3.0, addresses)
+	addresses := []PublicKey{pubKey1, pubKey2, pubKey3}
+	multiSigOut := NewMultiSigOutput(3.0*COIN, addresses)
 	tx1.AddMultisigOutput(multiSigOut)
 	tx1.Finalize()
 
@@ -1598,7 +1647,7 @@ func TestBlockchain_CreateTransactionUsingMultisigAndSignByMinimumNumberOfUser(t
 
 	tx2 := NewTransaction()
 	tx2.AddInput(tx1.GetHash(), 0)
-	tx2.AddOutput(2.0, pubKey1)
+	tx2.AddOutput(2.0*COIN, pubKey1)
 	// Sign with Alice and Carol.
 	tx2.SignMultiSigTx(privateKey1, 0)
 	tx2.SignMultiSigTx(privateKey3, 0)
@@ -1612,6 +1661,8 @@ func TestBlockchain_CreateTransactionUsingMultisigAndSignByMinimumNumberOfUser(t
 }
 
 func TestBlockchain_FullMultiSigTransactions(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
 	privateKey1, err := rsa.GenerateKey(rand.Reader, 1024)
 	if err != nil {
 		log.Fatal(err)
@@ -1640,8 +1691,8 @@ func TestBlockchain_FullMultiSigTransactions(t *testing.T) {
 
 	tx1 := NewTransaction()
 	tx1.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
-	addresses := []*rsa.PublicKey{pubKey1, pubKey2, pubKey3}
-	multiSigOut := NewMultiSigOutput(3.0, addresses)
+	addresses := []PublicKey{pubKey1, pubKey2, pubKey3}
+	multiSigOut := NewMultiSigOutput(3.0*COIN, addresses)
 	tx1.AddMultisigOutput(multiSigOut)
 	tx1.Finalize()
 
@@ -1656,7 +1707,7 @@ func TestBlockchain_FullMultiSigTransactions(t *testing.T) {
 
 	tx2 := NewTransaction()
 	tx2.AddInput(tx1.GetHash(), 0)
-	tx2.AddOutput(1.0, pubKey1)
+	tx2.AddOutput(1.0*COIN, pubKey1)
 	tx2.SignMultiSigTx(privateKey1, 0)
 	tx2.Finalize()
 
@@ -1668,7 +1719,7 @@ func TestBlockchain_FullMultiSigTransactions(t *testing.T) {
 
 	tx3 := NewTransaction()
 	tx3.AddInput(tx1.GetHash(), 0)
-	tx3.AddOutput(2.0, pubKey1)
+	tx3.AddOutput(2.0*COIN, pubKey1)
 	// Sign with Alice and Carol.
 	tx3.SignMultiSigTx(privateKey1, 0)
 	tx3.SignMultiSigTx(privateKey3, 0)
@@ -1682,6 +1733,8 @@ func TestBlockchain_FullMultiSigTransactions(t *testing.T) {
 }
 
 func TestBlockchain_CreateMultisigWithInvalidTransaction(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
 	privateKey1, err := rsa.GenerateKey(rand.Reader, 1024)
 	if err != nil {
 		log.Fatal(err)
@@ -1710,8 +1763,8 @@ func TestBlockchain_CreateMultisigWithInvalidTransaction(t *testing.T) {
 	block1 := NewBlock(genesisBlock.GetHash(), pubKey1)
 	tx1 := NewTransaction()
 	tx1.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
-	addresses := []*rsa.PublicKey{pubKey1, pubKey2, pubKey3}
-	multiSigOut := NewMultiSigOutput(3.0, addresses)
+	addresses := []PublicKey{pubKey1, pubKey2, pubKey3}
+	multiSigOut := NewMultiSigOutput(3.0*COIN, addresses)
 	tx1.AddMultisigOutput(multiSigOut)
 	tx1.Finalize()
 
@@ -1726,7 +1779,7 @@ func TestBlockchain_CreateMultisigWithInvalidTransaction(t *testing.T) {
 
 	tx2 := NewTransaction()
 	tx2.AddInput(tx1.GetHash(), 0)
-	tx2.AddOutput(2.0, pubKey1)
+	tx2.AddOutput(2.0*COIN, pubKey1)
 	tx2.SignMultiSigTx(privateKey1, 0) // Signing with only one user
 	tx2.Finalize()
 
@@ -1738,7 +1791,7 @@ func TestBlockchain_CreateMultisigWithInvalidTransaction(t *testing.T) {
 
 	invalidTx := NewTransaction()
 	invalidTx.AddInput([]byte("invalidTxHash"), 0) // Invalid previous transaction hash
-	invalidTx.AddOutput(1.0, pubKey2)
+	invalidTx.AddOutput(1.0*COIN, pubKey2)
 	invalidTx.SignMultiSigTx(privateKey2, 0)
 	invalidTx.Finalize()
 
@@ -1751,7 +1804,7 @@ func TestBlockchain_CreateMultisigWithInvalidTransaction(t *testing.T) {
 	// Attempt to create a valid multisig transaction with the minimum number of signatures
 	tx3 := NewTransaction()
 	tx3.AddInput(tx1.GetHash(), 0)
-	tx3.AddOutput(2.0, pubKey1)
+	tx3.AddOutput(2.0*COIN, pubKey1)
 	tx3.SignMultiSigTx(privateKey1, 0)
 	tx3.SignMultiSigTx(privateKey2, 0)
 	tx3.Finalize()
@@ -1765,7 +1818,7 @@ func TestBlockchain_CreateMultisigWithInvalidTransaction(t *testing.T) {
 	// Test multi-signature input with the wrong number of signatures
 	tx4 := NewTransaction()
 	tx4.AddInput(tx1.GetHash(), 0)
-	tx4.AddOutput(2.0, pubKey1)
+	tx4.AddOutput(2.0*COIN, pubKey1)
 	tx4.SignMultiSigTx(privateKey1, 0) // Missing a signature
 	tx4.Finalize()
 
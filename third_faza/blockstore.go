@@ -0,0 +1,268 @@
+package third_faza
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// BLOCK_LOG_FILE is the append-only file holding the encoded blocks.
+	BLOCK_LOG_FILE = "blocks.log"
+	// BLOCK_INDEX_FILE maps block hashes to record offsets in BLOCK_LOG_FILE.
+	BLOCK_INDEX_FILE = "blocks.idx"
+
+	// recordHeaderSize covers the payload length and the CRC-32 checksum of every log record.
+	recordHeaderSize = 8
+	// indexEntrySize covers a 32 byte block hash and an 8 byte log offset.
+	indexEntrySize = 40
+	// maxRecordSize bounds the payload length read from disk so a corrupt header cannot exhaust memory.
+	maxRecordSize = 64 << 20
+)
+
+var (
+	ErrBlockNotFound       = errors.New("block not found in store")
+	ErrStoredBlockRejected = errors.New("stored block rejected on replay")
+)
+
+// BlockStore is an append-only block log with a hash → offset index.
+//
+// Every log record is [payload length uint32][CRC-32 of payload uint32][payload], where the
+// payload is Block.MarshalBinary. A record is synced to disk before its index entry is written,
+// so after a crash the index may only lag behind the log. On open, records past the last indexed
+// one are verified and indexed again, and a truncated or corrupt tail is cut off.
+type BlockStore struct {
+	log     *os.File
+	index   *os.File
+	offsets map[string]int64
+	order   []string
+	size    int64
+}
+
+// OpenBlockStore opens the store in dir, creating the directory and files if needed,
+// and recovers from an interrupted write.
+func OpenBlockStore(dir string) (*BlockStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	logFile, err := os.OpenFile(filepath.Join(dir, BLOCK_LOG_FILE), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	indexFile, err := os.OpenFile(filepath.Join(dir, BLOCK_INDEX_FILE), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		logFile.Close()
+		return nil, err
+	}
+
+	store := &BlockStore{
+		log:     logFile,
+		index:   indexFile,
+		offsets: make(map[string]int64),
+		order:   make([]string, 0),
+	}
+	if err := store.recover(); err != nil {
+		store.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+// recover loads the index, verifies the log records that are not indexed yet and
+// truncates both files to their last consistent state.
+func (store *BlockStore) recover() error {
+	logInfo, err := store.log.Stat()
+	if err != nil {
+		return err
+	}
+	logSize := logInfo.Size()
+
+	indexInfo, err := store.index.Stat()
+	if err != nil {
+		return err
+	}
+	indexData := make([]byte, indexInfo.Size())
+	if _, err := store.index.ReadAt(indexData, 0); err != nil && err != io.EOF {
+		return err
+	}
+
+	// Trust index entries as long as they describe consecutive records inside the log.
+	position := int64(0)
+	for i := 0; i+indexEntrySize <= len(indexData); i += indexEntrySize {
+		entry := indexData[i : i+indexEntrySize]
+		offset := int64(binary.BigEndian.Uint64(entry[32:]))
+		if offset != position {
+			break
+		}
+		length, _, err := store.readRecordHeader(offset, logSize)
+		if err != nil {
+			break
+		}
+		store.addToIndex(entry[:32], offset)
+		position = offset + recordHeaderSize + int64(length)
+	}
+
+	// Records written after the last index entry are checked completely.
+	newEntries := make([]byte, 0)
+	for position < logSize {
+		block, length, err := store.readRecord(position, logSize)
+		if err != nil {
+			break
+		}
+		hash := fixedHash(block.GetHash())
+		store.addToIndex(hash, position)
+		newEntries = append(newEntries, indexEntry(hash, position)...)
+		position += recordHeaderSize + int64(length)
+	}
+
+	if position < logSize {
+		if err := store.log.Truncate(position); err != nil {
+			return err
+		}
+	}
+	store.size = position
+
+	indexSize := int64(len(store.order)-len(newEntries)/indexEntrySize) * indexEntrySize
+	if err := store.index.Truncate(indexSize); err != nil {
+		return err
+	}
+	if _, err := store.index.WriteAt(newEntries, indexSize); err != nil {
+		return err
+	}
+	if err := store.log.Sync(); err != nil {
+		return err
+	}
+	return store.index.Sync()
+}
+
+func indexEntry(hash []byte, offset int64) []byte {
+	entry := make([]byte, 0, indexEntrySize)
+	entry = append(entry, fixedHash(hash)...)
+	return binary.BigEndian.AppendUint64(entry, uint64(offset))
+}
+
+func (store *BlockStore) addToIndex(hash []byte, offset int64) {
+	key := keyFoBlock(hash)
+	store.offsets[key] = offset
+	store.order = append(store.order, key)
+}
+
+// readRecordHeader returns the payload length and checksum of the record at offset,
+// making sure the whole record lies before limit.
+func (store *BlockStore) readRecordHeader(offset int64, limit int64) (uint32, uint32, error) {
+	header := make([]byte, recordHeaderSize)
+	if offset+recordHeaderSize > limit {
+		return 0, 0, io.ErrUnexpectedEOF
+	}
+	if _, err := store.log.ReadAt(header, offset); err != nil {
+		return 0, 0, err
+	}
+	length := binary.BigEndian.Uint32(header)
+	checksum := binary.BigEndian.Uint32(header[4:])
+	if length == 0 || length > maxRecordSize || offset+recordHeaderSize+int64(length) > limit {
+		return 0, 0, io.ErrUnexpectedEOF
+	}
+	return length, checksum, nil
+}
+
+// readRecord reads, verifies and decodes the record at offset.
+func (store *BlockStore) readRecord(offset int64, limit int64) (*Block, uint32, error) {
+	length, checksum, err := store.readRecordHeader(offset, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	payload := make([]byte, length)
+	if _, err := store.log.ReadAt(payload, offset+recordHeaderSize); err != nil {
+		return nil, 0, err
+	}
+	if crc32.ChecksumIEEE(payload) != checksum {
+		return nil, 0, errors.New("block record checksum mismatch")
+	}
+
+	block := &Block{}
+	if err := block.UnmarshalBinary(payload); err != nil {
+		return nil, 0, err
+	}
+	return block, length, nil
+}
+
+// Append writes the block to the log and indexes it. Blocks that are already stored are skipped.
+func (store *BlockStore) Append(block *Block) error {
+	if store.Contains(block.GetHash()) {
+		return nil
+	}
+
+	payload, err := block.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	record := make([]byte, 0, recordHeaderSize+len(payload))
+	record = binary.BigEndian.AppendUint32(record, uint32(len(payload)))
+	record = binary.BigEndian.AppendUint32(record, crc32.ChecksumIEEE(payload))
+	record = append(record, payload...)
+
+	offset := store.size
+	if _, err := store.log.WriteAt(record, offset); err != nil {
+		return err
+	}
+	if err := store.log.Sync(); err != nil {
+		return err
+	}
+	store.size += int64(len(record))
+
+	if _, err := store.index.WriteAt(indexEntry(block.GetHash(), offset), int64(len(store.order))*indexEntrySize); err != nil {
+		return err
+	}
+	if err := store.index.Sync(); err != nil {
+		return err
+	}
+	store.addToIndex(block.GetHash(), offset)
+	return nil
+}
+
+// Contains reports whether a block with the given hash is stored.
+func (store *BlockStore) Contains(hash []byte) bool {
+	_, ok := store.offsets[keyFoBlock(fixedHash(hash))]
+	return ok
+}
+
+// Get reads the block with the given hash from the log.
+func (store *BlockStore) Get(hash []byte) (*Block, error) {
+	offset, ok := store.offsets[keyFoBlock(fixedHash(hash))]
+	if !ok {
+		return nil, ErrBlockNotFound
+	}
+	block, _, err := store.readRecord(offset, store.size)
+	return block, err
+}
+
+// Blocks reads every stored block in the order in which they were appended.
+func (store *BlockStore) Blocks() ([]*Block, error) {
+	blocks := make([]*Block, 0, len(store.order))
+	for _, key := range store.order {
+		block, _, err := store.readRecord(store.offsets[key], store.size)
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
+
+// Len returns the number of stored blocks.
+func (store *BlockStore) Len() int {
+	return len(store.order)
+}
+
+// Close closes the underlying files.
+func (store *BlockStore) Close() error {
+	logErr := store.log.Close()
+	indexErr := store.index.Close()
+	if logErr != nil {
+		return logErr
+	}
+	return indexErr
+}
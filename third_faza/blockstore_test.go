@@ -0,0 +1,156 @@
+package third_faza
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// buildStoredChain creates a chain backed by a store in dir with a few blocks and one payment.
+func buildStoredChain(t *testing.T, dir string) (*Blockchain, []*Block) {
+	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyBob := &privateKeyBob.PublicKey
+
+	privateKeyAlice, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyAlice := &privateKeyAlice.PublicKey
+
+	store, err := OpenBlockStore(dir)
+	assert.NoError(t, err)
+
+	genesisBlock := NewBlock(nil, pubKeyBob)
+	genesisBlock.Finalizee()
+	localBlockchain, err := NewBlockchainWithStore(genesisBlock, store)
+	assert.NoError(t, err)
+	HandleBlocks(localBlockchain)
+
+	tx1 := NewTransaction()
+	tx1.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
+	tx1.AddOutput(1*COIN, pubKeyAlice)
+	tx1.AddOutput(2.125*COIN, pubKeyBob)
+	tx1.SignTx(privateKeyBob, 0)
+
+	block1 := NewBlock(genesisBlock.GetHash(), pubKeyAlice)
+	block1.TransactionAdd(tx1)
+	block1.Finalizee()
+	assert.True(t, BlockProcess(block1))
+
+	blocks := []*Block{genesisBlock, block1}
+	for i := 0; i < 3; i++ {
+		block := BlockCreate(pubKeyAlice)
+		assert.NotNil(t, block, fmt.Sprintf("Block #%d should be created", i+2))
+		blocks = append(blocks, block)
+	}
+	return localBlockchain, blocks
+}
+
+func TestBlockStore_ReopenRebuildsChain(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
+	dir := t.TempDir()
+	original, blocks := buildStoredChain(t, dir)
+	assert.NoError(t, original.Store.Close())
+
+	store, err := OpenBlockStore(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, len(blocks), store.Len())
+
+	reopened, err := NewBlockchainWithStore(nil, store)
+	assert.NoError(t, err)
+	defer reopened.Store.Close()
+
+	assert.Equal(t, original.GetBlockAtMaxHeight().GetHash(), reopened.GetBlockAtMaxHeight().GetHash(), "Tip should survive a restart")
+	assert.Equal(t, original.GetBlockNodeAtMaxHeight().Height, reopened.GetBlockNodeAtMaxHeight().Height)
+	assert.Equal(t, len(original.GetUTXOPoolAtMaxHeight().H), len(reopened.GetUTXOPoolAtMaxHeight().H), "UTXO pool should be rebuilt")
+
+	stored, err := reopened.Store.Get(blocks[1].GetHash())
+	assert.NoError(t, err)
+	assert.Equal(t, blocks[1].GetHash(), stored.GetHash())
+}
+
+func TestBlockStore_TruncatedTailIsDropped(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
+	dir := t.TempDir()
+	original, blocks := buildStoredChain(t, dir)
+	assert.NoError(t, original.Store.Close())
+
+	logPath := filepath.Join(dir, BLOCK_LOG_FILE)
+	info, err := os.Stat(logPath)
+	assert.NoError(t, err)
+	assert.NoError(t, os.Truncate(logPath, info.Size()-10))
+
+	store, err := OpenBlockStore(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, len(blocks)-1, store.Len(), "Truncated last record should be dropped")
+
+	reopened, err := NewBlockchainWithStore(nil, store)
+	assert.NoError(t, err)
+	assert.Equal(t, blocks[len(blocks)-2].GetHash(), reopened.GetBlockAtMaxHeight().GetHash())
+
+	// The store keeps accepting blocks after recovery.
+	HandleBlocks(reopened)
+	block := NewBlock(reopened.GetBlockAtMaxHeight().GetHash(), blocks[1].GetCoinbase().GetOutput(0).Address)
+	block.Finalizee()
+	assert.True(t, BlockProcess(block))
+	assert.NoError(t, reopened.Store.Close())
+
+	store, err = OpenBlockStore(dir)
+	assert.NoError(t, err)
+	defer store.Close()
+	assert.Equal(t, len(blocks), store.Len())
+	assert.True(t, store.Contains(block.GetHash()))
+}
+
+func TestBlockStore_CorruptRecordAndLostIndexAreRecovered(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
+	dir := t.TempDir()
+	original, blocks := buildStoredChain(t, dir)
+	assert.NoError(t, original.Store.Close())
+
+	// Flip a byte inside the last record and lose the index completely.
+	logPath := filepath.Join(dir, BLOCK_LOG_FILE)
+	data, err := os.ReadFile(logPath)
+	assert.NoError(t, err)
+	data[len(data)-5] ^= 0xff
+	assert.NoError(t, os.WriteFile(logPath, data, 0o644))
+	assert.NoError(t, os.Remove(filepath.Join(dir, BLOCK_INDEX_FILE)))
+
+	store, err := OpenBlockStore(dir)
+	assert.NoError(t, err)
+	defer store.Close()
+	assert.Equal(t, len(blocks)-1, store.Len(), "Record with a bad checksum should be dropped")
+	assert.False(t, store.Contains(blocks[len(blocks)-1].GetHash()))
+	assert.True(t, store.Contains(blocks[len(blocks)-2].GetHash()))
+}
+
+func TestBlockStore_RejectedBlockIsReported(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
+	dir := t.TempDir()
+	original, blocks := buildStoredChain(t, dir)
+
+	// A block whose parent is unknown is stored, e.g. by an older version with other rules.
+	orphan := NewBlock([]byte("unknown parent"), blocks[1].GetCoinbase().GetOutput(0).Address)
+	orphan.Finalizee()
+	assert.NoError(t, original.Store.Append(orphan))
+	assert.NoError(t, original.Store.Close())
+
+	store, err := OpenBlockStore(dir)
+	assert.NoError(t, err)
+	defer store.Close()
+	_, err = NewBlockchainWithStore(nil, store)
+	assert.ErrorIs(t, err, ErrStoredBlockRejected)
+}
@@ -0,0 +1,118 @@
+package third_faza
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFee_BlockCreateClaimsFees(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
+	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyBob := &privateKeyBob.PublicKey
+
+	privateKeyAlice, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyAlice := &privateKeyAlice.PublicKey
+
+	genesisBlock := NewBlock(nil, pubKeyBob)
+	genesisBlock.Finalizee()
+	localBlockchain := NewBlockchain(genesisBlock)
+	HandleBlocks(localBlockchain)
+
+	tx1 := NewTransaction()
+	tx1.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
+	tx1.AddOutput(2.625*COIN, pubKeyAlice)
+	tx1.SignTx(privateKeyBob, 0)
+	assert.Equal(t, Amount(0.5*COIN), GetFee(tx1, localBlockchain.UTXOSet))
+	TxProcess(tx1)
+
+	block1 := BlockCreate(pubKeyAlice)
+	assert.NotNil(t, block1, "Block claiming subsidy and fees should be accepted")
+	assert.Equal(t, COINBASE+0.5*COIN, block1.GetCoinbase().GetOutput(0).Value)
+	assert.Equal(t, block1.GetCoinbase(), block1.GetTransaction(0))
+
+	output := localBlockchain.UTXOSet.GetTxOutput(*NewUTXO(block1.GetCoinbase().GetHash(), 0))
+	assert.NotNil(t, output)
+	assert.Equal(t, COINBASE+0.5*COIN, output.Value)
+}
+
+func TestFee_CoinbaseCannotExceedSubsidyAndFees(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
+	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyBob := &privateKeyBob.PublicKey
+
+	privateKeyAlice, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyAlice := &privateKeyAlice.PublicKey
+
+	genesisBlock := NewBlock(nil, pubKeyBob)
+	genesisBlock.Finalizee()
+	localBlockchain := NewBlockchain(genesisBlock)
+	HandleBlocks(localBlockchain)
+
+	tx1 := NewTransaction()
+	tx1.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
+	tx1.AddOutput(3*COIN, pubKeyAlice)
+	tx1.SignTx(privateKeyBob, 0)
+
+	greedy := NewBlock(genesisBlock.GetHash(), pubKeyAlice)
+	greedy.SetCoinbase(NewCoinbaseTransaction(COINBASE+0.2*COIN, pubKeyAlice))
+	greedy.TransactionAdd(tx1)
+	greedy.Finalizee()
+	assert.False(t, BlockProcess(greedy), "Coinbase above subsidy plus fees should be rejected")
+
+	extraCoinbase := NewBlock(genesisBlock.GetHash(), pubKeyAlice)
+	extraCoinbase.TransactionAdd(NewCoinbaseTransaction(1*COIN, pubKeyBob))
+	extraCoinbase.Finalizee()
+	assert.False(t, BlockProcess(extraCoinbase), "Only the first transaction may be a coinbase")
+
+	modest := NewBlock(genesisBlock.GetHash(), pubKeyAlice)
+	modest.SetCoinbase(NewCoinbaseTransaction(1*COIN, pubKeyAlice))
+	modest.Finalizee()
+	assert.True(t, BlockProcess(modest), "Coinbase below the reward should be accepted")
+
+	exact := NewBlock(genesisBlock.GetHash(), pubKeyAlice)
+	exact.SetCoinbase(NewCoinbaseTransaction(COINBASE+0.125*COIN, pubKeyAlice))
+	exact.TransactionAdd(tx1)
+	exact.Finalizee()
+	assert.True(t, BlockProcess(exact), "Coinbase claiming exactly subsidy plus fees should be accepted")
+}
+
+func TestFee_CoinbaseIsNotPooled(t *testing.T) {
+	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyBob := &privateKeyBob.PublicKey
+
+	genesisBlock := NewBlock(nil, pubKeyBob)
+	genesisBlock.Finalizee()
+	localBlockchain := NewBlockchain(genesisBlock)
+	HandleBlocks(localBlockchain)
+
+	coinbase := NewCoinbaseTransaction(1*COIN, pubKeyBob)
+	_, ok := TxProcess(coinbase)
+	assert.False(t, ok, "A coinbase should not enter the pool")
+	assert.Empty(t, localBlockchain.GetTransactionPool().GetTransactions())
+
+	HandleTxs(localBlockchain.UTXOSet)
+	assert.Empty(t, Handler([]*Transaction{coinbase}), "A coinbase should not be handed to a block")
+
+	assert.NotNil(t, BlockCreate(pubKeyBob), "Mining should go on after a coinbase was offered")
+}
@@ -1,7 +1,5 @@
 package third_faza
 
-import "crypto/rsa"
-
 var (
 	blockchain *Blockchain
 )
@@ -18,23 +16,26 @@ func BlockProcess(block *Block) bool {
 	return res
 }
 
-func BlockCreate(myAddress *rsa.PublicKey) *Block {
-	parent := blockchain.GetBlockAtMaxHeight()
+func BlockCreate(myAddress PublicKey) *Block {
+	parentNode := blockchain.GetBlockNodeAtMaxHeight()
+	parent := parentNode.B
 	parentHash := append([]byte{}, parent.GetHash()...)
 
 	current := NewBlock(parentHash, myAddress)
 	uPool := blockchain.GetUTXOPoolAtMaxHeight()
 	txPool := blockchain.GetTransactionPool()
 
+	// Transactions are taken with their unconfirmed ancestors by ancestor fee rate while they fit.
 	HandleTxs(uPool)
-	txs := make([]*Transaction, 0)
-	txs = txPool.GetTransactions()
-	rTxs := Handler(txs)
-
-	for _, tx := range rTxs {
+	for _, tx := range Handler(txPool.SelectPackages(MAX_BLOCK_SIZE)) {
 		current.TransactionAdd(tx)
 	}
 
+	// The coinbase claims the fees of the selected transactions on top of the subsidy.
+	if _, fees, ok := ConnectBlock(blockchain.GetUTXOPoolAtMaxHeight(), current); ok && fees > 0 {
+		current.SetCoinbase(NewCoinbaseTransaction(BlockSubsidy(parentNode.Height+1)+fees, myAddress))
+	}
+
 	current.Finalizee()
 	if blockchain.BlockAdd(current) {
 		return current
@@ -43,6 +44,8 @@ func BlockCreate(myAddress *rsa.PublicKey) *Block {
 	}
 }
 
-func TxProcess(tx *Transaction) {
-	blockchain.TransactionAdd(tx)
+// TxProcess adds tx to the transaction pool and returns the transactions it replaced and
+// whether it was accepted.
+func TxProcess(tx *Transaction) ([]*Transaction, bool) {
+	return blockchain.TransactionAdd(tx)
 }
@@ -1,18 +1,22 @@
 package third_faza
 
 import (
-	"crypto"
-	"crypto/rsa"
-	"crypto/sha256"
 	"encoding/hex"
 )
 
 const (
+	// NEED_SIGN is the default required signature count of outputs made by NewMultiSigOutput.
 	NEED_SIGN = 2
+	// COINBASE_MATURITY is the default of CoinbaseMaturity.
+	COINBASE_MATURITY = 100
 )
 
 var (
 	utxoPool *UTXOPool
+
+	// CoinbaseMaturity is the number of blocks that have to follow a coinbase transaction on
+	// the same branch before its outputs can be spent. 0 and 1 both allow spending them in the next block.
+	CoinbaseMaturity uint = COINBASE_MATURITY
 )
 
 /**
@@ -45,35 +49,32 @@ func UTXOPoolGet() *UTXOPool {
  * (3) žiadne UTXO nie je nárokované viackrát,
  * (4) všetky výstupné hodnoty {@code tx}s sú nezáporné a
  * (5) súčet vstupných hodnôt {@code tx}s je väčší alebo rovný súčtu jej
- *     výstupných hodnôt; a false inak.
+ *     výstupných hodnôt,
+ * (6) lock time {@code tx} a relatívne zámky jej vstupov sú splnené v bloku
+ *     na výške pool.Height+1 (s mediánom času pool.MedianTime); a false inak.
  */
 func TxIsValid(tx Transaction, pool *UTXOPool) bool {
-	sumOfInputs := 0.0
+	sumOfInputs := Amount(0)
 	claimedUTXOs := make(map[string]bool)
 
 	if tx.Coinbase {
 		return true
 	}
+	if !tx.IsFinal(pool.Height+1, pool.MedianTime) {
+		return false
+	}
 
 	for i, input := range tx.Inputs {
 		utxo := NewUTXO(input.PrevTxHash, input.OutputIndex)
-		if _, ok := pool.H[utxo.Key()]; !ok {
+		entry, ok := pool.H[utxo.Key()]
+		if !ok || !entry.IsMature(pool.Height+1) || !CheckSequenceLock(input.Sequence, entry, pool) {
 			return false
 		}
 		output := pool.GetTxOutput(*utxo)
-		data := tx.GetDataToSign(i)
 
-		if output.MultiSigAddresses != nil && len(output.MultiSigAddresses) > 0 {
-			if len(input.MultiSigSignature) < NEED_SIGN {
-				return false
-			}
-			if !VerifyMultiSig(data, input.MultiSigSignature, output.MultiSigAddresses) {
-				return false
-			}
-		} else {
-			if !VerifySignature(data, input.Signature, output.Address) {
-				return false
-			}
+		ctx := NewScriptContext(&tx, i, pool.Height+1)
+		if err := VerifyScript(input.UnlockingScript(), output.LockingScript(), ctx); err != nil {
+			return false
 		}
 
 		if claimedUTXOs[utxo.Key()] {
@@ -81,49 +82,63 @@ func TxIsValid(tx Transaction, pool *UTXOPool) bool {
 		}
 		claimedUTXOs[utxo.Key()] = true
 
-		sumOfInputs += output.Value
+		sum, err := SumAmounts(sumOfInputs, output.Value)
+		if err != nil {
+			return false
+		}
+		sumOfInputs = sum
 	}
 
-	// All outputs must be non-negative.
-	sumOfOutputs := 0.0
+	// All outputs must be non-negative and their sum may not exceed the money supply.
+	// Multisig outputs must be spendable and locking scripts well-formed.
+	sumOfOutputs := Amount(0)
 	for _, output := range tx.Outputs {
-		if output.Value < 0 {
+		if output.IsMultiSig() && !ValidMultiSigOutput(output) {
+			return false
+		}
+		if _, err := ParseScript(output.LockScript); err != nil {
 			return false
 		}
-		sumOfOutputs += output.Value
+		sum, err := SumAmounts(sumOfOutputs, output.Value)
+		if err != nil {
+			return false
+		}
+		sumOfOutputs = sum
 	}
 
 	return sumOfInputs >= sumOfOutputs
 }
 
-func VerifyMultiSig(data []byte, sigs [][]byte, addresses []*rsa.PublicKey) bool {
-	validCount := 0
-	usedKeys := make(map[string]bool)
-
-	for _, pubKey := range addresses {
-		keyId := hex.EncodeToString(pubKey.N.Bytes())
-		if usedKeys[keyId] {
-			continue
-		}
-
-		for _, sig := range sigs {
-			if VerifySignature(data, sig, pubKey) {
-				validCount++
-				usedKeys[keyId] = true
-				break
-			}
+// ValidMultiSigOutput reports whether a multisig output lists distinct keys, no more than
+// MAX_MULTISIG_KEYS of them, and requires between one and all of them to sign.
+func ValidMultiSigOutput(output *Output) bool {
+	if len(output.MultiSigAddresses) > MAX_MULTISIG_KEYS {
+		return false
+	}
+	if output.RequiredSigs < 1 || output.RequiredSigs > len(output.MultiSigAddresses) {
+		return false
+	}
+	keys := make(map[string]bool)
+	for _, pubKey := range output.MultiSigAddresses {
+		keyData := marshalTaggedPublicKey(pubKey)
+		if keyData == nil {
+			return false
 		}
-		if validCount >= NEED_SIGN {
-			return true
+		keyId := hex.EncodeToString(keyData)
+		if keys[keyId] {
+			return false
 		}
+		keys[keyId] = true
 	}
-	return false
+	return true
 }
 
-func VerifySignature(message []byte, signature []byte, address *rsa.PublicKey) bool {
-	hash := sha256.Sum256(message)
-	err := rsa.VerifyPKCS1v15(address, crypto.SHA256, hash[:], signature)
-	return err == nil
+// VerifySignature reports whether signature is a signature of message by address, using the
+// signature scheme of the key. Signatures stored in transactions end with their SigHashType;
+// Transaction.VerifyInputSignature strips it and picks the message it selects.
+func VerifySignature(message []byte, signature []byte, address PublicKey) bool {
+	scheme := SchemeOf(address)
+	return scheme != nil && scheme.Verify(address, message, signature)
 }
 
 /**
@@ -135,20 +150,66 @@ func Handler(possibleTxs []*Transaction) []*Transaction {
 	originalPool := NewUTXOPoolWithPool(utxoPool)
 	validTxs := make([]*Transaction, 0)
 
-	for i := range possibleTxs {
-		tx := possibleTxs[i]
-		if TxIsValid(*tx, originalPool) {
+	// A transaction may spend outputs of one later in the list, so the rejected ones are
+	// retried until a pass accepts nothing new.
+	pending := possibleTxs
+	for len(pending) > 0 {
+		rejected := make([]*Transaction, 0)
+		for _, tx := range pending {
+			if tx.IsCoinbase() || !TxIsValid(*tx, originalPool) {
+				rejected = append(rejected, tx)
+				continue
+			}
 			validTxs = append(validTxs, tx)
 
 			for _, input := range tx.GetInputs() {
 				originalPool.RemoveUTXO(UTXO{txHash: input.PrevTxHash, index: input.OutputIndex})
 			}
-			for j, output := range tx.GetOutputs() {
-				originalPool.Put(UTXO{txHash: tx.GetHash(), index: j}, *output)
-			}
+			putUnconfirmedOutputs(originalPool, tx)
 		}
+		if len(rejected) == len(pending) {
+			break
+		}
+		pending = rejected
 	}
 
 	utxoPool = originalPool
 	return validTxs
 }
+
+// putUnconfirmedOutputs adds the outputs of tx to pool as created by the block at pool.Height+1.
+func putUnconfirmedOutputs(pool *UTXOPool, tx *Transaction) {
+	for j, output := range tx.GetOutputs() {
+		pool.PutEntry(UTXO{txHash: tx.GetHash(), index: j}, UTXOEntry{Output: *output, Height: pool.Height + 1, Time: pool.MedianTime})
+	}
+}
+
+// GetFee calculates the fee for a transaction as the difference
+// between the total input value and total output value.
+// Returns -1 if any input references an invalid UTXO or a sum leaves the money range.
+func GetFee(tx *Transaction, pool *UTXOPool) Amount {
+	totalInputValue := Amount(0)
+	totalOutputValue := Amount(0)
+
+	for _, input := range tx.Inputs {
+		utxo := NewUTXO(input.PrevTxHash, input.OutputIndex)
+		output := pool.GetTxOutput(*utxo)
+		if output == nil {
+			return -1
+		}
+		sum, err := SumAmounts(totalInputValue, output.Value)
+		if err != nil {
+			return -1
+		}
+		totalInputValue = sum
+	}
+
+	for _, output := range tx.Outputs {
+		sum, err := SumAmounts(totalOutputValue, output.Value)
+		if err != nil {
+			return -1
+		}
+		totalOutputValue = sum
+	}
+	return totalInputValue - totalOutputValue
+}
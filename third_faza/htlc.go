@@ -0,0 +1,140 @@
+package third_faza
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+)
+
+var ErrNotHTLC = errors.New("script is not a hash time-locked contract")
+
+// HTLC is a hash time-locked contract. Its output can be claimed by Recipient with the
+// SHA-256 preimage of Hash, or taken back by Sender in a block at height Timeout or later.
+// Two HTLCs with the same Hash on different chains make an atomic swap: claiming one reveals
+// the preimage needed to claim the other. The one claimed second should time out later.
+type HTLC struct {
+	Hash      []byte
+	Recipient PublicKey
+	Sender    PublicKey
+	Timeout   uint
+}
+
+// NewHTLC returns a contract locked to the hash of preimage.
+func NewHTLC(preimage []byte, recipient PublicKey, sender PublicKey, timeout uint) *HTLC {
+	hash := sha256.Sum256(preimage)
+	return &HTLC{Hash: hash[:], Recipient: recipient, Sender: sender, Timeout: timeout}
+}
+
+// Script returns the locking script of the contract:
+//
+//	OP_IF
+//	    OP_SHA256 <hash> OP_EQUALVERIFY <recipient>
+//	OP_ELSE
+//	    <timeout> OP_CHECKLOCKTIMEVERIFY OP_DROP <sender>
+//	OP_ENDIF
+//	OP_CHECKSIG
+func (htlc *HTLC) Script() Script {
+	return NewScriptBuilder().
+		AddOp(OP_IF).
+		AddOp(OP_SHA256).AddData(htlc.Hash).AddOp(OP_EQUALVERIFY).AddData(EncodePublicKey(htlc.Recipient)).
+		AddOp(OP_ELSE).
+		AddInt(uint64(htlc.Timeout)).AddOp(OP_CHECKLOCKTIMEVERIFY).AddOp(OP_DROP).AddData(EncodePublicKey(htlc.Sender)).
+		AddOp(OP_ENDIF).
+		AddOp(OP_CHECKSIG).
+		Script()
+}
+
+// ParseHTLC returns the contract of a script built by HTLC.Script.
+func ParseHTLC(script Script) (*HTLC, error) {
+	ops, err := ParseScript(script)
+	if err != nil {
+		return nil, err
+	}
+	if len(ops) != 12 || len(ops[2].Data) != sha256.Size {
+		return nil, ErrNotHTLC
+	}
+
+	timeout := uint64(0)
+	if timeoutOp := ops[6]; timeoutOp.Opcode >= OP_1 && timeoutOp.Opcode <= OP_16 {
+		timeout = uint64(timeoutOp.Opcode-OP_1) + 1
+	} else if timeout, err = decodeScriptNum(timeoutOp.Data); err != nil {
+		return nil, ErrNotHTLC
+	}
+	recipient, err := DecodePublicKey(ops[4].Data)
+	if err != nil {
+		return nil, ErrNotHTLC
+	}
+	sender, err := DecodePublicKey(ops[9].Data)
+	if err != nil {
+		return nil, ErrNotHTLC
+	}
+
+	htlc := &HTLC{Hash: ops[2].Data, Recipient: recipient, Sender: sender, Timeout: uint(timeout)}
+	if !bytes.Equal(htlc.Script(), script) {
+		return nil, ErrNotHTLC
+	}
+	return htlc, nil
+}
+
+// NewHTLCOutput creates an output locked by the contract.
+func NewHTLCOutput(value Amount, htlc *HTLC) *Output {
+	return NewScriptOutput(value, htlc.Script())
+}
+
+// HTLC returns the contract locking the output, or nil if it is not an HTLC output.
+func (out *Output) HTLC() *HTLC {
+	if len(out.LockScript) == 0 {
+		return nil
+	}
+	htlc, err := ParseHTLC(out.LockScript)
+	if err != nil {
+		return nil
+	}
+	return htlc
+}
+
+// NewHTLCFundingTransaction returns a transaction spending the output prevTxHash:outputIndex
+// into an HTLC output of value. Change outputs can be added before it is signed with SignTx.
+func NewHTLCFundingTransaction(prevTxHash []byte, outputIndex int, value Amount, htlc *HTLC) *Transaction {
+	tx := NewTransaction()
+	tx.AddInput(prevTxHash, outputIndex)
+	tx.Outputs = append(tx.Outputs, NewHTLCOutput(value, htlc))
+	return tx
+}
+
+// NewHTLCClaimTransaction returns a signed transaction paying value from the HTLC output
+// htlcTxHash:outputIndex to the recipient, revealing the preimage.
+func NewHTLCClaimTransaction(htlcTxHash []byte, outputIndex int, value Amount, preimage []byte, recipientKey PrivateKey) *Transaction {
+	tx := NewTransaction()
+	tx.AddInput(htlcTxHash, outputIndex)
+	tx.AddOutput(value, PublicKeyOf(recipientKey))
+	sig := tx.CreateSignature(recipientKey, 0)
+	tx.SetUnlockScript(0, NewScriptBuilder().AddData(sig).AddData(preimage).AddOp(OP_TRUE).Script())
+	return tx
+}
+
+// NewHTLCRefundTransaction returns a signed transaction paying value from the HTLC output
+// htlcTxHash:outputIndex back to the sender. It is locked until the timeout of the contract.
+func NewHTLCRefundTransaction(htlcTxHash []byte, outputIndex int, value Amount, htlc *HTLC, senderKey PrivateKey) *Transaction {
+	tx := NewTransaction()
+	tx.AddInput(htlcTxHash, outputIndex)
+	tx.AddOutput(value, PublicKeyOf(senderKey))
+	tx.LockTime = uint32(htlc.Timeout)
+	sig := tx.CreateSignature(senderKey, 0)
+	tx.SetUnlockScript(0, NewScriptBuilder().AddData(sig).AddOp(OP_FALSE).Script())
+	return tx
+}
+
+// ExtractHTLCPreimage returns the preimage revealed by input of a claim transaction, or nil
+// if the input does not claim an HTLC.
+func ExtractHTLCPreimage(tx *Transaction, input int) []byte {
+	in := tx.GetInput(input)
+	if in == nil {
+		return nil
+	}
+	ops, err := ParseScript(in.UnlockScript)
+	if err != nil || len(ops) != 3 || ops[2].Opcode != OP_TRUE {
+		return nil
+	}
+	return ops[1].Data
+}
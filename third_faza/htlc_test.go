@@ -0,0 +1,156 @@
+package third_faza
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTLC_ScriptRoundTrip(t *testing.T) {
+	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	privateKeyAlice, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, timeout := range []uint{5, 300} {
+		htlc := NewHTLC([]byte("secret"), &privateKeyBob.PublicKey, &privateKeyAlice.PublicKey, timeout)
+		parsed, err := ParseHTLC(htlc.Script())
+		assert.NoError(t, err)
+		assert.Equal(t, htlc.Hash, parsed.Hash)
+		assert.Equal(t, timeout, parsed.Timeout)
+		assert.True(t, htlc.Recipient.Equal(parsed.Recipient))
+		assert.True(t, htlc.Sender.Equal(parsed.Sender))
+		assert.NotNil(t, NewHTLCOutput(COIN, htlc).HTLC())
+	}
+
+	_, err = ParseHTLC(PayToPubKeyScript(&privateKeyBob.PublicKey))
+	assert.ErrorIs(t, err, ErrNotHTLC)
+	assert.Nil(t, NewOutput(COIN, &privateKeyBob.PublicKey).HTLC())
+}
+
+func TestHTLC_AtomicSwapBetweenTwoChains(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
+	privateKeyAlice, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyAlice := &privateKeyAlice.PublicKey
+
+	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyBob := &privateKeyBob.PublicKey
+
+	// Alice owns coins on chain A, Bob on chain B.
+	genesisA := NewBlock(nil, pubKeyAlice)
+	genesisA.Finalizee()
+	chainA := NewBlockchain(genesisA)
+
+	genesisB := NewBlock(nil, pubKeyBob)
+	genesisB.Finalizee()
+	chainB := NewBlockchain(genesisB)
+
+	// 1) Alice locks her coins on A to Bob under the hash of her secret. She can take
+	// them back from height 8; Bob's contract on B times out earlier, at height 5.
+	secret := []byte("alice's swap secret")
+	htlcA := NewHTLC(secret, pubKeyBob, pubKeyAlice, 8)
+	fundA := NewHTLCFundingTransaction(genesisA.GetCoinbase().GetHash(), 0, 3*COIN, htlcA)
+	fundA.SignTx(privateKeyAlice, 0)
+
+	HandleBlocks(chainA)
+	TxProcess(fundA)
+	assert.NotNil(t, BlockCreate(pubKeyAlice))
+
+	// 2) Bob checks Alice's contract on A and locks his coins on B under the same hash.
+	lockedOnA := chainA.UTXOSet.GetTxOutput(*NewUTXO(fundA.GetHash(), 0))
+	assert.NotNil(t, lockedOnA)
+	seenA := lockedOnA.HTLC()
+	assert.NotNil(t, seenA)
+	assert.True(t, seenA.Recipient.Equal(pubKeyBob))
+
+	htlcB := &HTLC{Hash: seenA.Hash, Recipient: pubKeyAlice, Sender: pubKeyBob, Timeout: 5}
+	fundB := NewHTLCFundingTransaction(genesisB.GetCoinbase().GetHash(), 0, 3*COIN, htlcB)
+	fundB.SignTx(privateKeyBob, 0)
+
+	HandleBlocks(chainB)
+	TxProcess(fundB)
+	assert.NotNil(t, BlockCreate(pubKeyBob))
+
+	// Bob cannot refund before the timeout, nor claim his own contract.
+	earlyRefund := NewHTLCRefundTransaction(fundB.GetHash(), 0, 3*COIN, htlcB, privateKeyBob)
+	assert.False(t, TxIsValid(*earlyRefund, chainB.UTXOSet), "Refund before the timeout should be rejected")
+	wrongClaimer := NewHTLCClaimTransaction(fundB.GetHash(), 0, 3*COIN, secret, privateKeyBob)
+	assert.False(t, TxIsValid(*wrongClaimer, chainB.UTXOSet), "Only the recipient may claim")
+	wrongSecret := NewHTLCClaimTransaction(fundB.GetHash(), 0, 3*COIN, []byte("guess"), privateKeyAlice)
+	assert.False(t, TxIsValid(*wrongSecret, chainB.UTXOSet), "Claim with the wrong preimage should be rejected")
+
+	// 3) Alice claims on B, revealing the secret.
+	claimB := NewHTLCClaimTransaction(fundB.GetHash(), 0, 3*COIN, secret, privateKeyAlice)
+	TxProcess(claimB)
+	blockB := BlockCreate(pubKeyBob)
+	assert.NotNil(t, blockB)
+	assert.Equal(t, 2, len(blockB.GetTransactions()))
+	assert.NotNil(t, chainB.UTXOSet.GetTxOutput(*NewUTXO(claimB.GetHash(), 0)), "Alice should own the coins on B")
+
+	// 4) Bob reads the secret from Alice's claim on B and claims on A.
+	revealed := ExtractHTLCPreimage(blockB.GetTransaction(1), 0)
+	assert.Equal(t, secret, revealed)
+
+	HandleBlocks(chainA)
+	claimA := NewHTLCClaimTransaction(fundA.GetHash(), 0, 3*COIN, revealed, privateKeyBob)
+	TxProcess(claimA)
+	assert.NotNil(t, BlockCreate(pubKeyAlice))
+	assert.NotNil(t, chainA.UTXOSet.GetTxOutput(*NewUTXO(claimA.GetHash(), 0)), "Bob should own the coins on A")
+	assert.Nil(t, chainA.UTXOSet.GetTxOutput(*NewUTXO(fundA.GetHash(), 0)))
+}
+
+func TestHTLC_RefundAfterTimeout(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
+	privateKeyAlice, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyAlice := &privateKeyAlice.PublicKey
+
+	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyBob := &privateKeyBob.PublicKey
+
+	genesisBlock := NewBlock(nil, pubKeyAlice)
+	genesisBlock.Finalizee()
+	localBlockchain := NewBlockchain(genesisBlock)
+	HandleBlocks(localBlockchain)
+
+	htlc := NewHTLC([]byte("never revealed"), pubKeyBob, pubKeyAlice, 4)
+	fund := NewHTLCFundingTransaction(genesisBlock.GetCoinbase().GetHash(), 0, 3*COIN, htlc)
+	fund.AddOutput(0.125*COIN, pubKeyAlice)
+	fund.SignTx(privateKeyAlice, 0)
+	TxProcess(fund)
+	assert.NotNil(t, BlockCreate(pubKeyAlice))
+
+	refund := NewHTLCRefundTransaction(fund.GetHash(), 0, 3*COIN, htlc, privateKeyAlice)
+	assert.Equal(t, uint32(4), refund.LockTime)
+	TxProcess(refund)
+	assert.Nil(t, localBlockchain.GetTransactionPool().GetTransaction(refund.GetHash()), "Refund should wait for the timeout")
+
+	assert.NotNil(t, BlockCreate(pubKeyAlice))
+	TxProcess(refund)
+	block4 := BlockCreate(pubKeyAlice)
+	assert.NotNil(t, block4)
+	assert.Equal(t, 2, len(block4.GetTransactions()), "Refund should be mined at the timeout height")
+
+	notByBob := NewHTLCRefundTransaction(fund.GetHash(), 0, 3*COIN, htlc, privateKeyBob)
+	assert.False(t, TxIsValid(*notByBob, localBlockchain.UTXOSet))
+}
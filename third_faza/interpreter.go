@@ -0,0 +1,402 @@
+package third_faza
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+)
+
+// MAX_MULTISIG_KEYS is the largest number of keys OP_CHECKMULTISIG accepts.
+const MAX_MULTISIG_KEYS = 20
+
+var (
+	ErrScriptEmpty         = errors.New("output has no locking script")
+	ErrScriptTooLarge      = errors.New("script is too large")
+	ErrScriptMalformed     = errors.New("script has a truncated push")
+	ErrScriptElementSize   = errors.New("script push is too large")
+	ErrScriptNotPushOnly   = errors.New("unlocking script may only push data")
+	ErrScriptBadOpcode     = errors.New("unknown opcode")
+	ErrScriptReturn        = errors.New("OP_RETURN executed")
+	ErrScriptUnbalancedIf  = errors.New("unbalanced conditional")
+	ErrScriptVerify        = errors.New("verify failed")
+	ErrScriptNumber        = errors.New("invalid script number")
+	ErrScriptLockTime      = errors.New("lock time not reached")
+	ErrScriptPubKeyCount   = errors.New("invalid multisig key count")
+	ErrStackUnderflow      = errors.New("stack underflow")
+	ErrStackOverflow       = errors.New("stack overflow")
+	ErrScriptCleanStack    = errors.New("stack not clean after evaluation")
+	ErrScriptEvalFalse     = errors.New("script evaluated to false")
+	ErrScriptDuplicateKeys = errors.New("multisig lists a key twice")
+)
+
+// ScriptContext is what the interpreter knows about the spend being checked.
+type ScriptContext struct {
+	Tx         *Transaction
+	InputIndex int
+	// Height is the height of the block the transaction is (or would be) included in.
+	Height uint
+
+	sigData map[SigHashType][]byte
+}
+
+// NewScriptContext returns the context for spending input index of tx in a block at height.
+func NewScriptContext(tx *Transaction, index int, height uint) *ScriptContext {
+	return &ScriptContext{Tx: tx, InputIndex: index, Height: height}
+}
+
+// checkSig verifies a signature with its trailing hash type against the data that hash type
+// selects, computing the data once per hash type.
+func (ctx *ScriptContext) checkSig(signature []byte, encodedKey []byte) bool {
+	pubKey, err := DecodePublicKey(encodedKey)
+	if err != nil {
+		return false
+	}
+	sig, hashType, ok := SplitSigHashType(signature)
+	if !ok {
+		return false
+	}
+	data, cached := ctx.sigData[hashType]
+	if !cached {
+		if ctx.sigData == nil {
+			ctx.sigData = make(map[SigHashType][]byte)
+		}
+		data = ctx.Tx.GetDataToSignWithHashType(ctx.InputIndex, hashType)
+		ctx.sigData[hashType] = data
+	}
+	return data != nil && VerifySignature(data, sig, pubKey)
+}
+
+type scriptStack [][]byte
+
+func (s *scriptStack) push(data []byte) {
+	*s = append(*s, data)
+}
+
+func (s *scriptStack) pop() ([]byte, error) {
+	if len(*s) == 0 {
+		return nil, ErrStackUnderflow
+	}
+	top := (*s)[len(*s)-1]
+	*s = (*s)[:len(*s)-1]
+	return top, nil
+}
+
+func (s *scriptStack) peek() ([]byte, error) {
+	if len(*s) == 0 {
+		return nil, ErrStackUnderflow
+	}
+	return (*s)[len(*s)-1], nil
+}
+
+func (s *scriptStack) popBool() (bool, error) {
+	data, err := s.pop()
+	return castToBool(data), err
+}
+
+func (s *scriptStack) popNum() (uint64, error) {
+	data, err := s.pop()
+	if err != nil {
+		return 0, err
+	}
+	return decodeScriptNum(data)
+}
+
+func castToBool(data []byte) bool {
+	for _, b := range data {
+		if b != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func boolToStack(value bool) []byte {
+	if value {
+		return []byte{1}
+	}
+	return []byte{}
+}
+
+func decodeScriptNum(data []byte) (uint64, error) {
+	if len(data) > MAX_SCRIPT_NUM_SIZE || (len(data) > 0 && data[0] == 0) {
+		return 0, ErrScriptNumber
+	}
+	n := uint64(0)
+	for _, b := range data {
+		n = n<<8 | uint64(b)
+	}
+	return n, nil
+}
+
+// VerifyScript runs the unlocking script of an input followed by the locking script of the
+// output it spends. It returns nil when the spend is allowed. The unlocking script may only
+// push data and the evaluation must leave exactly one true value on the stack.
+func VerifyScript(unlock Script, lock Script, ctx *ScriptContext) error {
+	if len(lock) == 0 {
+		return ErrScriptEmpty
+	}
+	if _, err := ParseScript(unlock); err != nil {
+		return err
+	}
+	if !unlock.IsPushOnly() {
+		return ErrScriptNotPushOnly
+	}
+
+	stack := make(scriptStack, 0)
+	if err := executeScript(unlock, &stack, ctx); err != nil {
+		return err
+	}
+	if err := executeScript(lock, &stack, ctx); err != nil {
+		return err
+	}
+
+	if len(stack) == 0 || !castToBool(stack[len(stack)-1]) {
+		return ErrScriptEvalFalse
+	}
+	if len(stack) != 1 {
+		return ErrScriptCleanStack
+	}
+	return nil
+}
+
+func executeScript(script Script, stack *scriptStack, ctx *ScriptContext) error {
+	ops, err := ParseScript(script)
+	if err != nil {
+		return err
+	}
+
+	// Each entry tells whether the branch of an enclosing OP_IF is being executed.
+	conditions := make([]bool, 0)
+	for _, op := range ops {
+		executing := true
+		for _, condition := range conditions {
+			executing = executing && condition
+		}
+
+		switch op.Opcode {
+		case OP_IF, OP_NOTIF:
+			condition := false
+			if executing {
+				value, err := stack.popBool()
+				if err != nil {
+					return err
+				}
+				condition = value == (op.Opcode == OP_IF)
+			}
+			conditions = append(conditions, condition)
+			continue
+		case OP_ELSE:
+			if len(conditions) == 0 {
+				return ErrScriptUnbalancedIf
+			}
+			conditions[len(conditions)-1] = !conditions[len(conditions)-1]
+			continue
+		case OP_ENDIF:
+			if len(conditions) == 0 {
+				return ErrScriptUnbalancedIf
+			}
+			conditions = conditions[:len(conditions)-1]
+			continue
+		}
+		if !executing {
+			continue
+		}
+
+		if err := executeOp(op, stack, ctx); err != nil {
+			return err
+		}
+		if len(*stack) > MAX_STACK_SIZE {
+			return ErrStackOverflow
+		}
+	}
+
+	if len(conditions) > 0 {
+		return ErrScriptUnbalancedIf
+	}
+	return nil
+}
+
+func executeOp(op ScriptOp, stack *scriptStack, ctx *ScriptContext) error {
+	switch {
+	case op.Opcode <= OP_PUSHDATA2:
+		stack.push(op.Data)
+		return nil
+	case op.Opcode >= OP_1 && op.Opcode <= OP_16:
+		stack.push([]byte{op.Opcode - OP_1 + 1})
+		return nil
+	}
+
+	switch op.Opcode {
+	case OP_VERIFY:
+		value, err := stack.popBool()
+		if err != nil {
+			return err
+		}
+		if !value {
+			return ErrScriptVerify
+		}
+
+	case OP_RETURN:
+		return ErrScriptReturn
+
+	case OP_DROP:
+		_, err := stack.pop()
+		return err
+
+	case OP_DUP:
+		top, err := stack.peek()
+		if err != nil {
+			return err
+		}
+		stack.push(top)
+
+	case OP_SWAP:
+		a, err := stack.pop()
+		if err != nil {
+			return err
+		}
+		b, err := stack.pop()
+		if err != nil {
+			return err
+		}
+		stack.push(a)
+		stack.push(b)
+
+	case OP_SIZE:
+		top, err := stack.peek()
+		if err != nil {
+			return err
+		}
+		stack.push(encodeScriptNum(uint64(len(top))))
+
+	case OP_EQUAL, OP_EQUALVERIFY:
+		a, err := stack.pop()
+		if err != nil {
+			return err
+		}
+		b, err := stack.pop()
+		if err != nil {
+			return err
+		}
+		equal := bytes.Equal(a, b)
+		if op.Opcode == OP_EQUALVERIFY {
+			if !equal {
+				return ErrScriptVerify
+			}
+			return nil
+		}
+		stack.push(boolToStack(equal))
+
+	case OP_SHA256:
+		data, err := stack.pop()
+		if err != nil {
+			return err
+		}
+		hash := sha256.Sum256(data)
+		stack.push(hash[:])
+
+	case OP_CHECKSIG, OP_CHECKSIGVERIFY:
+		pubKey, err := stack.pop()
+		if err != nil {
+			return err
+		}
+		sig, err := stack.pop()
+		if err != nil {
+			return err
+		}
+		valid := ctx.checkSig(sig, pubKey)
+		if op.Opcode == OP_CHECKSIGVERIFY {
+			if !valid {
+				return ErrScriptVerify
+			}
+			return nil
+		}
+		stack.push(boolToStack(valid))
+
+	case OP_CHECKMULTISIG, OP_CHECKMULTISIGVERIFY:
+		valid, err := checkMultiSig(stack, ctx)
+		if err != nil {
+			return err
+		}
+		if op.Opcode == OP_CHECKMULTISIGVERIFY {
+			if !valid {
+				return ErrScriptVerify
+			}
+			return nil
+		}
+		stack.push(boolToStack(valid))
+
+	case OP_CHECKLOCKTIMEVERIFY:
+		top, err := stack.peek()
+		if err != nil {
+			return err
+		}
+		lockHeight, err := decodeScriptNum(top)
+		if err != nil {
+			return err
+		}
+		if lockHeight > uint64(ctx.Height) {
+			return ErrScriptLockTime
+		}
+
+	default:
+		return ErrScriptBadOpcode
+	}
+	return nil
+}
+
+// checkMultiSig pops <sig>... <required> <pubKey>... <n> and checks that there are exactly
+// required signatures, each by a different listed key.
+func checkMultiSig(stack *scriptStack, ctx *ScriptContext) (bool, error) {
+	n, err := stack.popNum()
+	if err != nil {
+		return false, err
+	}
+	if n < 1 || n > MAX_MULTISIG_KEYS {
+		return false, ErrScriptPubKeyCount
+	}
+	encodedKeys := make([][]byte, n)
+	for i := int(n) - 1; i >= 0; i-- {
+		if encodedKeys[i], err = stack.pop(); err != nil {
+			return false, err
+		}
+	}
+	required, err := stack.popNum()
+	if err != nil {
+		return false, err
+	}
+	if required < 1 || required > n {
+		return false, ErrScriptPubKeyCount
+	}
+	sigs := make([][]byte, required)
+	for i := int(required) - 1; i >= 0; i-- {
+		if sigs[i], err = stack.pop(); err != nil {
+			return false, err
+		}
+	}
+
+	for i := range encodedKeys {
+		for j := i + 1; j < len(encodedKeys); j++ {
+			if bytes.Equal(encodedKeys[i], encodedKeys[j]) {
+				return false, ErrScriptDuplicateKeys
+			}
+		}
+	}
+
+	// Each signature must match a key that no earlier signature used.
+	usedKeys := make(map[int]bool)
+	for _, sig := range sigs {
+		matched := false
+		for i, encodedKey := range encodedKeys {
+			if !usedKeys[i] && ctx.checkSig(sig, encodedKey) {
+				usedKeys[i] = true
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false, nil
+		}
+	}
+	return true, nil
+}
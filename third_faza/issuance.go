@@ -0,0 +1,116 @@
+package third_faza
+
+// IssuanceSchedule describes how many new coins the coinbase of a block may create.
+//
+// The subsidy starts at InitialSubsidy and halves every HalvingInterval blocks (never when the
+// interval is 0). Once halving would take it below MinimumSubsidy, every following block pays
+// TailEmission instead, so a zero tail emission ends issuance and caps the total supply.
+// Halving rounds down to whole base units.
+type IssuanceSchedule struct {
+	InitialSubsidy  Amount
+	HalvingInterval uint
+	MinimumSubsidy  Amount
+	TailEmission    Amount
+}
+
+// DefaultIssuanceSchedule pays COINBASE per block and halves it every 210000 blocks
+// down to a single base unit, without tail emission.
+func DefaultIssuanceSchedule() IssuanceSchedule {
+	return IssuanceSchedule{
+		InitialSubsidy:  COINBASE,
+		HalvingInterval: 210000,
+		MinimumSubsidy:  1,
+		TailEmission:    0,
+	}
+}
+
+// Issuance is the schedule used by NewBlock, BlockCreate and BlockAdd.
+var Issuance = DefaultIssuanceSchedule()
+
+// BlockSubsidy returns the subsidy of the block at height under the current Issuance schedule.
+func BlockSubsidy(height uint) Amount {
+	return Issuance.Subsidy(height)
+}
+
+// halvings returns how many times the subsidy has been halved at height. The genesis block has height 1.
+func (schedule IssuanceSchedule) halvings(height uint) uint {
+	if schedule.HalvingInterval == 0 || height == 0 {
+		return 0
+	}
+	return (height - 1) / schedule.HalvingInterval
+}
+
+// eraSubsidy returns the subsidy paid after the given number of halvings and whether
+// halving has already ended and the tail emission is paid.
+func (schedule IssuanceSchedule) eraSubsidy(halvings uint) (Amount, bool) {
+	subsidy := schedule.InitialSubsidy
+	for i := uint(0); i < halvings; i++ {
+		subsidy /= 2
+		if subsidy < schedule.MinimumSubsidy || subsidy == 0 {
+			return schedule.TailEmission, true
+		}
+	}
+	return subsidy, false
+}
+
+// Subsidy returns the number of new coins the block at height may create.
+func (schedule IssuanceSchedule) Subsidy(height uint) Amount {
+	if height == 0 {
+		return 0
+	}
+	subsidy, _ := schedule.eraSubsidy(schedule.halvings(height))
+	return subsidy
+}
+
+// SupplyAt returns the number of coins the schedule issues in the blocks from the genesis up to height.
+// The result saturates at MAX_AMOUNT.
+func (schedule IssuanceSchedule) SupplyAt(height uint) Amount {
+	if schedule.HalvingInterval == 0 {
+		return saturate(schedule.InitialSubsidy.MulInt(int64(height)))
+	}
+
+	supply := Amount(0)
+	for era := uint(0); era*schedule.HalvingInterval < height; era++ {
+		subsidy, tail := schedule.eraSubsidy(era)
+		remaining := height - era*schedule.HalvingInterval
+		if !tail && remaining > schedule.HalvingInterval {
+			remaining = schedule.HalvingInterval
+		}
+		supply = saturate(supply.Add(saturate(subsidy.MulInt(int64(remaining)))))
+		if tail {
+			break
+		}
+	}
+	return supply
+}
+
+// MaxSupply returns the total number of coins the schedule will ever issue,
+// or MAX_AMOUNT when issuance never ends.
+func (schedule IssuanceSchedule) MaxSupply() Amount {
+	if schedule.HalvingInterval == 0 {
+		if schedule.InitialSubsidy > 0 {
+			return MAX_AMOUNT
+		}
+		return 0
+	}
+
+	supply := Amount(0)
+	for era := uint(0); ; era++ {
+		subsidy, tail := schedule.eraSubsidy(era)
+		if tail {
+			if subsidy > 0 {
+				return MAX_AMOUNT
+			}
+			return supply
+		}
+		supply = saturate(supply.Add(saturate(subsidy.MulInt(int64(schedule.HalvingInterval)))))
+	}
+}
+
+// saturate returns the result of a checked operation, or MAX_AMOUNT if it overflowed.
+func saturate(amount Amount, ok bool) Amount {
+	if !ok {
+		return MAX_AMOUNT
+	}
+	return amount
+}
@@ -0,0 +1,74 @@
+package third_faza
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIssuance_SubsidyHalvesAndEnds(t *testing.T) {
+	schedule := IssuanceSchedule{InitialSubsidy: 8 * COIN, HalvingInterval: 10, MinimumSubsidy: 1 * COIN}
+
+	assert.Equal(t, Amount(8*COIN), schedule.Subsidy(1), "Genesis pays the initial subsidy")
+	assert.Equal(t, Amount(8*COIN), schedule.Subsidy(10))
+	assert.Equal(t, Amount(4*COIN), schedule.Subsidy(11), "Subsidy halves after the interval")
+	assert.Equal(t, Amount(1*COIN), schedule.Subsidy(31))
+	assert.Equal(t, Amount(0), schedule.Subsidy(41), "Subsidy below the minimum ends issuance")
+
+	assert.Equal(t, Amount((80+40+5*2)*COIN), schedule.SupplyAt(25))
+	assert.Equal(t, Amount(150*COIN), schedule.SupplyAt(1000))
+	assert.Equal(t, Amount(150*COIN), schedule.MaxSupply(), "Supply should be capped without tail emission")
+
+	schedule.TailEmission = 0.5 * COIN
+	assert.Equal(t, Amount(0.5*COIN), schedule.Subsidy(41), "Tail emission continues after halving ends")
+	assert.Equal(t, Amount((150+10*0.5)*COIN), schedule.SupplyAt(50))
+	assert.Equal(t, MAX_AMOUNT, schedule.MaxSupply(), "Tail emission has no supply cap")
+
+	// Halving rounds down to whole base units until the subsidy reaches zero.
+	assert.Equal(t, Amount(131249997690000), DefaultIssuanceSchedule().MaxSupply())
+	assert.Equal(t, Amount(0), DefaultIssuanceSchedule().Subsidy(210000*64+1))
+}
+
+func TestIssuance_CoinbaseFollowsSchedule(t *testing.T) {
+	defaultIssuance := Issuance
+	Issuance = IssuanceSchedule{InitialSubsidy: COINBASE, HalvingInterval: 2, MinimumSubsidy: 1}
+	defer func() {
+		Issuance = defaultIssuance
+	}()
+
+	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyBob := &privateKeyBob.PublicKey
+
+	privateKeyAlice, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyAlice := &privateKeyAlice.PublicKey
+
+	genesisBlock := NewBlock(nil, pubKeyBob)
+	genesisBlock.Finalizee()
+	localBlockchain := NewBlockchain(genesisBlock)
+	HandleBlocks(localBlockchain)
+
+	block2 := BlockCreate(pubKeyAlice)
+	assert.NotNil(t, block2)
+	assert.Equal(t, COINBASE, block2.GetCoinbase().GetOutput(0).Value)
+
+	greedy := NewBlock(block2.GetHash(), pubKeyBob)
+	greedy.SetCoinbase(NewCoinbaseTransaction(COINBASE, pubKeyBob))
+	greedy.Finalizee()
+	assert.False(t, BlockProcess(greedy), "Block after the halving may not claim the old subsidy")
+
+	block3 := BlockCreate(pubKeyBob)
+	assert.NotNil(t, block3)
+	assert.Equal(t, COINBASE/2, block3.GetCoinbase().GetOutput(0).Value)
+
+	assert.Equal(t, Issuance.SupplyAt(3), localBlockchain.GetIssuedSupplyAtMaxHeight())
+	assert.Equal(t, 2*COINBASE, localBlockchain.GetIssuedSupplyAt(localBlockchain.Get(block2.GetHash())))
+}
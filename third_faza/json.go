@@ -0,0 +1,396 @@
+package third_faza
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"sort"
+)
+
+// The JSON representation is meant for scripts and dashboards: hashes and signatures are
+// hex strings, public keys are PEM-encoded PKIX keys, values are integers in base units
+// (see Amount) and timestamps are UNIX seconds. Hashes are recomputed while decoding and must match.
+
+type jsonInput struct {
+	PrevTxHash         string   `json:"prevTxHash"`
+	OutputIndex        int      `json:"outputIndex"`
+	Signature          string   `json:"signature,omitempty"`
+	MultiSigSignatures []string `json:"multiSigSignatures,omitempty"`
+	UnlockScript       string   `json:"unlockScript,omitempty"`
+	Sequence           uint32   `json:"sequence,omitempty"`
+}
+
+type jsonOutput struct {
+	Value             Amount   `json:"value"`
+	Address           string   `json:"address,omitempty"`
+	MultiSigAddresses []string `json:"multiSigAddresses,omitempty"`
+	RequiredSigs      int      `json:"requiredSigs,omitempty"`
+	LockScript        string   `json:"lockScript,omitempty"`
+}
+
+type jsonTransaction struct {
+	Hash      string    `json:"hash"`
+	Timestamp int64     `json:"timestamp"`
+	LockTime  uint32    `json:"lockTime,omitempty"`
+	Coinbase  bool      `json:"coinbase"`
+	Inputs    []*Input  `json:"inputs"`
+	Outputs   []*Output `json:"outputs"`
+}
+
+type jsonBlockHeader struct {
+	Version       uint32 `json:"version"`
+	PrevBlockHash string `json:"prevBlockHash"`
+	MerkleRoot    string `json:"merkleRoot"`
+	WitnessRoot   string `json:"witnessRoot"`
+	Timestamp     int64  `json:"timestamp"`
+	Bits          uint32 `json:"bits"`
+	Nonce         uint64 `json:"nonce"`
+}
+
+type jsonBlock struct {
+	Hash         string          `json:"hash"`
+	Header       jsonBlockHeader `json:"header"`
+	Transactions []*Transaction  `json:"transactions"`
+}
+
+type jsonUTXO struct {
+	TxHash string `json:"txHash"`
+	Index  int    `json:"index"`
+}
+
+type jsonUTXOEntry struct {
+	UTXO     *UTXO   `json:"utxo"`
+	Output   *Output `json:"output"`
+	Height   uint    `json:"height,omitempty"`
+	Coinbase bool    `json:"coinbase,omitempty"`
+	Time     int64   `json:"time,omitempty"`
+}
+
+// EncodePublicKeyPEM returns the PEM encoding of the PKIX form of the public key.
+func EncodePublicKeyPEM(pubKey PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pubKey)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), nil
+}
+
+// DecodePublicKeyPEM parses a public key produced by EncodePublicKeyPEM.
+func DecodePublicKeyPEM(data string) (PublicKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, errors.New("invalid PEM public key")
+	}
+	return DecodePublicKey(block.Bytes)
+}
+
+func decodeHex(value string) ([]byte, error) {
+	if value == "" {
+		return nil, nil
+	}
+	return hex.DecodeString(value)
+}
+
+// MarshalJSON encodes the input with hex hashes and signatures.
+func (in *Input) MarshalJSON() ([]byte, error) {
+	j := jsonInput{
+		PrevTxHash:   hex.EncodeToString(in.PrevTxHash),
+		OutputIndex:  in.OutputIndex,
+		Signature:    hex.EncodeToString(in.Signature),
+		UnlockScript: hex.EncodeToString(in.UnlockScript),
+		Sequence:     in.Sequence,
+	}
+	for _, sig := range in.MultiSigSignature {
+		j.MultiSigSignatures = append(j.MultiSigSignatures, hex.EncodeToString(sig))
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON decodes an input produced by MarshalJSON.
+func (in *Input) UnmarshalJSON(data []byte) error {
+	var j jsonInput
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	prevTxHash, err := decodeHex(j.PrevTxHash)
+	if err != nil {
+		return err
+	}
+	signature, err := decodeHex(j.Signature)
+	if err != nil {
+		return err
+	}
+	var multiSigSignatures [][]byte
+	for _, sigHex := range j.MultiSigSignatures {
+		sig, err := decodeHex(sigHex)
+		if err != nil {
+			return err
+		}
+		multiSigSignatures = append(multiSigSignatures, sig)
+	}
+	unlockScript, err := decodeHex(j.UnlockScript)
+	if err != nil {
+		return err
+	}
+
+	in.PrevTxHash = prevTxHash
+	in.OutputIndex = j.OutputIndex
+	in.Signature = signature
+	in.MultiSigSignature = multiSigSignatures
+	in.UnlockScript = unlockScript
+	in.Sequence = j.Sequence
+	return nil
+}
+
+// MarshalJSON encodes the output with PEM-encoded recipient keys.
+func (out *Output) MarshalJSON() ([]byte, error) {
+	j := jsonOutput{Value: out.Value}
+	if len(out.LockScript) > 0 {
+		j.LockScript = hex.EncodeToString(out.LockScript)
+	} else if len(out.MultiSigAddresses) > 0 {
+		j.RequiredSigs = out.RequiredSigs
+		for _, pubKey := range out.MultiSigAddresses {
+			encoded, err := EncodePublicKeyPEM(pubKey)
+			if err != nil {
+				return nil, err
+			}
+			j.MultiSigAddresses = append(j.MultiSigAddresses, encoded)
+		}
+	} else if out.Address != nil {
+		encoded, err := EncodePublicKeyPEM(out.Address)
+		if err != nil {
+			return nil, err
+		}
+		j.Address = encoded
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON decodes an output produced by MarshalJSON.
+func (out *Output) UnmarshalJSON(data []byte) error {
+	var j jsonOutput
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	var address PublicKey
+	if j.Address != "" {
+		pubKey, err := DecodePublicKeyPEM(j.Address)
+		if err != nil {
+			return err
+		}
+		address = pubKey
+	}
+	var multiSigAddresses []PublicKey
+	for _, encoded := range j.MultiSigAddresses {
+		pubKey, err := DecodePublicKeyPEM(encoded)
+		if err != nil {
+			return err
+		}
+		multiSigAddresses = append(multiSigAddresses, pubKey)
+	}
+
+	lockScript, err := decodeHex(j.LockScript)
+	if err != nil {
+		return err
+	}
+
+	out.Value = j.Value
+	out.Address = address
+	out.LockScript = lockScript
+	out.MultiSigAddresses = multiSigAddresses
+	out.RequiredSigs = j.RequiredSigs
+	return nil
+}
+
+// MarshalJSON encodes the transaction together with its hex hash.
+func (tx *Transaction) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonTransaction{
+		Hash:      hex.EncodeToString(tx.Hash),
+		Timestamp: tx.Timestamp,
+		LockTime:  tx.LockTime,
+		Coinbase:  tx.Coinbase,
+		Inputs:    tx.Inputs,
+		Outputs:   tx.Outputs,
+	})
+}
+
+// UnmarshalJSON decodes a transaction and recomputes its hash.
+// A hash present in the JSON has to match the recomputed one.
+func (tx *Transaction) UnmarshalJSON(data []byte) error {
+	var j jsonTransaction
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	expectedHash, err := decodeHex(j.Hash)
+	if err != nil {
+		return err
+	}
+
+	decoded := Transaction{
+		Inputs:    j.Inputs,
+		Outputs:   j.Outputs,
+		Coinbase:  j.Coinbase,
+		Timestamp: j.Timestamp,
+		LockTime:  j.LockTime,
+	}
+	if decoded.Inputs == nil {
+		decoded.Inputs = make([]*Input, 0)
+	}
+	if decoded.Outputs == nil {
+		decoded.Outputs = make([]*Output, 0)
+	}
+	for _, in := range decoded.Inputs {
+		if in == nil {
+			return errors.New("null transaction input")
+		}
+	}
+	for _, out := range decoded.Outputs {
+		if out == nil {
+			return errors.New("null transaction output")
+		}
+	}
+	decoded.Finalize()
+	if expectedHash != nil && !bytes.Equal(expectedHash, decoded.Hash) {
+		return errors.New("transaction hash does not match its contents")
+	}
+
+	*tx = decoded
+	return nil
+}
+
+// MarshalJSON encodes the block header, hash and transactions.
+func (block *Block) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonBlock{
+		Hash: hex.EncodeToString(block.hash),
+		Header: jsonBlockHeader{
+			Version:       block.header.Version,
+			PrevBlockHash: hex.EncodeToString(block.header.PrevBlockHash),
+			MerkleRoot:    hex.EncodeToString(block.header.MerkleRoot),
+			WitnessRoot:   hex.EncodeToString(block.header.WitnessRoot),
+			Timestamp:     block.header.Timestamp,
+			Bits:          block.header.Bits,
+			Nonce:         block.header.Nonce,
+		},
+		Transactions: block.txs,
+	})
+}
+
+// UnmarshalJSON decodes a block and recomputes its hash from the header.
+// A hash present in the JSON has to match the recomputed one.
+func (block *Block) UnmarshalJSON(data []byte) error {
+	var j jsonBlock
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	expectedHash, err := decodeHex(j.Hash)
+	if err != nil {
+		return err
+	}
+	prevBlockHash, err := decodeHex(j.Header.PrevBlockHash)
+	if err != nil {
+		return err
+	}
+	merkleRoot, err := decodeHex(j.Header.MerkleRoot)
+	if err != nil {
+		return err
+	}
+	witnessRoot, err := decodeHex(j.Header.WitnessRoot)
+	if err != nil {
+		return err
+	}
+	for _, tx := range j.Transactions {
+		if tx == nil {
+			return errors.New("null block transaction")
+		}
+	}
+
+	decoded := Block{
+		header: BlockHeader{
+			Version:       j.Header.Version,
+			PrevBlockHash: prevBlockHash,
+			MerkleRoot:    merkleRoot,
+			WitnessRoot:   witnessRoot,
+			Timestamp:     j.Header.Timestamp,
+			Bits:          j.Header.Bits,
+			Nonce:         j.Header.Nonce,
+		},
+		txs: j.Transactions,
+	}
+	if decoded.txs == nil {
+		decoded.txs = make([]*Transaction, 0)
+	}
+	if len(decoded.txs) > 0 && decoded.txs[0].IsCoinbase() {
+		decoded.coinbase = decoded.txs[0]
+	}
+	decoded.hash = decoded.CalculateHash()
+	if expectedHash != nil && !bytes.Equal(expectedHash, decoded.hash) {
+		return errors.New("block hash does not match its header")
+	}
+
+	*block = decoded
+	return nil
+}
+
+// MarshalJSON encodes the UTXO as its hex transaction hash and output index.
+func (u *UTXO) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonUTXO{TxHash: hex.EncodeToString(u.txHash), Index: u.index})
+}
+
+// UnmarshalJSON decodes a UTXO produced by MarshalJSON.
+func (u *UTXO) UnmarshalJSON(data []byte) error {
+	var j jsonUTXO
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	txHash, err := decodeHex(j.TxHash)
+	if err != nil {
+		return err
+	}
+	u.txHash = txHash
+	u.index = j.Index
+	return nil
+}
+
+// MarshalJSON encodes the pool as a list of UTXO/output pairs ordered by UTXO key.
+// The height of the pool itself is not encoded.
+func (utxoPool *UTXOPool) MarshalJSON() ([]byte, error) {
+	keys := make([]string, 0, len(utxoPool.H))
+	for key := range utxoPool.H {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	entries := make([]jsonUTXOEntry, 0, len(keys))
+	for _, key := range keys {
+		utxo, err := parseUTXOKey(key)
+		if err != nil {
+			return nil, err
+		}
+		entry := utxoPool.H[key]
+		entries = append(entries, jsonUTXOEntry{UTXO: utxo, Output: &entry.Output, Height: entry.Height, Coinbase: entry.Coinbase, Time: entry.Time})
+	}
+	return json.Marshal(entries)
+}
+
+// UnmarshalJSON replaces the pool contents with the entries of a list produced by MarshalJSON.
+func (utxoPool *UTXOPool) UnmarshalJSON(data []byte) error {
+	var entries []jsonUTXOEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+
+	pool := NewUTXOPool()
+	for _, entry := range entries {
+		if entry.UTXO == nil || entry.Output == nil {
+			return errors.New("incomplete UTXO pool entry")
+		}
+		pool.PutEntry(*entry.UTXO, UTXOEntry{Output: *entry.Output, Height: entry.Height, Coinbase: entry.Coinbase, Time: entry.Time})
+	}
+	utxoPool.H = pool.H
+	return nil
+}
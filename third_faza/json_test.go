@@ -0,0 +1,59 @@
+package third_faza
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSON_BlockRoundTrip(t *testing.T) {
+	block, tx := serializationFixture()
+
+	data, err := json.Marshal(block)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "BEGIN PUBLIC KEY", "Keys should be PEM encoded")
+	assert.Contains(t, string(data), "multiSigAddresses")
+
+	decoded := &Block{}
+	assert.NoError(t, json.Unmarshal(data, decoded))
+	assert.Equal(t, block.GetHash(), decoded.GetHash())
+	assert.Equal(t, block.ComputeMerkleRoot(), decoded.ComputeMerkleRoot())
+	assert.Equal(t, tx.GetHash(), decoded.GetTransaction(1).GetHash())
+	assert.True(t, tx.Outputs[0].Equals(decoded.GetTransaction(1).Outputs[0]))
+	assert.NotNil(t, decoded.GetCoinbase())
+}
+
+func TestJSON_TransactionWithWrongHashIsRejected(t *testing.T) {
+	_, tx := serializationFixture()
+
+	data, err := json.Marshal(tx)
+	assert.NoError(t, err)
+
+	tampered := strings.Replace(string(data), `"timestamp":`, `"timestamp":1`, 1)
+	assert.Error(t, json.Unmarshal([]byte(tampered), &Transaction{}), "Changed transaction should not match its hash")
+}
+
+func TestJSON_UTXOPoolRoundTrip(t *testing.T) {
+	_, tx := serializationFixture()
+
+	pool := NewUTXOPool()
+	for i, output := range tx.GetOutputs() {
+		pool.PutEntry(*NewUTXO(tx.GetHash(), i), UTXOEntry{Output: *output, Height: uint(i + 1), Coinbase: i == 0, Time: 1700000000 + int64(i)})
+	}
+
+	data, err := json.Marshal(pool)
+	assert.NoError(t, err)
+
+	decoded := NewUTXOPool()
+	assert.NoError(t, json.Unmarshal(data, decoded))
+	assert.Equal(t, len(pool.H), len(decoded.H))
+	for _, utxo := range pool.GetAllUTXO() {
+		assert.True(t, decoded.Contains(*utxo))
+		assert.Equal(t, pool.GetTxOutput(*utxo).Value, decoded.GetTxOutput(*utxo).Value)
+		assert.Equal(t, pool.GetEntry(*utxo).Height, decoded.GetEntry(*utxo).Height)
+		assert.Equal(t, pool.GetEntry(*utxo).Coinbase, decoded.GetEntry(*utxo).Coinbase)
+		assert.Equal(t, pool.GetEntry(*utxo).Time, decoded.GetEntry(*utxo).Time, "Creation time should survive for sequence locks")
+	}
+}
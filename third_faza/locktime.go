@@ -0,0 +1,62 @@
+package third_faza
+
+const (
+	// LOCKTIME_THRESHOLD separates the two meanings of Transaction.LockTime: values below it
+	// are block heights, values from it on are UNIX times.
+	LOCKTIME_THRESHOLD = 500000000
+
+	// SEQUENCE_LOCKTIME_DISABLE_FLAG turns off the relative lock of an input.
+	SEQUENCE_LOCKTIME_DISABLE_FLAG uint32 = 1 << 31
+	// SEQUENCE_LOCKTIME_TYPE_FLAG makes the relative lock a time in units of
+	// 2^SEQUENCE_LOCKTIME_GRANULARITY seconds instead of a number of blocks.
+	SEQUENCE_LOCKTIME_TYPE_FLAG uint32 = 1 << 22
+	// SEQUENCE_LOCKTIME_MASK selects the value of the relative lock.
+	SEQUENCE_LOCKTIME_MASK uint32 = 0x0000ffff
+	// SEQUENCE_LOCKTIME_GRANULARITY is the shift turning time-based relative lock units into seconds (512 s).
+	SEQUENCE_LOCKTIME_GRANULARITY = 9
+)
+
+// SequenceLockBlocks returns the Input.Sequence that keeps the input unspendable until the
+// spent output is buried under the given number of blocks, counting its own block.
+func SequenceLockBlocks(blocks uint16) uint32 {
+	return uint32(blocks)
+}
+
+// SequenceLockTime returns the Input.Sequence that keeps the input unspendable until the
+// median time past has moved at least seconds beyond the one when the spent output was
+// confirmed. The time is rounded up to the 512 second granularity.
+func SequenceLockTime(seconds uint32) uint32 {
+	units := (uint64(seconds) + 1<<SEQUENCE_LOCKTIME_GRANULARITY - 1) >> SEQUENCE_LOCKTIME_GRANULARITY
+	if units > uint64(SEQUENCE_LOCKTIME_MASK) {
+		units = uint64(SEQUENCE_LOCKTIME_MASK)
+	}
+	return SEQUENCE_LOCKTIME_TYPE_FLAG | uint32(units)
+}
+
+// IsFinal reports whether the transaction may be included in a block at height whose parent
+// has the given median time past. A LockTime of zero never locks; otherwise the height or
+// time it names must have been reached.
+func (tx *Transaction) IsFinal(height uint, medianTime int64) bool {
+	if tx.LockTime == 0 {
+		return true
+	}
+	if tx.LockTime < LOCKTIME_THRESHOLD {
+		return uint64(tx.LockTime) <= uint64(height)
+	}
+	return int64(tx.LockTime) <= medianTime
+}
+
+// CheckSequenceLock reports whether the relative lock of an input spending entry is satisfied
+// in the block at pool.Height+1. Block locks count from the height of the block that created
+// the output, time locks from the median time past the output was confirmed with.
+func CheckSequenceLock(sequence uint32, entry UTXOEntry, pool *UTXOPool) bool {
+	if sequence&SEQUENCE_LOCKTIME_DISABLE_FLAG != 0 {
+		return true
+	}
+	value := sequence & SEQUENCE_LOCKTIME_MASK
+	if sequence&SEQUENCE_LOCKTIME_TYPE_FLAG != 0 {
+		lockTime := entry.Time + int64(value)<<SEQUENCE_LOCKTIME_GRANULARITY
+		return pool.MedianTime >= lockTime
+	}
+	return pool.Height+1 >= entry.Height+uint(value)
+}
@@ -0,0 +1,157 @@
+package third_faza
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockTime_AbsoluteHeightAndTime(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
+	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyBob := &privateKeyBob.PublicKey
+
+	privateKeyAlice, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyAlice := &privateKeyAlice.PublicKey
+
+	now := time.Now().Unix()
+	genesisBlock := NewBlock(nil, pubKeyBob)
+	genesisBlock.SetTimestamp(now - 10000)
+	genesisBlock.Finalizee()
+	localBlockchain := NewBlockchain(genesisBlock)
+	HandleBlocks(localBlockchain)
+
+	heightLocked := NewTransaction()
+	heightLocked.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
+	heightLocked.AddOutput(1*COIN, pubKeyAlice)
+	heightLocked.LockTime = 3
+	heightLocked.SignTx(privateKeyBob, 0)
+
+	timeLocked := NewTransaction()
+	timeLocked.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
+	timeLocked.AddOutput(2*COIN, pubKeyAlice)
+	timeLocked.LockTime = uint32(now - 5000)
+	timeLocked.SignTx(privateKeyBob, 0)
+
+	TxProcess(heightLocked)
+	TxProcess(timeLocked)
+	assert.Equal(t, 0, len(localBlockchain.GetTransactionPool().GetTransactions()), "Locked transactions should not enter the pool")
+
+	early := NewBlock(genesisBlock.GetHash(), pubKeyBob)
+	early.TransactionAdd(heightLocked)
+	early.Finalizee()
+	assert.False(t, BlockProcess(early), "Block 2 may not include a transaction locked until block 3")
+
+	// Block 2 moves the median time past beyond the time lock and makes height 3 next.
+	block2 := BlockCreate(pubKeyBob)
+	assert.NotNil(t, block2)
+	assert.Equal(t, block2.GetTimestamp(), localBlockchain.UTXOSet.MedianTime)
+	assert.True(t, heightLocked.IsFinal(3, 0))
+	assert.True(t, timeLocked.IsFinal(3, localBlockchain.UTXOSet.MedianTime))
+
+	TxProcess(timeLocked)
+	assert.NotNil(t, localBlockchain.GetTransactionPool().GetTransaction(timeLocked.GetHash()))
+	assert.True(t, TxIsValid(*heightLocked, localBlockchain.UTXOSet))
+
+	// The lock time is signed.
+	heightLocked.LockTime = 2
+	heightLocked.Finalize()
+	assert.False(t, TxIsValid(*heightLocked, localBlockchain.UTXOSet), "Changing the lock time should break the signature")
+
+	block3 := BlockCreate(pubKeyBob)
+	assert.NotNil(t, block3)
+	assert.Equal(t, 2, len(block3.GetTransactions()), "BlockCreate should pick the unlocked transaction")
+}
+
+func TestLockTime_RelativeSequenceLocks(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
+	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyBob := &privateKeyBob.PublicKey
+
+	privateKeyAlice, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyAlice := &privateKeyAlice.PublicKey
+
+	now := time.Now().Unix()
+	genesisBlock := NewBlock(nil, pubKeyBob)
+	genesisBlock.SetTimestamp(now - 10000)
+	genesisBlock.Finalizee()
+	localBlockchain := NewBlockchain(genesisBlock)
+	HandleBlocks(localBlockchain)
+
+	tx1 := NewTransaction()
+	tx1.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
+	tx1.AddOutput(1*COIN, pubKeyAlice)
+	tx1.AddOutput(2*COIN, pubKeyAlice)
+	tx1.SignTx(privateKeyBob, 0)
+
+	block2 := NewBlock(genesisBlock.GetHash(), pubKeyBob)
+	block2.SetTimestamp(now - 9000)
+	block2.TransactionAdd(tx1)
+	block2.Finalizee()
+	assert.True(t, BlockProcess(block2))
+	entry := localBlockchain.UTXOSet.GetEntry(*NewUTXO(tx1.GetHash(), 0))
+	assert.Equal(t, uint(2), entry.Height)
+	assert.Equal(t, now-10000, entry.Time, "Output time should be the median time past of its block's parent")
+
+	// Spendable from height 2+3 on.
+	blockLocked := NewTransaction()
+	blockLocked.AddInput(tx1.GetHash(), 0)
+	blockLocked.Inputs[0].Sequence = SequenceLockBlocks(3)
+	blockLocked.AddOutput(1*COIN, pubKeyBob)
+	blockLocked.SignTx(privateKeyAlice, 0)
+
+	// Spendable once the median time past is 1024 seconds after now-10000.
+	timeLocked := NewTransaction()
+	timeLocked.AddInput(tx1.GetHash(), 1)
+	timeLocked.Inputs[0].Sequence = SequenceLockTime(1000)
+	timeLocked.AddOutput(2*COIN, pubKeyBob)
+	timeLocked.SignTx(privateKeyAlice, 0)
+	assert.Equal(t, SEQUENCE_LOCKTIME_TYPE_FLAG|2, timeLocked.Inputs[0].Sequence, "Time should round up to 512 second units")
+
+	assert.False(t, TxIsValid(*blockLocked, localBlockchain.UTXOSet))
+	assert.False(t, TxIsValid(*timeLocked, localBlockchain.UTXOSet))
+
+	block3 := NewBlock(block2.GetHash(), pubKeyBob)
+	block3.SetTimestamp(now - 8000)
+	block3.Finalizee()
+	assert.True(t, BlockProcess(block3))
+	assert.False(t, TxIsValid(*timeLocked, localBlockchain.UTXOSet), "Median time past is still now-9000")
+
+	block4 := NewBlock(block3.GetHash(), pubKeyBob)
+	block4.SetTimestamp(now - 7000)
+	block4.Finalizee()
+	assert.True(t, BlockProcess(block4))
+	assert.Equal(t, now-8000, localBlockchain.UTXOSet.MedianTime)
+	assert.True(t, TxIsValid(*timeLocked, localBlockchain.UTXOSet))
+	assert.True(t, TxIsValid(*blockLocked, localBlockchain.UTXOSet), "Block 5 may spend an output of block 2 locked for 3 blocks")
+
+	disabled := NewTransaction()
+	disabled.AddInput(tx1.GetHash(), 0)
+	disabled.Inputs[0].Sequence = SEQUENCE_LOCKTIME_DISABLE_FLAG | 0xffff
+	disabled.AddOutput(1*COIN, pubKeyBob)
+	disabled.SignTx(privateKeyAlice, 0)
+	assert.True(t, TxIsValid(*disabled, localBlockchain.UTXOSet))
+
+	// The sequence is signed.
+	blockLocked.Inputs[0].Sequence = 0
+	blockLocked.Finalize()
+	assert.False(t, TxIsValid(*blockLocked, localBlockchain.UTXOSet))
+}
@@ -1,29 +1,27 @@
 package third_faza
 
 import (
-	"crypto/rand"
-	"crypto/rsa"
 	"log"
 )
 
 func mainD() {
-	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
+	privateKeyBob, err := GenerateKey()
 	if err != nil {
 		log.Fatal(err)
 	}
-	pubKeyBob := &privateKeyBob.PublicKey
+	pubKeyBob := PublicKeyOf(privateKeyBob)
 
-	privateKeyAlice, err := rsa.GenerateKey(rand.Reader, 1024)
+	privateKeyAlice, err := GenerateKey()
 	if err != nil {
 		log.Fatal(err)
 	}
-	pubKeyAlice := &privateKeyAlice.PublicKey
+	pubKeyAlice := PublicKeyOf(privateKeyAlice)
 
-	privateKeyCyril, err := rsa.GenerateKey(rand.Reader, 1024)
+	privateKeyCyril, err := GenerateKey()
 	if err != nil {
 		log.Fatal(err)
 	}
-	pubKeyCyril := &privateKeyCyril.PublicKey
+	pubKeyCyril := PublicKeyOf(privateKeyCyril)
 
 	genesisBlock := NewBlock(nil, pubKeyBob)
 	genesisBlock.Finalizee()
@@ -35,9 +33,9 @@ func mainD() {
 	tx1 := NewTransaction()
 	tx1.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
 
-	tx1.AddOutput(1, pubKeyAlice)
-	tx1.AddOutput(1, pubKeyAlice)
-	tx1.AddOutput(1.125, pubKeyAlice)
+	tx1.AddOutput(1*COIN, pubKeyAlice)
+	tx1.AddOutput(1*COIN, pubKeyAlice)
+	tx1.AddOutput(1.125*COIN, pubKeyAlice)
 
 	// Je len jeden (na pozicii 0) Transaction.Input v tx1
 	// a ten obsahuje mince od Boba, a preto je potrebne podpisat transakciu privatnym klucom Boba
@@ -55,9 +53,9 @@ func mainD() {
 
 	tx2.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
 
-	tx2.AddOutput(1, pubKeyBob)
-	tx2.AddOutput(1, pubKeyBob)
-	tx2.AddOutput(1.125, pubKeyBob)
+	tx2.AddOutput(1*COIN, pubKeyBob)
+	tx2.AddOutput(1*COIN, pubKeyBob)
+	tx2.AddOutput(1.125*COIN, pubKeyBob)
 
 	tx2.SignTx(privateKeyBob, 0)
 
@@ -75,7 +73,7 @@ func mainD() {
 	tx3.AddInput(tx1.GetHash(), 1)
 	// tx3.AddInput(tx1.GetHash(), 2)
 
-	tx3.AddOutput(2, pubKeyCyril)
+	tx3.AddOutput(2*COIN, pubKeyCyril)
 
 	tx3.SignTx(privateKeyAlice, 0)
 	tx3.SignTx(privateKeyAlice, 1)
@@ -93,8 +91,8 @@ func mainD() {
 
 	tx4.AddInput(tx3.GetHash(), 0)
 
-	tx4.AddOutput(1.5, pubKeyBob)
-	tx4.AddOutput(0.5, pubKeyBob)
+	tx4.AddOutput(1.5*COIN, pubKeyBob)
+	tx4.AddOutput(0.5*COIN, pubKeyBob)
 
 	tx4.SignTx(privateKeyCyril, 0)
 
@@ -0,0 +1,78 @@
+package third_faza
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setCoinbaseMaturity sets CoinbaseMaturity for the rest of the test. Tests that spend a
+// coinbase right away set it to 0.
+func setCoinbaseMaturity(t *testing.T, maturity uint) {
+	defaultMaturity := CoinbaseMaturity
+	CoinbaseMaturity = maturity
+	t.Cleanup(func() {
+		CoinbaseMaturity = defaultMaturity
+	})
+}
+
+func TestMaturity_CoinbaseSpendWaitsForMaturity(t *testing.T) {
+	setCoinbaseMaturity(t, 3)
+
+	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyBob := &privateKeyBob.PublicKey
+
+	privateKeyAlice, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyAlice := &privateKeyAlice.PublicKey
+
+	genesisBlock := NewBlock(nil, pubKeyBob)
+	genesisBlock.Finalizee()
+	localBlockchain := NewBlockchain(genesisBlock)
+	HandleBlocks(localBlockchain)
+
+	genesisUTXO := *NewUTXO(genesisBlock.GetCoinbase().GetHash(), 0)
+	entry := localBlockchain.UTXOSet.GetEntry(genesisUTXO)
+	assert.Equal(t, uint(1), entry.Height)
+	assert.True(t, entry.Coinbase)
+
+	tx1 := NewTransaction()
+	tx1.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
+	tx1.AddOutput(3.125*COIN, pubKeyAlice)
+	tx1.SignTx(privateKeyBob, 0)
+
+	immature := NewBlock(genesisBlock.GetHash(), pubKeyAlice)
+	immature.TransactionAdd(tx1)
+	immature.Finalizee()
+	assert.False(t, BlockProcess(immature), "Coinbase output should not be spendable in the next block")
+
+	TxProcess(tx1)
+	assert.Nil(t, localBlockchain.GetTransactionPool().GetTransaction(tx1.GetHash()), "Immature spend should not enter the pool")
+
+	block2 := BlockCreate(pubKeyAlice)
+	assert.NotNil(t, block2)
+	coinbaseEntry := localBlockchain.UTXOSet.GetEntry(*NewUTXO(block2.GetCoinbase().GetHash(), 0))
+	assert.Equal(t, uint(2), coinbaseEntry.Height)
+	assert.True(t, coinbaseEntry.Coinbase)
+
+	block3 := BlockCreate(pubKeyAlice)
+	assert.NotNil(t, block3)
+
+	block4 := NewBlock(block3.GetHash(), pubKeyAlice)
+	block4.TransactionAdd(tx1)
+	block4.Finalizee()
+	assert.True(t, BlockProcess(block4), "Coinbase output buried under enough blocks should be spendable")
+
+	spent := localBlockchain.UTXOSet.GetEntry(*NewUTXO(tx1.GetHash(), 0))
+	assert.Equal(t, uint(4), spent.Height)
+	assert.False(t, spent.Coinbase)
+	assert.Equal(t, uint(4), localBlockchain.UTXOSet.Height)
+}
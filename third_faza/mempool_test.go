@@ -0,0 +1,310 @@
+package third_faza
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMempool_FeeRateOrderEvictionAndExpiry(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
+	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyBob := &privateKeyBob.PublicKey
+
+	privateKeyAlice, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyAlice := &privateKeyAlice.PublicKey
+
+	genesisBlock := NewBlock(nil, pubKeyBob)
+	genesisBlock.Finalizee()
+	localBlockchain := NewBlockchain(genesisBlock)
+	HandleBlocks(localBlockchain)
+
+	tx1 := NewTransaction()
+	tx1.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
+	tx1.AddOutput(1*COIN, pubKeyAlice)
+	tx1.AddOutput(1*COIN, pubKeyAlice)
+	tx1.AddOutput(1*COIN, pubKeyAlice)
+	tx1.SignTx(privateKeyBob, 0)
+	TxProcess(tx1)
+	assert.NotNil(t, BlockCreate(pubKeyBob))
+
+	pool := NewTransactionPool()
+	spends := make([]*Transaction, 3)
+	for i := range spends {
+		spends[i] = NewTransaction()
+		spends[i].AddInput(tx1.GetHash(), i)
+		spends[i].AddOutput(1*COIN-Amount(i+1)*COIN/100, pubKeyBob)
+		spends[i].SignTx(privateKeyAlice, 0)
+		assert.Empty(t, pool.AddTransaction(spends[i], GetFee(spends[i], localBlockchain.UTXOSet)))
+	}
+	assert.Equal(t, []*Transaction{spends[2], spends[1], spends[0]}, pool.GetTransactions(), "Higher fee rates come first")
+	assert.Equal(t, spends[0].Size()+spends[1].Size()+spends[2].Size(), pool.Size())
+
+	assert.Empty(t, pool.AddTransaction(spends[2], 0), "Adding a pooled transaction again changes nothing")
+
+	// A full pool keeps the highest fee rates, which can mean turning the new transaction away.
+	pool.MaxSize = pool.Size()
+	cheap := NewTransactionFromTransaction(spends[0])
+	cheap.Timestamp++
+	cheap.SignTx(privateKeyAlice, 0)
+	assert.Equal(t, []*Transaction{cheap}, pool.AddTransaction(cheap, 1))
+	assert.Nil(t, pool.GetTransaction(cheap.GetHash()))
+
+	rich := NewTransactionFromTransaction(spends[0])
+	rich.Timestamp += 2
+	rich.SignTx(privateKeyAlice, 0)
+	assert.Equal(t, []*Transaction{spends[0]}, pool.AddTransaction(rich, 1*COIN), "The lowest fee rate is evicted")
+	assert.Equal(t, []*Transaction{rich, spends[2], spends[1]}, pool.GetTransactions())
+
+	// A fee too large for its rate to be computed is turned away, not ranked.
+	_, ok := FeeRate(MAX_AMOUNT/100, 200)
+	assert.False(t, ok, "The fee rate should overflow")
+	huge := NewTransactionFromTransaction(spends[1])
+	huge.Timestamp += 3
+	huge.SignTx(privateKeyAlice, 0)
+	assert.False(t, pool.CanReplace(MAX_AMOUNT/100, huge.Size(), nil))
+	assert.Equal(t, []*Transaction{huge}, pool.AddTransaction(huge, MAX_AMOUNT/100))
+	assert.Nil(t, pool.GetTransaction(huge.GetHash()))
+
+	// Transactions waiting longer than the expiry are dropped.
+	pool.GetEntry(spends[1].GetHash()).Time -= MEMPOOL_EXPIRY + 1
+	assert.Equal(t, []*Transaction{spends[1]}, pool.Expire(time.Now().Unix()))
+	assert.Equal(t, []*Transaction{rich, spends[2]}, pool.GetTransactions())
+}
+
+func TestMempool_BlockCreateTakesHighestFees(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
+	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyBob := &privateKeyBob.PublicKey
+
+	privateKeyAlice, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyAlice := &privateKeyAlice.PublicKey
+
+	genesisBlock := NewBlock(nil, pubKeyBob)
+	genesisBlock.Finalizee()
+	localBlockchain := NewBlockchain(genesisBlock)
+	HandleBlocks(localBlockchain)
+
+	// Two transactions spend the same output; the one paying more replaces the other.
+	low := NewTransaction()
+	low.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
+	low.AddOutput(3*COIN, pubKeyAlice)
+	low.SignTx(privateKeyBob, 0)
+
+	high := NewTransaction()
+	high.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
+	high.AddOutput(2*COIN, pubKeyAlice)
+	high.SignTx(privateKeyBob, 0)
+
+	_, ok := TxProcess(low)
+	assert.True(t, ok)
+	replaced, ok := TxProcess(high)
+	assert.True(t, ok)
+	assert.Equal(t, []*Transaction{low}, replaced)
+	assert.Equal(t, []*Transaction{high}, localBlockchain.GetTransactionPool().GetTransactions())
+
+	block := BlockCreate(pubKeyBob)
+	assert.NotNil(t, block)
+	assert.Equal(t, 2, len(block.GetTransactions()))
+	assert.Equal(t, high.GetHash(), block.GetTransactions()[1].GetHash())
+	assert.Equal(t, COINBASE+1.125*COIN, block.GetCoinbase().GetOutput(0).Value, "The coinbase claims the higher fee")
+	assert.Empty(t, localBlockchain.GetTransactionPool().GetTransactions())
+}
+
+func TestMempool_UnconfirmedChainsAndPackages(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
+	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyBob := &privateKeyBob.PublicKey
+
+	privateKeyAlice, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyAlice := &privateKeyAlice.PublicKey
+
+	genesisBlock := NewBlock(nil, pubKeyBob)
+	genesisBlock.Finalizee()
+	localBlockchain := NewBlockchain(genesisBlock)
+	HandleBlocks(localBlockchain)
+
+	tx1 := NewTransaction()
+	tx1.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
+	tx1.AddOutput(1*COIN, pubKeyAlice)
+	tx1.AddOutput(1*COIN, pubKeyAlice)
+	tx1.AddOutput(1*COIN, pubKeyBob)
+	tx1.SignTx(privateKeyBob, 0)
+	TxProcess(tx1)
+	assert.NotNil(t, BlockCreate(pubKeyBob))
+
+	// The parent pays no fee; its child pays enough for both.
+	parent := NewTransaction()
+	parent.AddInput(tx1.GetHash(), 0)
+	parent.AddOutput(1*COIN, pubKeyAlice)
+	parent.SignTx(privateKeyAlice, 0)
+
+	child := NewTransaction()
+	child.AddInput(parent.GetHash(), 0)
+	child.AddOutput(COIN/2, pubKeyBob)
+	child.SignTx(privateKeyAlice, 0)
+
+	other := NewTransaction()
+	other.AddInput(tx1.GetHash(), 1)
+	other.AddOutput(0.9*COIN, pubKeyBob)
+	other.SignTx(privateKeyAlice, 0)
+
+	HandleTxs(localBlockchain.UTXOSet)
+	assert.Equal(t, []*Transaction{parent, child}, Handler([]*Transaction{child, parent}), "A child listed before its parent should be accepted")
+
+	TxProcess(child)
+	pool := localBlockchain.GetTransactionPool()
+	assert.Nil(t, pool.GetTransaction(child.GetHash()), "A child is not accepted before its parent")
+	TxProcess(parent)
+	TxProcess(child)
+	TxProcess(other)
+	assert.Equal(t, []*Transaction{parent, child}, pool.Ancestors(child.GetHash()))
+	assert.Equal(t, []*Transaction{parent, child}, pool.Descendants(parent.GetHash()))
+	assert.Equal(t, []*Transaction{other}, pool.Descendants(other.GetHash()))
+	assert.Equal(t, Amount(COIN/2), pool.GetEntry(parent.GetHash()).DescendantFee)
+	assert.Equal(t, parent.Size()+child.Size(), pool.GetEntry(parent.GetHash()).DescendantSize)
+	assert.Equal(t, Amount(COIN/2), pool.GetEntry(child.GetHash()).AncestorFee)
+	assert.Equal(t, parent.Size()+child.Size(), pool.GetEntry(child.GetHash()).AncestorSize)
+	assert.Equal(t, []*Transaction{parent, child, other}, pool.SelectPackages(MAX_BLOCK_SIZE), "The child pays for its parent")
+	assert.Equal(t, []*Transaction{parent, child}, pool.SelectPackages(parent.Size()+child.Size()))
+
+	// Eviction rates a parent by its descendants and takes them along.
+	copied := NewTransactionPoolFromPool(pool)
+	copied.RemoveTransaction(other.GetHash())
+	copied.MaxSize = pool.Size() - 1
+	assert.Equal(t, []*Transaction{other}, copied.AddTransaction(other, GetFee(other, localBlockchain.UTXOSet)))
+	assert.Equal(t, []*Transaction{parent, child}, copied.RemoveTransactionWithDescendants(parent.GetHash()))
+	assert.Equal(t, 0, copied.Size())
+	assert.Equal(t, 3, len(pool.GetTransactions()), "Copies do not share entries")
+
+	// Confirming the parent leaves the child on its own.
+	confirmed := NewTransactionPoolFromPool(pool)
+	confirmed.RemoveTransaction(parent.GetHash())
+	assert.Equal(t, child.Size(), confirmed.GetEntry(child.GetHash()).AncestorSize)
+	assert.Equal(t, Amount(COIN/2), confirmed.GetEntry(child.GetHash()).AncestorFee)
+	assert.Equal(t, []*Transaction{child, other}, confirmed.SelectPackages(MAX_BLOCK_SIZE))
+
+	block := BlockCreate(pubKeyBob)
+	assert.NotNil(t, block)
+	assert.Equal(t, 4, len(block.GetTransactions()))
+	assert.Equal(t, COINBASE+0.6*COIN, block.GetCoinbase().GetOutput(0).Value)
+	assert.Empty(t, pool.GetTransactions())
+}
+
+func TestMempool_ReplaceByFee(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
+	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyBob := &privateKeyBob.PublicKey
+
+	privateKeyAlice, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyAlice := &privateKeyAlice.PublicKey
+
+	genesisBlock := NewBlock(nil, pubKeyBob)
+	genesisBlock.Finalizee()
+	localBlockchain := NewBlockchain(genesisBlock)
+	HandleBlocks(localBlockchain)
+
+	tx1 := NewTransaction()
+	tx1.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
+	tx1.AddOutput(1*COIN, pubKeyAlice)
+	tx1.AddOutput(2*COIN, pubKeyAlice)
+	tx1.SignTx(privateKeyBob, 0)
+	TxProcess(tx1)
+	assert.NotNil(t, BlockCreate(pubKeyBob))
+	pool := localBlockchain.GetTransactionPool()
+
+	original := NewTransaction()
+	original.AddInput(tx1.GetHash(), 0)
+	original.AddOutput(0.9*COIN, pubKeyBob)
+	original.SignTx(privateKeyAlice, 0)
+	replaced, ok := TxProcess(original)
+	assert.True(t, ok)
+	assert.Empty(t, replaced)
+
+	child := NewTransaction()
+	child.AddInput(original.GetHash(), 0)
+	child.AddOutput(0.8*COIN, pubKeyAlice)
+	child.SignTx(privateKeyBob, 0)
+	_, ok = TxProcess(child)
+	assert.True(t, ok)
+
+	// Replacing the original also replaces its child, so the fee has to exceed both.
+	assert.Equal(t, []*Transaction{original, child}, pool.Conflicts(doubleSpend(tx1, 0, 0.8*COIN, pubKeyAlice, privateKeyAlice)))
+	replaced, ok = TxProcess(doubleSpend(tx1, 0, 0.85*COIN, pubKeyAlice, privateKeyAlice))
+	assert.False(t, ok, "A higher fee than the original alone is not enough")
+	assert.Empty(t, replaced)
+	replaced, ok = TxProcess(doubleSpend(tx1, 0, 0.8*COIN, pubKeyAlice, privateKeyAlice))
+	assert.False(t, ok, "An equal total fee is not enough")
+	assert.Empty(t, replaced)
+	assert.Equal(t, []*Transaction{original, child}, pool.GetTransactions())
+
+	replacement := doubleSpend(tx1, 0, 0.7*COIN, pubKeyAlice, privateKeyAlice)
+	replaced, ok = TxProcess(replacement)
+	assert.True(t, ok)
+	assert.Equal(t, []*Transaction{original, child}, replaced)
+	assert.Equal(t, []*Transaction{replacement}, pool.GetTransactions())
+
+	// A larger transaction paying more in total may still pay a lower fee rate.
+	assert.False(t, pool.CanReplace(0.31*COIN, 2*replacement.Size(), []*Transaction{replacement}))
+	assert.True(t, pool.CanReplace(0.31*COIN, replacement.Size(), []*Transaction{replacement}))
+	assert.True(t, pool.CanReplace(0, replacement.Size(), nil), "Without conflicts there is nothing to outbid")
+
+	// A replacement the full pool would evict right away leaves the pool as it was.
+	larger := NewTransaction()
+	larger.AddInput(tx1.GetHash(), 0)
+	larger.AddOutput(0.2*COIN, pubKeyAlice)
+	larger.AddOutput(0.2*COIN, pubKeyBob)
+	larger.SignTx(privateKeyAlice, 0)
+	pool.MaxSize = replacement.Size()
+	replaced, ok = TxProcess(larger)
+	assert.False(t, ok, "A replacement that does not fit should be rejected")
+	assert.Empty(t, replaced)
+	assert.Equal(t, []*Transaction{replacement}, pool.GetTransactions())
+	assert.Equal(t, replacement.Size(), pool.Size())
+	pool.MaxSize = MAX_MEMPOOL_SIZE
+
+	block := BlockCreate(pubKeyBob)
+	assert.NotNil(t, block)
+	assert.Equal(t, replacement.GetHash(), block.GetTransactions()[1].GetHash())
+}
+
+// doubleSpend returns a signed transaction spending output index of tx to pubKey.
+func doubleSpend(tx *Transaction, index int, value Amount, pubKey PublicKey, sk PrivateKey) *Transaction {
+	spend := NewTransaction()
+	spend.AddInput(tx.GetHash(), index)
+	spend.AddOutput(value, pubKey)
+	spend.SignTx(sk, 0)
+	return spend
+}
@@ -0,0 +1,122 @@
+package third_faza
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+)
+
+// MerkleRoot computes the root of the binary Merkle tree built over the given hashes.
+// Each level hashes adjacent pairs with SHA-256; an odd node is paired with itself.
+// An empty list yields an all-zero root.
+func MerkleRoot(hashes [][]byte) []byte {
+	if len(hashes) == 0 {
+		return make([]byte, sha256.Size)
+	}
+
+	level := make([][]byte, len(hashes))
+	copy(level, hashes)
+	for len(level) > 1 {
+		level = merkleParents(level)
+	}
+	return append([]byte{}, level[0]...)
+}
+
+// merkleParents hashes one level of the tree into the level above it.
+func merkleParents(level [][]byte) [][]byte {
+	parents := make([][]byte, 0, (len(level)+1)/2)
+	for i := 0; i < len(level); i += 2 {
+		left := level[i]
+		right := left
+		if i+1 < len(level) {
+			right = level[i+1]
+		}
+		parents = append(parents, merkleHash(left, right))
+	}
+	return parents
+}
+
+// merkleHash returns the hash of the concatenation of two child nodes.
+func merkleHash(left, right []byte) []byte {
+	data := make([]byte, 0, len(left)+len(right))
+	data = append(data, left...)
+	data = append(data, right...)
+	hash := sha256.Sum256(data)
+	return hash[:]
+}
+
+// MerkleProof shows that the transaction TxHash is the leaf at position Index of the Merkle tree
+// of the block BlockHash. Branch lists the sibling hashes from the leaf level up to the root.
+type MerkleProof struct {
+	BlockHash []byte
+	TxHash    []byte
+	Index     int
+	Branch    [][]byte
+}
+
+// MerkleBranch returns the sibling hashes needed to recompute the root from the leaf at index.
+func MerkleBranch(hashes [][]byte, index int) [][]byte {
+	if index < 0 || index >= len(hashes) {
+		return nil
+	}
+
+	branch := make([][]byte, 0)
+	level := make([][]byte, len(hashes))
+	copy(level, hashes)
+	for len(level) > 1 {
+		sibling := index ^ 1
+		if sibling >= len(level) {
+			sibling = index
+		}
+		branch = append(branch, append([]byte{}, level[sibling]...))
+
+		level = merkleParents(level)
+		index /= 2
+	}
+	return branch
+}
+
+// GetMerkleProof builds an inclusion proof for the transaction with the given hash.
+// The block has to be finalized so that the proof refers to its hash.
+func (block *Block) GetMerkleProof(txHash []byte) (*MerkleProof, error) {
+	hashes := make([][]byte, len(block.txs))
+	index := -1
+	for i, tx := range block.txs {
+		hashes[i] = tx.GetHash()
+		if index < 0 && bytes.Equal(hashes[i], txHash) {
+			index = i
+		}
+	}
+	if index < 0 {
+		return nil, errors.New("transaction is not in the block")
+	}
+
+	return &MerkleProof{
+		BlockHash: append([]byte{}, block.GetHash()...),
+		TxHash:    append([]byte{}, txHash...),
+		Index:     index,
+		Branch:    MerkleBranch(hashes, index),
+	}, nil
+}
+
+// VerifyMerkleProof recomputes the root from the proof and compares it with the given Merkle root.
+func VerifyMerkleProof(root []byte, proof *MerkleProof) bool {
+	if proof == nil || proof.Index < 0 || len(proof.TxHash) != sha256.Size {
+		return false
+	}
+
+	hash := proof.TxHash
+	index := proof.Index
+	for _, sibling := range proof.Branch {
+		if len(sibling) != sha256.Size {
+			return false
+		}
+		if index%2 == 0 {
+			hash = merkleHash(hash, sibling)
+		} else {
+			hash = merkleHash(sibling, hash)
+		}
+		index /= 2
+	}
+	return index == 0 && bytes.Equal(hash, root)
+}
@@ -0,0 +1,134 @@
+package third_faza
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMultiSig_ThreeOfFiveNeedsExactlyThreeDistinctSignatures(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
+	privateKeys := make([]*rsa.PrivateKey, 5)
+	addresses := make([]PublicKey, 5)
+	for i := range privateKeys {
+		privateKey, err := rsa.GenerateKey(rand.Reader, 1024)
+		if err != nil {
+			log.Fatal(err)
+		}
+		privateKeys[i] = privateKey
+		addresses[i] = &privateKey.PublicKey
+	}
+
+	genesisBlock := NewBlock(nil, addresses[0])
+	genesisBlock.Finalizee()
+	localBlockchain := NewBlockchain(genesisBlock)
+	HandleBlocks(localBlockchain)
+
+	tx1 := NewTransaction()
+	tx1.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
+	tx1.AddMultisigOutput(NewMOfNMultiSigOutput(3*COIN, 3, addresses))
+	tx1.SignTx(privateKeys[0], 0)
+
+	block1 := NewBlock(genesisBlock.GetHash(), addresses[0])
+	block1.TransactionAdd(tx1)
+	block1.Finalizee()
+	assert.True(t, BlockProcess(block1), "Block with a 3-of-5 output should be accepted")
+
+	spend := func(signers ...int) *Transaction {
+		tx := NewTransaction()
+		tx.AddInput(tx1.GetHash(), 0)
+		tx.AddOutput(3*COIN, addresses[4])
+		for _, signer := range signers {
+			tx.SignMultiSigTx(privateKeys[signer], 0)
+		}
+		return tx
+	}
+	pool := localBlockchain.UTXOSet
+
+	assert.False(t, TxIsValid(*spend(0, 2), pool), "Two signatures should not satisfy a 3-of-5 output")
+	assert.True(t, TxIsValid(*spend(0, 2, 4), pool), "Three distinct signatures should satisfy a 3-of-5 output")
+	assert.True(t, TxIsValid(*spend(3, 1, 2), pool), "Signature order should not matter")
+	assert.False(t, TxIsValid(*spend(0, 2, 4, 1), pool), "Extra signatures should be rejected")
+
+	duplicate := spend(0, 2)
+	duplicate.Inputs[0].AddMultiSignature(duplicate.Inputs[0].MultiSigSignature[1])
+	duplicate.Finalize()
+	assert.False(t, TxIsValid(*duplicate, pool), "A repeated signature should not count twice")
+
+	sameKey := spend(0, 2, 2)
+	assert.False(t, TxIsValid(*sameKey, pool), "Two signatures of the same key should not count twice")
+
+	outsider, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	foreign := spend(0, 2)
+	foreign.SignMultiSigTx(outsider, 0)
+	assert.False(t, TxIsValid(*foreign, pool), "A signature of a key outside the output should be rejected")
+}
+
+func TestMultiSig_RequiredSigsIsCommittedAndChecked(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
+	privateKeys := make([]*rsa.PrivateKey, 3)
+	addresses := make([]PublicKey, 3)
+	for i := range privateKeys {
+		privateKey, err := rsa.GenerateKey(rand.Reader, 1024)
+		if err != nil {
+			log.Fatal(err)
+		}
+		privateKeys[i] = privateKey
+		addresses[i] = &privateKey.PublicKey
+	}
+
+	genesisBlock := NewBlock(nil, addresses[0])
+	genesisBlock.Finalizee()
+	localBlockchain := NewBlockchain(genesisBlock)
+	HandleBlocks(localBlockchain)
+
+	twoOfThree := NewTransaction()
+	twoOfThree.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
+	twoOfThree.AddMultisigOutput(NewMOfNMultiSigOutput(3*COIN, 2, addresses))
+	twoOfThree.SignTx(privateKeys[0], 0)
+	assert.True(t, TxIsValid(*twoOfThree, localBlockchain.UTXOSet))
+
+	// Raising the threshold after signing changes what was signed.
+	hash := twoOfThree.GetHash()
+	twoOfThree.Outputs[0].RequiredSigs = 3
+	twoOfThree.Finalize()
+	assert.NotEqual(t, hash, twoOfThree.GetHash())
+	assert.False(t, TxIsValid(*twoOfThree, localBlockchain.UTXOSet), "Signature should cover the required signature count")
+
+	tooManyKeys := make([]PublicKey, MAX_MULTISIG_KEYS+1)
+	for i := range tooManyKeys {
+		privateKey, err := GenerateKey()
+		if err != nil {
+			log.Fatal(err)
+		}
+		tooManyKeys[i] = PublicKeyOf(privateKey)
+	}
+
+	for _, output := range []*Output{
+		NewMOfNMultiSigOutput(3*COIN, 0, addresses),
+		NewMOfNMultiSigOutput(3*COIN, 1, tooManyKeys),
+		NewMOfNMultiSigOutput(3*COIN, 4, addresses),
+		NewMOfNMultiSigOutput(3*COIN, 2, []PublicKey{addresses[0], addresses[1], addresses[0]}),
+	} {
+		invalid := NewTransaction()
+		invalid.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
+		invalid.AddMultisigOutput(output)
+		invalid.SignTx(privateKeys[0], 0)
+		assert.False(t, TxIsValid(*invalid, localBlockchain.UTXOSet), "Unspendable multisig output should be rejected")
+	}
+
+	data, err := twoOfThree.MarshalBinary()
+	assert.NoError(t, err)
+	decoded := &Transaction{}
+	assert.NoError(t, decoded.UnmarshalBinary(data))
+	assert.Equal(t, 3, decoded.Outputs[0].RequiredSigs, "Required signature count should be encoded")
+	assert.Equal(t, twoOfThree.GetHash(), decoded.GetHash())
+}
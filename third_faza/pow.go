@@ -0,0 +1,129 @@
+package third_faza
+
+import (
+	"math/big"
+)
+
+const (
+	// POW_LIMIT_BITS is the compact encoding of the easiest allowed target.
+	// It is also the target of the genesis block and of the first retarget window.
+	POW_LIMIT_BITS uint32 = 0x2000ffff
+	// DIFFICULTY_ADJUSTMENT_INTERVAL is the number of blocks between two retargets.
+	DIFFICULTY_ADJUSTMENT_INTERVAL = 10
+	// TARGET_BLOCK_SPACING is the desired number of seconds between two blocks.
+	TARGET_BLOCK_SPACING = 10
+	// MAX_ADJUSTMENT_FACTOR limits how much the target may change in a single retarget.
+	MAX_ADJUSTMENT_FACTOR = 4
+)
+
+// CompactToBig converts a compact "bits" value (as used in Bitcoin headers) into the full target.
+// The top byte is a base-256 exponent and the lower three bytes are the mantissa.
+func CompactToBig(bits uint32) *big.Int {
+	mantissa := int64(bits & 0x007fffff)
+	negative := bits&0x00800000 != 0
+	exponent := uint(bits >> 24)
+
+	target := big.NewInt(mantissa)
+	if exponent <= 3 {
+		target.Rsh(target, 8*(3-exponent))
+	} else {
+		target.Lsh(target, 8*(exponent-3))
+	}
+	if negative {
+		target.Neg(target)
+	}
+	return target
+}
+
+// BigToCompact converts a target into its compact "bits" representation.
+// Precision below the three mantissa bytes is lost.
+func BigToCompact(target *big.Int) uint32 {
+	if target.Sign() == 0 {
+		return 0
+	}
+
+	exponent := uint((target.BitLen() + 7) / 8)
+	var mantissa uint32
+	if exponent <= 3 {
+		mantissa = uint32(new(big.Int).Lsh(target, 8*(3-exponent)).Uint64())
+	} else {
+		mantissa = uint32(new(big.Int).Rsh(target, 8*(exponent-3)).Uint64())
+	}
+
+	// The sign bit must stay clear, so move one byte into the exponent if needed.
+	if mantissa&0x00800000 != 0 {
+		mantissa >>= 8
+		exponent++
+	}
+
+	bits := uint32(exponent<<24) | mantissa
+	if target.Sign() < 0 {
+		bits |= 0x00800000
+	}
+	return bits
+}
+
+// PowLimit returns the easiest target a block may be mined against.
+func PowLimit() *big.Int {
+	return CompactToBig(POW_LIMIT_BITS)
+}
+
+// HashMeetsTarget reports whether the hash, read as a big-endian number, does not exceed the target encoded in bits.
+func HashMeetsTarget(hash []byte, bits uint32) bool {
+	target := CompactToBig(bits)
+	if target.Sign() <= 0 || target.Cmp(PowLimit()) > 0 {
+		return false
+	}
+	return new(big.Int).SetBytes(hash).Cmp(target) <= 0
+}
+
+// NextWorkRequired returns the bits a block built on top of parent must be mined against.
+// The target stays the same inside a window of DIFFICULTY_ADJUSTMENT_INTERVAL blocks and is
+// scaled by the ratio of the actual to the expected timespan of the window when a new one starts.
+func NextWorkRequired(parent *BlockNode) uint32 {
+	if parent == nil {
+		return POW_LIMIT_BITS
+	}
+
+	parentBits := parent.B.GetBits()
+	if parent.Height%DIFFICULTY_ADJUSTMENT_INTERVAL != 0 {
+		return parentBits
+	}
+
+	first := parent
+	for i := 0; i < DIFFICULTY_ADJUSTMENT_INTERVAL-1 && first.Parent != nil; i++ {
+		first = first.Parent
+	}
+	if first == parent {
+		return parentBits
+	}
+
+	expected := int64(TARGET_BLOCK_SPACING * (parent.Height - first.Height))
+	actual := parent.B.GetTimestamp() - first.B.GetTimestamp()
+	if actual < expected/MAX_ADJUSTMENT_FACTOR {
+		actual = expected / MAX_ADJUSTMENT_FACTOR
+	}
+	if actual > expected*MAX_ADJUSTMENT_FACTOR {
+		actual = expected * MAX_ADJUSTMENT_FACTOR
+	}
+
+	target := CompactToBig(parentBits)
+	target.Mul(target, big.NewInt(actual))
+	target.Div(target, big.NewInt(expected))
+
+	if target.Cmp(PowLimit()) > 0 {
+		target = PowLimit()
+	}
+	return BigToCompact(target)
+}
+
+// BlockWork returns the expected number of hashes needed to mine a block against bits,
+// 2^256 / (target + 1). Invalid targets count as no work.
+func BlockWork(bits uint32) *big.Int {
+	target := CompactToBig(bits)
+	if target.Sign() <= 0 {
+		return big.NewInt(0)
+	}
+	work := new(big.Int).Lsh(big.NewInt(1), 256)
+	return work.Div(work, target.Add(target, big.NewInt(1)))
+}
@@ -0,0 +1,219 @@
+package third_faza
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"log"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPow_CompactRoundTrip(t *testing.T) {
+	for _, bits := range []uint32{POW_LIMIT_BITS, 0x1d00ffff, 0x1b0404cb, 0x207fffff} {
+		assert.Equal(t, bits, BigToCompact(CompactToBig(bits)), fmt.Sprintf("bits %08x should survive a round trip", bits))
+	}
+}
+
+func TestPow_FinalizedBlockMeetsTarget(t *testing.T) {
+	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyBob := &privateKeyBob.PublicKey
+
+	genesisBlock := NewBlock(nil, pubKeyBob)
+	genesisBlock.Finalizee()
+
+	assert.True(t, HashMeetsTarget(genesisBlock.GetHash(), genesisBlock.GetBits()), "Mined hash should meet the target")
+	assert.Equal(t, genesisBlock.GetHash(), genesisBlock.CalculateHash(), "Stored hash should match the block contents")
+}
+
+func TestPow_BlockWithTamperedContentsIsRejected(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
+	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyBob := &privateKeyBob.PublicKey
+
+	privateKeyAlice, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyAlice := &privateKeyAlice.PublicKey
+
+	genesisBlock := NewBlock(nil, pubKeyBob)
+	genesisBlock.Finalizee()
+	localBlockchain := NewBlockchain(genesisBlock)
+	HandleBlocks(localBlockchain)
+
+	block1 := NewBlock(genesisBlock.GetHash(), pubKeyAlice)
+	block1.Finalizee()
+
+	tx1 := NewTransaction()
+	tx1.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
+	tx1.AddOutput(3.125*COIN, pubKeyAlice)
+	tx1.SignTx(privateKeyBob, 0)
+	block1.TransactionAdd(tx1)
+
+	assert.False(t, BlockProcess(block1), "Block changed after mining should be rejected")
+
+	block1.Finalizee()
+	assert.True(t, BlockProcess(block1), "Block mined again over its new contents should be accepted")
+}
+
+func TestPow_BlockWithWrongDifficultyIsRejected(t *testing.T) {
+	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyBob := &privateKeyBob.PublicKey
+
+	genesisBlock := NewBlock(nil, pubKeyBob)
+	genesisBlock.Finalizee()
+	localBlockchain := NewBlockchain(genesisBlock)
+	HandleBlocks(localBlockchain)
+
+	block1 := NewBlock(genesisBlock.GetHash(), pubKeyBob)
+	block1.SetBits(0x1f00ffff)
+	block1.Finalizee()
+
+	assert.False(t, BlockProcess(block1), "Block mined against a target different from the expected one should be rejected")
+
+	block2 := NewBlock(genesisBlock.GetHash(), pubKeyBob)
+	block2.Finalizee()
+	for HashMeetsTarget(block2.CalculateHash(), block2.GetBits()) {
+		block2.header.Nonce++
+	}
+	block2.hash = block2.CalculateHash()
+
+	assert.False(t, BlockProcess(block2), "Block whose hash does not meet the target should be rejected")
+}
+
+func TestPow_DifficultyRetargetsEveryInterval(t *testing.T) {
+	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyBob := &privateKeyBob.PublicKey
+
+	genesisBlock := NewBlock(nil, pubKeyBob)
+	genesisBlock.Finalizee()
+	localBlockchain := NewBlockchain(genesisBlock)
+	HandleBlocks(localBlockchain)
+
+	// Blocks arrive much faster than TARGET_BLOCK_SPACING, so the target must shrink.
+	prev := genesisBlock
+	for i := 1; i < DIFFICULTY_ADJUSTMENT_INTERVAL; i++ {
+		block := NewBlock(prev.GetHash(), pubKeyBob)
+		block.SetTimestamp(genesisBlock.GetTimestamp() + int64(i))
+		assert.Equal(t, POW_LIMIT_BITS, block.GetBits(), "Difficulty should not change inside a retarget window")
+		block.Finalizee()
+		assert.True(t, BlockProcess(block), fmt.Sprintf("Block #%d should be accepted", i))
+		prev = block
+	}
+
+	fast := NewBlock(prev.GetHash(), pubKeyBob)
+	assert.Equal(t, -1, CompactToBig(fast.GetBits()).Cmp(PowLimit()), "Fast blocks should make the next target harder")
+
+	// A slow window cannot make the target easier than the proof-of-work limit.
+	parent := localBlockchain.Get(prev.GetHash())
+	parent.B.SetTimestamp(genesisBlock.GetTimestamp() + 100*TARGET_BLOCK_SPACING*DIFFICULTY_ADJUSTMENT_INTERVAL)
+	assert.Equal(t, POW_LIMIT_BITS, NextWorkRequired(parent), "Target should be capped at the proof-of-work limit")
+}
+
+func TestPow_TipFollowsChainWorkNotHeight(t *testing.T) {
+	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyBob := &privateKeyBob.PublicKey
+
+	genesisBlock := NewBlock(nil, pubKeyBob)
+	genesisBlock.SetTimestamp(time.Now().Unix() - 1000)
+	genesisBlock.Finalizee()
+	localBlockchain := NewBlockchain(genesisBlock)
+	HandleBlocks(localBlockchain)
+
+	mine := func(parent *Block, timestamp int64) *Block {
+		block := NewBlock(parent.GetHash(), pubKeyBob)
+		block.SetTimestamp(timestamp)
+		block.Finalizee()
+		assert.True(t, BlockProcess(block))
+		return block
+	}
+
+	prev := genesisBlock
+	for i := 1; i < DIFFICULTY_ADJUSTMENT_INTERVAL-1; i++ {
+		prev = mine(prev, genesisBlock.GetTimestamp()+int64(i))
+	}
+	fork := prev
+
+	// Branch A keeps the fast pace, so its block after the retarget is harder.
+	blockA1 := mine(fork, genesisBlock.GetTimestamp()+DIFFICULTY_ADJUSTMENT_INTERVAL)
+	blockA2 := mine(blockA1, genesisBlock.GetTimestamp()+DIFFICULTY_ADJUSTMENT_INTERVAL+1)
+	assert.Equal(t, 1, BlockWork(blockA2.GetBits()).Cmp(BlockWork(POW_LIMIT_BITS)), "Harder target should carry more work")
+
+	// Branch B is slow and stays at the proof-of-work limit, but grows one block longer.
+	blockB1 := mine(fork, genesisBlock.GetTimestamp()+500)
+	blockB2 := mine(blockB1, genesisBlock.GetTimestamp()+501)
+	assert.Equal(t, POW_LIMIT_BITS, blockB2.GetBits())
+	blockB3 := mine(blockB2, genesisBlock.GetTimestamp()+502)
+
+	tip := localBlockchain.GetBlockNodeAtMaxHeight()
+	assert.Equal(t, blockA2.GetHash(), tip.B.GetHash(), "Branch with more work should stay the tip")
+	assert.Greater(t, localBlockchain.Get(blockB3.GetHash()).Height, tip.Height)
+	assert.Equal(t, 1, tip.ChainWork.Cmp(localBlockchain.Get(blockB3.GetHash()).ChainWork))
+
+	expected := new(big.Int).Add(localBlockchain.Get(blockA1.GetHash()).ChainWork, BlockWork(blockA2.GetBits()))
+	assert.Equal(t, 0, expected.Cmp(tip.ChainWork), "Chain work should add the work of every block")
+}
+
+func TestPow_EqualWorkTieGoesToLowerHash(t *testing.T) {
+	keys := make([]PublicKey, 5)
+	for i := range keys {
+		privateKey, err := rsa.GenerateKey(rand.Reader, 1024)
+		if err != nil {
+			log.Fatal(err)
+		}
+		keys[i] = &privateKey.PublicKey
+	}
+
+	genesisBlock := NewBlock(nil, keys[0])
+	genesisBlock.Finalizee()
+	chains := []*Blockchain{NewBlockchain(genesisBlock), NewBlockchain(genesisBlock)}
+	HandleBlocks(chains[0])
+
+	mine := func(parent *Block, address PublicKey) *Block {
+		block := NewBlock(parent.GetHash(), address)
+		block.Finalizee()
+		return block
+	}
+	blockA1 := mine(genesisBlock, keys[1])
+	blockA2 := mine(blockA1, keys[2])
+	blockB1 := mine(genesisBlock, keys[3])
+	blockB2 := mine(blockB1, keys[4])
+
+	winner := blockA2
+	if bytes.Compare(blockB2.GetHash(), blockA2.GetHash()) < 0 {
+		winner = blockB2
+	}
+
+	// Both orders of arrival end on the same tip.
+	orders := [][]*Block{{blockA1, blockA2, blockB1, blockB2}, {blockB1, blockB2, blockA1, blockA2}}
+	for i, order := range orders {
+		HandleBlocks(chains[i])
+		for _, block := range order {
+			assert.True(t, BlockProcess(block))
+		}
+		assert.Equal(t, winner.GetHash(), chains[i].GetBlockAtMaxHeight().GetHash(), fmt.Sprintf("Order #%d should pick the lower hash", i))
+		assert.Equal(t, 2, len(chains[i].MaxHeightNode))
+		assert.True(t, chains[i].UTXOSet.Contains(*NewUTXO(winner.GetCoinbase().GetHash(), 0)), "The UTXO set should follow the tip")
+	}
+}
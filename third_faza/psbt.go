@@ -0,0 +1,273 @@
+package third_faza
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+)
+
+// PSBT_VERSION is the version written to encoded partially signed transactions.
+const PSBT_VERSION = 1
+
+var (
+	ErrPSBTMissingOutput     = errors.New("spent output not found")
+	ErrPSBTMismatch          = errors.New("partially signed transactions are for different transactions")
+	ErrPSBTIncomplete        = errors.New("not enough signatures to finalize the transaction")
+	ErrPSBTUnsupportedOutput = errors.New("spent output cannot be signed from a partially signed transaction")
+)
+
+// PSBTInput carries what a cosigner needs to sign one input: the output it spends and the
+// signatures collected so far, keyed by the hex of the signer's EncodePublicKey.
+type PSBTInput struct {
+	SpentOutput *Output
+	Signatures  map[string][]byte
+}
+
+// PartiallySignedTransaction lets several parties sign a transaction without sharing their
+// keys. Each cosigner signs a copy with Sign, the copies are combined with Merge, and
+// Finalize turns the collected signatures into the witness of a spendable transaction.
+// Tx never carries witness data, so its ID is the one of the final transaction.
+type PartiallySignedTransaction struct {
+	Tx     *Transaction
+	Inputs []*PSBTInput
+}
+
+// NewPSBT returns a partially signed transaction for tx with the outputs it spends taken
+// from pool. Signatures already in tx are dropped.
+func NewPSBT(tx *Transaction, pool *UTXOPool) (*PartiallySignedTransaction, error) {
+	unsigned := NewTransactionFromTransaction(tx)
+	psbt := &PartiallySignedTransaction{Tx: unsigned, Inputs: make([]*PSBTInput, len(unsigned.Inputs))}
+	for i, in := range unsigned.Inputs {
+		in.Signature = nil
+		in.MultiSigSignature = nil
+		in.UnlockScript = nil
+
+		spent := pool.GetTxOutput(*NewUTXO(in.PrevTxHash, in.OutputIndex))
+		if spent == nil {
+			return nil, ErrPSBTMissingOutput
+		}
+		spentCopy := *spent
+		psbt.Inputs[i] = &PSBTInput{SpentOutput: &spentCopy, Signatures: make(map[string][]byte)}
+	}
+	unsigned.Finalize()
+	return psbt, nil
+}
+
+// SignableBy reports whether a signature of pubKey helps to spend the output: it pays to the
+// key or its address, or the key is one of its multisig cosigners.
+func (out *Output) SignableBy(pubKey PublicKey) bool {
+	if out.IsMultiSig() {
+		for _, address := range out.MultiSigAddresses {
+			if keysEqual(address, pubKey) {
+				return true
+			}
+		}
+		return false
+	}
+	return out.PaysTo(pubKey)
+}
+
+// Sign adds a SIGHASH_ALL signature of sk to every input it can help to spend and returns the
+// number of inputs signed.
+func (psbt *PartiallySignedTransaction) Sign(sk PrivateKey) int {
+	return psbt.SignWithHashType(sk, SIGHASH_ALL)
+}
+
+// SignWithHashType is Sign with signatures covering only what hashType selects.
+func (psbt *PartiallySignedTransaction) SignWithHashType(sk PrivateKey, hashType SigHashType) int {
+	pubKey := PublicKeyOf(sk)
+	keyId := hex.EncodeToString(EncodePublicKey(pubKey))
+	signed := 0
+	for i, in := range psbt.Inputs {
+		if !in.SpentOutput.SignableBy(pubKey) {
+			continue
+		}
+		sig := psbt.Tx.CreateSignatureWithHashType(sk, i, hashType)
+		if sig == nil {
+			continue
+		}
+		in.Signatures[keyId] = sig
+		signed++
+	}
+	return signed
+}
+
+// Merge adds the signatures collected in other, which must be for the same transaction.
+func (psbt *PartiallySignedTransaction) Merge(other *PartiallySignedTransaction) error {
+	if !bytes.Equal(psbt.Tx.GetHash(), other.Tx.GetHash()) || len(psbt.Inputs) != len(other.Inputs) {
+		return ErrPSBTMismatch
+	}
+	for i, in := range other.Inputs {
+		for keyId, sig := range in.Signatures {
+			psbt.Inputs[i].Signatures[keyId] = sig
+		}
+	}
+	return nil
+}
+
+// signatureOf returns the collected signature of pubKey for the input if it verifies.
+func (psbt *PartiallySignedTransaction) signatureOf(input int, pubKey PublicKey) []byte {
+	sig := psbt.Inputs[input].Signatures[hex.EncodeToString(EncodePublicKey(pubKey))]
+	if sig == nil || !psbt.Tx.VerifyInputSignature(input, sig, pubKey) {
+		return nil
+	}
+	return sig
+}
+
+// Finalize returns the transaction with the witness of every input built from the collected
+// signatures. Signatures that do not verify are ignored.
+func (psbt *PartiallySignedTransaction) Finalize() (*Transaction, error) {
+	tx := NewTransactionFromTransaction(psbt.Tx)
+	for i, in := range psbt.Inputs {
+		out := in.SpentOutput
+		switch {
+		case out.IsMultiSig():
+			sigs := make([][]byte, 0, out.RequiredSigs)
+			for _, address := range out.MultiSigAddresses {
+				if len(sigs) == out.RequiredSigs {
+					break
+				}
+				if sig := psbt.signatureOf(i, address); sig != nil {
+					sigs = append(sigs, sig)
+				}
+			}
+			if len(sigs) < out.RequiredSigs {
+				return nil, ErrPSBTIncomplete
+			}
+			tx.Inputs[i].MultiSigSignature = sigs
+
+		case len(out.LockScript) > 0:
+			pubKeyHash := ExtractPubKeyHash(out.LockScript)
+			if pubKeyHash == nil {
+				return nil, ErrPSBTUnsupportedOutput
+			}
+			var unlock Script
+			for keyId := range in.Signatures {
+				encodedKey, _ := hex.DecodeString(keyId)
+				pubKey, err := DecodePublicKey(encodedKey)
+				if err != nil || !bytes.Equal(PubKeyHash(pubKey), pubKeyHash) {
+					continue
+				}
+				if sig := psbt.signatureOf(i, pubKey); sig != nil {
+					unlock = SignatureScript(sig, encodedKey)
+					break
+				}
+			}
+			if unlock == nil {
+				return nil, ErrPSBTIncomplete
+			}
+			tx.Inputs[i].UnlockScript = unlock
+
+		case out.Address != nil:
+			sig := psbt.signatureOf(i, out.Address)
+			if sig == nil {
+				return nil, ErrPSBTIncomplete
+			}
+			tx.Inputs[i].AddSignature(sig)
+
+		default:
+			return nil, ErrPSBTUnsupportedOutput
+		}
+	}
+	tx.Finalize()
+	return tx, nil
+}
+
+type jsonPSBTInput struct {
+	SpentOutput *Output           `json:"spentOutput"`
+	Signatures  map[string]string `json:"signatures,omitempty"`
+}
+
+type jsonPSBT struct {
+	Version     int              `json:"version"`
+	Transaction *Transaction     `json:"transaction"`
+	Inputs      []*jsonPSBTInput `json:"inputs"`
+}
+
+// MarshalJSON encodes the transaction, the spent outputs and hex signatures keyed by public key.
+func (psbt *PartiallySignedTransaction) MarshalJSON() ([]byte, error) {
+	j := jsonPSBT{Version: PSBT_VERSION, Transaction: psbt.Tx, Inputs: make([]*jsonPSBTInput, len(psbt.Inputs))}
+	for i, in := range psbt.Inputs {
+		sigs := make(map[string]string, len(in.Signatures))
+		for keyId, sig := range in.Signatures {
+			sigs[keyId] = hex.EncodeToString(sig)
+		}
+		j.Inputs[i] = &jsonPSBTInput{SpentOutput: in.SpentOutput, Signatures: sigs}
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON decodes a partially signed transaction produced by MarshalJSON.
+func (psbt *PartiallySignedTransaction) UnmarshalJSON(data []byte) error {
+	var j jsonPSBT
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	if j.Version != PSBT_VERSION {
+		return ErrUnsupportedVersion
+	}
+	if j.Transaction == nil || len(j.Inputs) != len(j.Transaction.Inputs) {
+		return ErrInvalidEncoding
+	}
+
+	inputs := make([]*PSBTInput, len(j.Inputs))
+	for i, in := range j.Inputs {
+		if in == nil || in.SpentOutput == nil {
+			return ErrPSBTMissingOutput
+		}
+		sigs := make(map[string][]byte, len(in.Signatures))
+		for keyId, encoded := range in.Signatures {
+			sig, err := hex.DecodeString(encoded)
+			if err != nil {
+				return err
+			}
+			sigs[keyId] = sig
+		}
+		inputs[i] = &PSBTInput{SpentOutput: in.SpentOutput, Signatures: sigs}
+	}
+	psbt.Tx = j.Transaction
+	psbt.Inputs = inputs
+	return nil
+}
+
+// EncodePSBT writes the partially signed transaction to w as indented JSON.
+func EncodePSBT(w io.Writer, psbt *PartiallySignedTransaction) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(psbt)
+}
+
+// DecodePSBT reads a partially signed transaction written by EncodePSBT from r.
+func DecodePSBT(r io.Reader) (*PartiallySignedTransaction, error) {
+	psbt := &PartiallySignedTransaction{}
+	if err := json.NewDecoder(r).Decode(psbt); err != nil {
+		return nil, err
+	}
+	return psbt, nil
+}
+
+// WritePSBTFile stores the partially signed transaction as JSON at path.
+func WritePSBTFile(path string, psbt *PartiallySignedTransaction) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := EncodePSBT(file, psbt); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// ReadPSBTFile loads a partially signed transaction written by WritePSBTFile.
+func ReadPSBTFile(path string) (*PartiallySignedTransaction, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return DecodePSBT(file)
+}
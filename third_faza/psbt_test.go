@@ -0,0 +1,129 @@
+package third_faza
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"log"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPSBT_MultiPartySigning(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
+	privateKeys := make([]*rsa.PrivateKey, 3)
+	addresses := make([]PublicKey, 3)
+	for i := range privateKeys {
+		privateKey, err := rsa.GenerateKey(rand.Reader, 1024)
+		if err != nil {
+			log.Fatal(err)
+		}
+		privateKeys[i] = privateKey
+		addresses[i] = &privateKey.PublicKey
+	}
+	privateKeyBob := privateKeys[0]
+	pubKeyBob := addresses[0]
+
+	genesisBlock := NewBlock(nil, pubKeyBob)
+	genesisBlock.Finalizee()
+	localBlockchain := NewBlockchain(genesisBlock)
+	HandleBlocks(localBlockchain)
+
+	// Bob funds a 2-of-3 multisig output, keeps change at his address and a plain output.
+	tx1 := NewTransaction()
+	tx1.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
+	tx1.AddMultisigOutput(NewMOfNMultiSigOutput(2*COIN, 2, addresses))
+	assert.NoError(t, tx1.AddAddressOutput(1*COIN, AddressOf(pubKeyBob)))
+	tx1.AddOutput(0.125*COIN, pubKeyBob)
+	tx1.SignTx(privateKeyBob, 0)
+	TxProcess(tx1)
+	assert.NotNil(t, BlockCreate(pubKeyBob))
+
+	spend := NewTransaction()
+	spend.AddInput(tx1.GetHash(), 0)
+	spend.AddInput(tx1.GetHash(), 1)
+	spend.AddInput(tx1.GetHash(), 2)
+	spend.AddOutput(3.125*COIN, addresses[2])
+	spend.Finalize()
+
+	psbt, err := NewPSBT(spend, localBlockchain.UTXOSet)
+	assert.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "spend.psbt")
+	assert.NoError(t, WritePSBTFile(path, psbt))
+
+	// Each cosigner signs their own copy of the file.
+	copies := make([]*PartiallySignedTransaction, 2)
+	for i := range copies {
+		copies[i], err = ReadPSBTFile(path)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 3, copies[0].Sign(privateKeyBob), "Bob can sign the multisig, address and plain inputs")
+	assert.Equal(t, 1, copies[1].Sign(privateKeys[1]), "The second cosigner only takes part in the multisig input")
+	assert.NoError(t, WritePSBTFile(path, copies[1]))
+
+	_, err = copies[1].Finalize()
+	assert.ErrorIs(t, err, ErrPSBTIncomplete)
+
+	signedByCosigner, err := ReadPSBTFile(path)
+	assert.NoError(t, err)
+	assert.NoError(t, copies[0].Merge(signedByCosigner))
+	final, err := copies[0].Finalize()
+	assert.NoError(t, err)
+	assert.Equal(t, spend.GetHash(), final.GetHash(), "Finalizing should keep the transaction ID")
+	assert.Equal(t, 2, len(final.Inputs[0].MultiSigSignature))
+	assert.True(t, TxIsValid(*final, localBlockchain.UTXOSet))
+
+	TxProcess(final)
+	block := BlockCreate(pubKeyBob)
+	assert.NotNil(t, block)
+	assert.Equal(t, 2, len(block.GetTransactions()))
+}
+
+func TestPSBT_RejectsForeignAndInvalidSignatures(t *testing.T) {
+	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyBob := &privateKeyBob.PublicKey
+
+	privateKeyAlice, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyAlice := &privateKeyAlice.PublicKey
+
+	genesisBlock := NewBlock(nil, pubKeyBob)
+	genesisBlock.Finalizee()
+	localBlockchain := NewBlockchain(genesisBlock)
+	HandleBlocks(localBlockchain)
+
+	spend := NewTransaction()
+	spend.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
+	spend.AddOutput(3*COIN, pubKeyAlice)
+	psbt, err := NewPSBT(spend, localBlockchain.UTXOSet)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 0, psbt.Sign(privateKeyAlice), "Alice's key cannot spend Bob's output")
+	assert.Equal(t, 1, psbt.Sign(privateKeyBob))
+
+	// A signature that does not verify is not used.
+	for keyId := range psbt.Inputs[0].Signatures {
+		psbt.Inputs[0].Signatures[keyId][0] ^= 0xff
+	}
+	_, err = psbt.Finalize()
+	assert.ErrorIs(t, err, ErrPSBTIncomplete)
+
+	other := NewTransaction()
+	other.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
+	other.AddOutput(2*COIN, pubKeyAlice)
+	otherPSBT, err := NewPSBT(other, localBlockchain.UTXOSet)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, psbt.Merge(otherPSBT), ErrPSBTMismatch)
+
+	missing := NewTransaction()
+	missing.AddInput(other.GetHash(), 0)
+	_, err = NewPSBT(missing, localBlockchain.UTXOSet)
+	assert.ErrorIs(t, err, ErrPSBTMissingOutput)
+}
@@ -0,0 +1,90 @@
+package third_faza
+
+// ReorgEvent describes a switch of the main chain from one branch to another.
+// Disconnected lists the abandoned blocks from the old tip down to the fork, Connected lists
+// the blocks of the new branch from the fork up to the new tip.
+type ReorgEvent struct {
+	OldTip       *BlockNode
+	NewTip       *BlockNode
+	Fork         *BlockNode
+	Depth        uint
+	Disconnected []*Block
+	Connected    []*Block
+	Resurrected  []*Transaction
+	Evicted      []*Transaction
+}
+
+// OnReorg registers a function that is called after every chain reorganization.
+func (blockChain *Blockchain) OnReorg(listener func(event ReorgEvent)) {
+	blockChain.reorgListeners = append(blockChain.reorgListeners, listener)
+}
+
+// reorganize updates the transaction pool after the tip moved from oldTip to newTip on another
+// branch. The UTXO set has to reflect newTip already. Transactions of the abandoned blocks go back
+// to the pool if they are still valid, transactions confirmed on the new branch leave it and pool
+// transactions that conflict with the new branch are evicted. Like TransactionAdd, only
+// transactions spending outputs of the new chain are accepted back.
+func (blockChain *Blockchain) reorganize(oldTip *BlockNode, newTip *BlockNode) ReorgEvent {
+	fork := FindFork(oldTip, newTip)
+	event := ReorgEvent{
+		OldTip:       oldTip,
+		NewTip:       newTip,
+		Fork:         fork,
+		Disconnected: make([]*Block, 0),
+		Connected:    make([]*Block, 0),
+		Resurrected:  make([]*Transaction, 0),
+	}
+
+	for node := oldTip; node != fork; node = node.Parent {
+		event.Disconnected = append(event.Disconnected, node.B)
+	}
+	event.Depth = uint(len(event.Disconnected))
+	for node := newTip; node != fork; node = node.Parent {
+		event.Connected = append([]*Block{node.B}, event.Connected...)
+	}
+
+	confirmed := make(map[string]bool)
+	for _, block := range event.Connected {
+		for _, tx := range block.GetTransactions() {
+			confirmed[keyFor(tx.GetHash())] = true
+			blockChain.GlobalTransactionPool.RemoveTransaction(tx.GetHash())
+		}
+	}
+
+	trimmed := make([]*Transaction, 0)
+	for i := len(event.Disconnected) - 1; i >= 0; i-- {
+		for _, tx := range event.Disconnected[i].GetTransactions() {
+			if tx.IsCoinbase() || confirmed[keyFor(tx.GetHash())] {
+				continue
+			}
+			if replaced, evicted, ok := blockChain.acceptToPool(tx); ok {
+				trimmed = append(trimmed, replaced...)
+				trimmed = append(trimmed, evicted...)
+				event.Resurrected = append(event.Resurrected, tx)
+			}
+		}
+	}
+
+	event.Evicted = append(trimmed, blockChain.evictConflicts()...)
+	return event
+}
+
+// evictConflicts removes pool transactions that are no longer valid against the UTXO set
+// and the pooled transactions they depend on, together with their descendants, and returns them.
+// Parents come first, so a transaction is checked against the parents that stayed.
+func (blockChain *Blockchain) evictConflicts() []*Transaction {
+	txPool := blockChain.GlobalTransactionPool
+	evicted := make([]*Transaction, 0)
+	if len(txPool.H) == 0 {
+		return evicted
+	}
+	for _, tx := range txPool.GetTransactions() {
+		if txPool.GetTransaction(tx.GetHash()) == nil {
+			continue
+		}
+		if !TxIsValid(*tx, blockChain.mempoolView(tx, nil)) {
+			evicted = append(evicted, txPool.RemoveTransactionWithDescendants(tx.GetHash())...)
+		}
+	}
+	return evicted
+}
@@ -0,0 +1,122 @@
+package third_faza
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReorg_ResurrectsAndEvictsTransactions(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
+	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyBob := &privateKeyBob.PublicKey
+
+	privateKeyAlice, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyAlice := &privateKeyAlice.PublicKey
+
+	privateKeyCarol, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyCarol := &privateKeyCarol.PublicKey
+
+	privateKeyDave, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyDave := &privateKeyDave.PublicKey
+
+	genesisBlock := NewBlock(nil, pubKeyBob)
+	genesisBlock.Finalizee()
+	localBlockchain := NewBlockchain(genesisBlock)
+	HandleBlocks(localBlockchain)
+
+	events := make([]ReorgEvent, 0)
+	localBlockchain.OnReorg(func(event ReorgEvent) {
+		events = append(events, event)
+	})
+
+	block1 := NewBlock(genesisBlock.GetHash(), pubKeyAlice)
+	block1.Finalizee()
+	assert.True(t, BlockProcess(block1))
+
+	// Branch A spends the genesis output and the output of block1.
+	txA_1 := NewTransaction()
+	txA_1.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
+	txA_1.AddOutput(3.125*COIN, pubKeyAlice)
+	txA_1.SignTx(privateKeyBob, 0)
+
+	txA_2 := NewTransaction()
+	txA_2.AddInput(block1.GetCoinbase().GetHash(), 0)
+	txA_2.AddOutput(3.125*COIN, pubKeyBob)
+	txA_2.SignTx(privateKeyAlice, 0)
+
+	blockA := NewBlock(block1.GetHash(), pubKeyCarol)
+	blockA.TransactionAdd(txA_1)
+	blockA.TransactionAdd(txA_2)
+	blockA.Finalizee()
+	assert.True(t, BlockProcess(blockA))
+
+	// The pool transaction spends an output that only exists on branch A.
+	txPool := NewTransaction()
+	txPool.AddInput(blockA.GetCoinbase().GetHash(), 0)
+	txPool.AddOutput(3.125*COIN, pubKeyAlice)
+	txPool.SignTx(privateKeyCarol, 0)
+	TxProcess(txPool)
+	assert.NotNil(t, localBlockchain.GetTransactionPool().GetTransaction(txPool.GetHash()))
+
+	// Branch B spends the genesis output differently and becomes longer.
+	txB_1 := NewTransaction()
+	txB_1.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
+	txB_1.AddOutput(3.125*COIN, pubKeyDave)
+	txB_1.SignTx(privateKeyBob, 0)
+
+	// Ties go to the lower hash, so blockB is mined until it loses the tie against blockA.
+	blockB := NewBlock(block1.GetHash(), pubKeyDave)
+	blockB.TransactionAdd(txB_1)
+	blockB.Finalizee()
+	for bytes.Compare(blockB.GetHash(), blockA.GetHash()) < 0 {
+		blockB.SetTimestamp(blockB.GetTimestamp() + 1)
+		blockB.Finalizee()
+	}
+	assert.True(t, BlockProcess(blockB))
+	assert.Equal(t, 0, len(events), "Equal height branch should not take over")
+
+	blockB2 := NewBlock(blockB.GetHash(), pubKeyDave)
+	blockB2.Finalizee()
+	assert.True(t, BlockProcess(blockB2))
+
+	assert.Equal(t, 1, len(events), "Switching to branch B should emit one reorg event")
+	event := events[0]
+	assert.Equal(t, blockA.GetHash(), event.OldTip.B.GetHash())
+	assert.Equal(t, blockB2.GetHash(), event.NewTip.B.GetHash())
+	assert.Equal(t, block1.GetHash(), event.Fork.B.GetHash())
+	assert.Equal(t, uint(1), event.Depth)
+	assert.Equal(t, 1, len(event.Disconnected))
+	assert.Equal(t, 2, len(event.Connected))
+	assert.Equal(t, blockB.GetHash(), event.Connected[0].GetHash(), "Connected blocks should start at the fork")
+
+	pool := localBlockchain.GetTransactionPool()
+	assert.NotNil(t, pool.GetTransaction(txA_2.GetHash()), "Still valid transaction should return to the pool")
+	assert.Nil(t, pool.GetTransaction(txA_1.GetHash()), "Transaction conflicting with branch B should not return")
+	assert.Nil(t, pool.GetTransaction(txPool.GetHash()), "Transaction spending a branch A output should be evicted")
+	assert.Equal(t, 1, len(event.Resurrected))
+	assert.Equal(t, 1, len(event.Evicted))
+	assert.Equal(t, txPool.GetHash(), event.Evicted[0].GetHash())
+
+	blockC := BlockCreate(pubKeyAlice)
+	assert.NotNil(t, blockC, "Resurrected transaction should be minable on the new tip")
+	assert.Equal(t, 2, len(blockC.GetTransactions()))
+	assert.Equal(t, 0, len(pool.GetTransactions()), "Mined transactions should leave the pool")
+}
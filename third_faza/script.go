@@ -0,0 +1,314 @@
+package third_faza
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Script is a program of the small stack language that locks and unlocks outputs.
+// An output's locking script runs after the spending input's unlocking script on the
+// same stack; the spend is valid when it finishes with a single true value on the stack.
+//
+// Data pushes put byte strings on the stack. Numbers are unsigned big-endian byte
+// strings without leading zeros (zero is the empty string), and any non-zero value is true.
+type Script []byte
+
+// Opcodes of the script language.
+const (
+	OP_0         byte = 0x00
+	OP_FALSE     byte = OP_0
+	OP_PUSHDATA1 byte = 0x4c // next byte is the length of the data
+	OP_PUSHDATA2 byte = 0x4d // next two bytes (big-endian) are the length of the data
+	OP_1         byte = 0x51
+	OP_TRUE      byte = OP_1
+	OP_16        byte = 0x60
+
+	OP_IF     byte = 0x63
+	OP_NOTIF  byte = 0x64
+	OP_ELSE   byte = 0x67
+	OP_ENDIF  byte = 0x68
+	OP_VERIFY byte = 0x69
+	OP_RETURN byte = 0x6a
+
+	OP_DROP byte = 0x75
+	OP_DUP  byte = 0x76
+	OP_SWAP byte = 0x7c
+	OP_SIZE byte = 0x82
+
+	OP_EQUAL       byte = 0x87
+	OP_EQUALVERIFY byte = 0x88
+
+	OP_SHA256              byte = 0xa8
+	OP_CHECKSIG            byte = 0xac
+	OP_CHECKSIGVERIFY      byte = 0xad
+	OP_CHECKMULTISIG       byte = 0xae
+	OP_CHECKMULTISIGVERIFY byte = 0xaf
+
+	OP_CHECKLOCKTIMEVERIFY byte = 0xb1
+)
+
+const (
+	// MAX_SCRIPT_SIZE is the largest script accepted in an input or output.
+	MAX_SCRIPT_SIZE = 10000
+	// MAX_SCRIPT_ELEMENT_SIZE is the largest data push.
+	MAX_SCRIPT_ELEMENT_SIZE = 1024
+	// MAX_STACK_SIZE limits the number of stack elements during evaluation.
+	MAX_STACK_SIZE = 1000
+	// MAX_SCRIPT_NUM_SIZE is the largest number (in bytes) the interpreter does arithmetic with.
+	MAX_SCRIPT_NUM_SIZE = 8
+)
+
+var opcodeNames = map[byte]string{
+	OP_0:                   "OP_0",
+	OP_PUSHDATA1:           "OP_PUSHDATA1",
+	OP_PUSHDATA2:           "OP_PUSHDATA2",
+	OP_IF:                  "OP_IF",
+	OP_NOTIF:               "OP_NOTIF",
+	OP_ELSE:                "OP_ELSE",
+	OP_ENDIF:               "OP_ENDIF",
+	OP_VERIFY:              "OP_VERIFY",
+	OP_RETURN:              "OP_RETURN",
+	OP_DROP:                "OP_DROP",
+	OP_DUP:                 "OP_DUP",
+	OP_SWAP:                "OP_SWAP",
+	OP_SIZE:                "OP_SIZE",
+	OP_EQUAL:               "OP_EQUAL",
+	OP_EQUALVERIFY:         "OP_EQUALVERIFY",
+	OP_SHA256:              "OP_SHA256",
+	OP_CHECKSIG:            "OP_CHECKSIG",
+	OP_CHECKSIGVERIFY:      "OP_CHECKSIGVERIFY",
+	OP_CHECKMULTISIG:       "OP_CHECKMULTISIG",
+	OP_CHECKMULTISIGVERIFY: "OP_CHECKMULTISIGVERIFY",
+	OP_CHECKLOCKTIMEVERIFY: "OP_CHECKLOCKTIMEVERIFY",
+}
+
+// ScriptOp is a single parsed instruction: an opcode and, for pushes, its data.
+type ScriptOp struct {
+	Opcode byte
+	Data   []byte
+}
+
+// IsPush reports whether the instruction only puts a value on the stack.
+func (op ScriptOp) IsPush() bool {
+	return op.Opcode <= OP_PUSHDATA2 || (op.Opcode >= OP_1 && op.Opcode <= OP_16)
+}
+
+// ParseScript splits a script into instructions. It fails on truncated pushes and
+// pushes larger than MAX_SCRIPT_ELEMENT_SIZE; unknown opcodes are only rejected when executed.
+func ParseScript(script Script) ([]ScriptOp, error) {
+	if len(script) > MAX_SCRIPT_SIZE {
+		return nil, ErrScriptTooLarge
+	}
+	ops := make([]ScriptOp, 0)
+	for i := 0; i < len(script); {
+		opcode := script[i]
+		i++
+
+		size := 0
+		switch {
+		case opcode > OP_0 && opcode < OP_PUSHDATA1:
+			size = int(opcode)
+		case opcode == OP_PUSHDATA1:
+			if i+1 > len(script) {
+				return nil, ErrScriptMalformed
+			}
+			size = int(script[i])
+			i++
+		case opcode == OP_PUSHDATA2:
+			if i+2 > len(script) {
+				return nil, ErrScriptMalformed
+			}
+			size = int(binary.BigEndian.Uint16(script[i:]))
+			i += 2
+		}
+		if size > MAX_SCRIPT_ELEMENT_SIZE {
+			return nil, ErrScriptElementSize
+		}
+		if i+size > len(script) {
+			return nil, ErrScriptMalformed
+		}
+
+		op := ScriptOp{Opcode: opcode}
+		if size > 0 {
+			op.Data = script[i : i+size]
+		}
+		ops = append(ops, op)
+		i += size
+	}
+	return ops, nil
+}
+
+// IsPushOnly reports whether the script is well-formed and contains data pushes only.
+func (script Script) IsPushOnly() bool {
+	ops, err := ParseScript(script)
+	if err != nil {
+		return false
+	}
+	for _, op := range ops {
+		if !op.IsPush() {
+			return false
+		}
+	}
+	return true
+}
+
+// String disassembles the script, e.g. "<3046...> OP_CHECKSIG".
+func (script Script) String() string {
+	ops, err := ParseScript(script)
+	if err != nil {
+		return "[invalid script]"
+	}
+	parts := make([]string, 0, len(ops))
+	for _, op := range ops {
+		switch {
+		case op.Data != nil:
+			parts = append(parts, "<"+hex.EncodeToString(op.Data)+">")
+		case op.Opcode >= OP_1 && op.Opcode <= OP_16:
+			parts = append(parts, fmt.Sprintf("OP_%d", op.Opcode-OP_1+1))
+		case opcodeNames[op.Opcode] != "":
+			parts = append(parts, opcodeNames[op.Opcode])
+		default:
+			parts = append(parts, fmt.Sprintf("OP_UNKNOWN_%02x", op.Opcode))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// ScriptBuilder assembles a script, choosing the shortest encoding for each push.
+type ScriptBuilder struct {
+	script Script
+}
+
+// NewScriptBuilder returns an empty builder.
+func NewScriptBuilder() *ScriptBuilder {
+	return &ScriptBuilder{script: make(Script, 0)}
+}
+
+// AddOp appends an opcode.
+func (b *ScriptBuilder) AddOp(opcode byte) *ScriptBuilder {
+	b.script = append(b.script, opcode)
+	return b
+}
+
+// AddData appends a push of data.
+func (b *ScriptBuilder) AddData(data []byte) *ScriptBuilder {
+	switch {
+	case len(data) == 0:
+		b.script = append(b.script, OP_0)
+	case len(data) < int(OP_PUSHDATA1):
+		b.script = append(b.script, byte(len(data)))
+	case len(data) <= 0xff:
+		b.script = append(b.script, OP_PUSHDATA1, byte(len(data)))
+	default:
+		b.script = append(b.script, OP_PUSHDATA2)
+		b.script = binary.BigEndian.AppendUint16(b.script, uint16(len(data)))
+	}
+	b.script = append(b.script, data...)
+	return b
+}
+
+// AddInt appends a push of a number, using OP_0 and OP_1 to OP_16 for small values.
+func (b *ScriptBuilder) AddInt(n uint64) *ScriptBuilder {
+	if n >= 1 && n <= 16 {
+		return b.AddOp(OP_1 + byte(n-1))
+	}
+	return b.AddData(encodeScriptNum(n))
+}
+
+// Script returns the assembled script.
+func (b *ScriptBuilder) Script() Script {
+	return b.script
+}
+
+func encodeScriptNum(n uint64) []byte {
+	data := binary.BigEndian.AppendUint64(nil, n)
+	for len(data) > 0 && data[0] == 0 {
+		data = data[1:]
+	}
+	return data
+}
+
+// EncodePublicKey returns the DER (PKIX) encoding used for public keys inside scripts.
+// It identifies the key type itself, so keys of every standard scheme can be mixed in scripts.
+func EncodePublicKey(pubKey PublicKey) []byte {
+	if SchemeOf(pubKey) == nil {
+		return nil
+	}
+	der, err := x509.MarshalPKIXPublicKey(pubKey)
+	if err != nil {
+		return nil
+	}
+	return der
+}
+
+// DecodePublicKey parses a public key produced by EncodePublicKey.
+func DecodePublicKey(der []byte) (PublicKey, error) {
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, err
+	}
+	pubKey, ok := key.(PublicKey)
+	if !ok || SchemeOf(pubKey) == nil {
+		return nil, ErrUnknownKeyType
+	}
+	return pubKey, nil
+}
+
+// PubKeyHash returns the SHA-256 hash of the encoded public key.
+func PubKeyHash(pubKey PublicKey) []byte {
+	hash := sha256.Sum256(EncodePublicKey(pubKey))
+	return hash[:]
+}
+
+// PayToPubKeyScript locks an output to a public key: <pubKey> OP_CHECKSIG.
+// It is the locking script of outputs with an Address.
+func PayToPubKeyScript(pubKey PublicKey) Script {
+	return NewScriptBuilder().AddData(EncodePublicKey(pubKey)).AddOp(OP_CHECKSIG).Script()
+}
+
+// PayToPubKeyHashScript locks an output to the hash of a public key; the spender
+// reveals the key: OP_DUP OP_SHA256 <hash> OP_EQUALVERIFY OP_CHECKSIG.
+func PayToPubKeyHashScript(pubKeyHash []byte) Script {
+	return NewScriptBuilder().
+		AddOp(OP_DUP).AddOp(OP_SHA256).AddData(pubKeyHash).AddOp(OP_EQUALVERIFY).
+		AddOp(OP_CHECKSIG).Script()
+}
+
+// MultiSigScript locks an output to required of the given keys:
+// <required> <pubKey>... <n> OP_CHECKMULTISIG. It is the locking script of multisig outputs.
+func MultiSigScript(required int, pubKeys []PublicKey) Script {
+	b := NewScriptBuilder().AddInt(uint64(required))
+	for _, pubKey := range pubKeys {
+		b.AddData(EncodePublicKey(pubKey))
+	}
+	return b.AddInt(uint64(len(pubKeys))).AddOp(OP_CHECKMULTISIG).Script()
+}
+
+// HashLockScript locks an output to whoever knows the SHA-256 preimage of hash and
+// signs with pubKey: OP_SHA256 <hash> OP_EQUALVERIFY <pubKey> OP_CHECKSIG.
+func HashLockScript(hash []byte, pubKey PublicKey) Script {
+	return NewScriptBuilder().
+		AddOp(OP_SHA256).AddData(hash).AddOp(OP_EQUALVERIFY).
+		AddData(EncodePublicKey(pubKey)).AddOp(OP_CHECKSIG).Script()
+}
+
+// TimeLockScript locks an output to pubKey until the block at height:
+// <height> OP_CHECKLOCKTIMEVERIFY OP_DROP <pubKey> OP_CHECKSIG.
+func TimeLockScript(height uint, pubKey PublicKey) Script {
+	return NewScriptBuilder().
+		AddInt(uint64(height)).AddOp(OP_CHECKLOCKTIMEVERIFY).AddOp(OP_DROP).
+		AddData(EncodePublicKey(pubKey)).AddOp(OP_CHECKSIG).Script()
+}
+
+// SignatureScript returns the unlocking script pushing the given signatures in order.
+func SignatureScript(sigs ...[]byte) Script {
+	b := NewScriptBuilder()
+	for _, sig := range sigs {
+		b.AddData(sig)
+	}
+	return b.Script()
+}
@@ -0,0 +1,157 @@
+package third_faza
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScript_BuildParseAndDisassemble(t *testing.T) {
+	hash := sha256.Sum256([]byte("secret"))
+	script := NewScriptBuilder().
+		AddInt(300).AddOp(OP_CHECKLOCKTIMEVERIFY).AddOp(OP_DROP).
+		AddOp(OP_SHA256).AddData(hash[:]).AddOp(OP_EQUAL).AddInt(2).
+		Script()
+	assert.Equal(t, "<012c> OP_CHECKLOCKTIMEVERIFY OP_DROP OP_SHA256 <"+
+		"2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b> OP_EQUAL OP_2", script.String())
+
+	ops, err := ParseScript(script)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, len(ops))
+	assert.Equal(t, hash[:], ops[4].Data)
+
+	long := NewScriptBuilder().AddData(make([]byte, 300)).Script()
+	assert.Equal(t, OP_PUSHDATA2, long[0])
+	assert.True(t, long.IsPushOnly())
+	assert.False(t, script.IsPushOnly())
+
+	_, err = ParseScript(Script{OP_PUSHDATA1, 10, 1, 2})
+	assert.ErrorIs(t, err, ErrScriptMalformed)
+	_, err = ParseScript(NewScriptBuilder().AddData(make([]byte, MAX_SCRIPT_ELEMENT_SIZE+1)).Script())
+	assert.ErrorIs(t, err, ErrScriptElementSize)
+}
+
+func TestScript_Evaluation(t *testing.T) {
+	ctx := NewScriptContext(NewTransaction(), 0, 10)
+	preimage := []byte("secret")
+	hash := sha256.Sum256(preimage)
+	hashLock := NewScriptBuilder().AddOp(OP_SHA256).AddData(hash[:]).AddOp(OP_EQUAL).Script()
+
+	assert.NoError(t, VerifyScript(SignatureScript(preimage), hashLock, ctx))
+	assert.ErrorIs(t, VerifyScript(SignatureScript([]byte("guess")), hashLock, ctx), ErrScriptEvalFalse)
+	assert.ErrorIs(t, VerifyScript(SignatureScript([]byte{1}, preimage), hashLock, ctx), ErrScriptCleanStack)
+	assert.ErrorIs(t, VerifyScript(Script{OP_DUP}, hashLock, ctx), ErrScriptNotPushOnly)
+	assert.ErrorIs(t, VerifyScript(nil, nil, ctx), ErrScriptEmpty)
+
+	// OP_IF picks a branch: 1 selects the hash lock, 0 the height lock at 10.
+	branches := NewScriptBuilder().
+		AddOp(OP_IF).
+		AddOp(OP_SHA256).AddData(hash[:]).AddOp(OP_EQUAL).
+		AddOp(OP_ELSE).
+		AddInt(10).AddOp(OP_CHECKLOCKTIMEVERIFY).
+		AddOp(OP_ENDIF).
+		Script()
+	assert.NoError(t, VerifyScript(NewScriptBuilder().AddData(preimage).AddInt(1).Script(), branches, ctx))
+	assert.NoError(t, VerifyScript(SignatureScript(nil), branches, ctx))
+	assert.ErrorIs(t, VerifyScript(SignatureScript(nil), branches, NewScriptContext(NewTransaction(), 0, 9)), ErrScriptLockTime)
+
+	assert.ErrorIs(t, VerifyScript(nil, Script{OP_1, OP_IF}, ctx), ErrScriptUnbalancedIf)
+	assert.ErrorIs(t, VerifyScript(nil, Script{OP_1, OP_RETURN}, ctx), ErrScriptReturn)
+	assert.ErrorIs(t, VerifyScript(nil, Script{OP_DROP}, ctx), ErrStackUnderflow)
+	assert.ErrorIs(t, VerifyScript(nil, Script{0xff}, ctx), ErrScriptBadOpcode)
+}
+
+func TestScript_ScriptOutputsOnChain(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
+	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyBob := &privateKeyBob.PublicKey
+
+	privateKeyAlice, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyAlice := &privateKeyAlice.PublicKey
+
+	genesisBlock := NewBlock(nil, pubKeyBob)
+	genesisBlock.Finalizee()
+	localBlockchain := NewBlockchain(genesisBlock)
+	HandleBlocks(localBlockchain)
+
+	preimage := []byte("swap secret")
+	hash := sha256.Sum256(preimage)
+
+	tx1 := NewTransaction()
+	tx1.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
+	tx1.AddScriptOutput(1*COIN, PayToPubKeyHashScript(PubKeyHash(pubKeyAlice)))
+	tx1.AddScriptOutput(1*COIN, HashLockScript(hash[:], pubKeyAlice))
+	tx1.AddScriptOutput(1*COIN, TimeLockScript(5, pubKeyAlice))
+	tx1.SignTx(privateKeyBob, 0)
+
+	block1 := NewBlock(genesisBlock.GetHash(), pubKeyBob)
+	block1.TransactionAdd(tx1)
+	block1.Finalizee()
+	assert.True(t, BlockProcess(block1), "Block with script outputs should be accepted")
+	pool := localBlockchain.UTXOSet
+
+	spend := func(index int) *Transaction {
+		tx := NewTransaction()
+		tx.AddInput(tx1.GetHash(), index)
+		tx.AddOutput(1*COIN, pubKeyBob)
+		return tx
+	}
+
+	// Pay to public key hash: the spender reveals the key.
+	p2pkh := spend(0)
+	sig := p2pkh.CreateSignature(privateKeyAlice, 0)
+	p2pkh.SetUnlockScript(0, SignatureScript(sig, EncodePublicKey(pubKeyBob)))
+	assert.False(t, TxIsValid(*p2pkh, pool), "Key not matching the hash should be rejected")
+	p2pkh.SetUnlockScript(0, SignatureScript(sig, EncodePublicKey(pubKeyAlice)))
+	assert.True(t, TxIsValid(*p2pkh, pool))
+
+	// Hash lock: the spender reveals the preimage and signs.
+	hashLocked := spend(1)
+	sig = hashLocked.CreateSignature(privateKeyAlice, 0)
+	hashLocked.SetUnlockScript(0, SignatureScript(sig, []byte("wrong secret")))
+	assert.False(t, TxIsValid(*hashLocked, pool), "Wrong preimage should be rejected")
+	hashLocked.SetUnlockScript(0, SignatureScript(sig, preimage))
+	assert.True(t, TxIsValid(*hashLocked, pool))
+
+	// Time lock: the output can only be spent at height 5 or later.
+	timeLocked := spend(2)
+	timeLocked.SetUnlockScript(0, SignatureScript(timeLocked.CreateSignature(privateKeyAlice, 0)))
+	assert.False(t, TxIsValid(*timeLocked, pool), "Output should be locked until height 5")
+
+	block2 := NewBlock(block1.GetHash(), pubKeyBob)
+	block2.TransactionAdd(p2pkh)
+	block2.TransactionAdd(hashLocked)
+	block2.Finalizee()
+	assert.True(t, BlockProcess(block2))
+
+	early := NewBlock(block2.GetHash(), pubKeyBob)
+	early.TransactionAdd(timeLocked)
+	early.Finalizee()
+	assert.False(t, BlockProcess(early), "Block at height 4 may not spend an output locked until height 5")
+
+	block3 := NewBlock(block2.GetHash(), pubKeyBob)
+	block3.Finalizee()
+	assert.True(t, BlockProcess(block3))
+	block4 := NewBlock(block3.GetHash(), pubKeyBob)
+	block4.TransactionAdd(timeLocked)
+	block4.Finalizee()
+	assert.True(t, BlockProcess(block4), "Block at height 5 may spend the time-locked output")
+
+	data, err := tx1.MarshalBinary()
+	assert.NoError(t, err)
+	decoded := &Transaction{}
+	assert.NoError(t, decoded.UnmarshalBinary(data))
+	assert.Equal(t, tx1.GetHash(), decoded.GetHash())
+	assert.Equal(t, tx1.Outputs[1].LockScript, decoded.Outputs[1].LockScript)
+}
@@ -0,0 +1,373 @@
+package third_faza
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	// TX_WIRE_VERSION is the version byte that starts every encoded transaction. It changes
+	// only when the encoded layout does; decoding rejects any other version.
+	TX_WIRE_VERSION = 6
+	// BLOCK_WIRE_VERSION is the version byte that starts every encoded block. It changes
+	// only when the block layout does.
+	BLOCK_WIRE_VERSION = 2
+)
+
+// Output kinds used by the wire encoding.
+const (
+	outputKindNone     = 0
+	outputKindAddress  = 1
+	outputKindMultiSig = 2
+	outputKindScript   = 3
+)
+
+var (
+	ErrUnexpectedEnd      = errors.New("unexpected end of encoded data")
+	ErrTrailingData       = errors.New("trailing bytes after encoded data")
+	ErrUnsupportedVersion = errors.New("unsupported encoding version")
+	ErrInvalidEncoding    = errors.New("invalid encoding")
+)
+
+// The wire encoding is big-endian. Variable-length fields (hashes, signatures, public keys)
+// are prefixed with their length as uint32 and lists are prefixed with their element count.
+// Transaction and block hashes are not encoded; they are recomputed while decoding.
+
+// wireReader consumes an encoded buffer and remembers the first error it runs into,
+// so decoders can read a whole structure and check the error once at the end.
+type wireReader struct {
+	data []byte
+	err  error
+}
+
+func (r *wireReader) fail(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+func (r *wireReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(r.data) {
+		r.fail(ErrUnexpectedEnd)
+		return nil
+	}
+	chunk := r.data[:n]
+	r.data = r.data[n:]
+	return chunk
+}
+
+func (r *wireReader) readByte() byte {
+	chunk := r.next(1)
+	if chunk == nil {
+		return 0
+	}
+	return chunk[0]
+}
+
+func (r *wireReader) readUint32() uint32 {
+	chunk := r.next(4)
+	if chunk == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(chunk)
+}
+
+func (r *wireReader) readUint64() uint64 {
+	chunk := r.next(8)
+	if chunk == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint64(chunk)
+}
+
+// readBytes reads a length-prefixed byte string. An empty string is returned as nil.
+func (r *wireReader) readBytes() []byte {
+	length := r.readUint32()
+	if r.err != nil || length == 0 {
+		return nil
+	}
+	if uint64(length) > uint64(len(r.data)) {
+		r.fail(ErrUnexpectedEnd)
+		return nil
+	}
+	return append([]byte{}, r.next(int(length))...)
+}
+
+// readCount reads a list length and rejects counts that cannot fit in the remaining data,
+// given that every element takes at least minSize bytes.
+func (r *wireReader) readCount(minSize int) int {
+	count := r.readUint32()
+	if r.err != nil {
+		return 0
+	}
+	if uint64(count)*uint64(minSize) > uint64(len(r.data)) {
+		r.fail(ErrUnexpectedEnd)
+		return 0
+	}
+	return int(count)
+}
+
+func (r *wireReader) finish() error {
+	if r.err == nil && len(r.data) > 0 {
+		r.fail(ErrTrailingData)
+	}
+	return r.err
+}
+
+func appendBytes(data []byte, value []byte) []byte {
+	data = binary.BigEndian.AppendUint32(data, uint32(len(value)))
+	return append(data, value...)
+}
+
+func appendPublicKey(data []byte, pubKey PublicKey) []byte {
+	return appendBytes(data, marshalTaggedPublicKey(pubKey))
+}
+
+func readPublicKey(r *wireReader) PublicKey {
+	keyData := r.readBytes()
+	if r.err != nil {
+		return nil
+	}
+	pubKey, err := unmarshalTaggedPublicKey(keyData)
+	if err != nil {
+		r.fail(ErrInvalidEncoding)
+		return nil
+	}
+	return pubKey
+}
+
+func (in *Input) appendBinary(data []byte) []byte {
+	data = appendBytes(data, in.PrevTxHash)
+	data = binary.BigEndian.AppendUint32(data, uint32(in.OutputIndex))
+	data = binary.BigEndian.AppendUint32(data, in.Sequence)
+	data = appendBytes(data, in.Signature)
+	data = binary.BigEndian.AppendUint32(data, uint32(len(in.MultiSigSignature)))
+	for _, sig := range in.MultiSigSignature {
+		data = appendBytes(data, sig)
+	}
+	return appendBytes(data, in.UnlockScript)
+}
+
+func (in *Input) readBinary(r *wireReader) {
+	in.PrevTxHash = r.readBytes()
+	in.OutputIndex = int(int32(r.readUint32()))
+	in.Sequence = r.readUint32()
+	in.Signature = r.readBytes()
+	in.MultiSigSignature = nil
+	count := r.readCount(4)
+	for i := 0; i < count && r.err == nil; i++ {
+		in.MultiSigSignature = append(in.MultiSigSignature, r.readBytes())
+	}
+	in.UnlockScript = r.readBytes()
+}
+
+// MarshalBinary encodes the input: previous transaction hash, output index, sequence, signature, multisig signatures
+// and unlocking script.
+func (in *Input) MarshalBinary() ([]byte, error) {
+	return in.appendBinary(nil), nil
+}
+
+// UnmarshalBinary decodes an input produced by MarshalBinary.
+func (in *Input) UnmarshalBinary(data []byte) error {
+	r := &wireReader{data: data}
+	in.readBinary(r)
+	return r.finish()
+}
+
+func (out *Output) appendBinary(data []byte) []byte {
+	data = binary.BigEndian.AppendUint64(data, uint64(out.Value))
+	if len(out.LockScript) > 0 {
+		data = append(data, outputKindScript)
+		data = appendBytes(data, out.LockScript)
+	} else if len(out.MultiSigAddresses) > 0 {
+		data = append(data, outputKindMultiSig)
+		data = binary.BigEndian.AppendUint32(data, uint32(len(out.MultiSigAddresses)))
+		data = binary.BigEndian.AppendUint32(data, uint32(out.RequiredSigs))
+		for _, pubKey := range out.MultiSigAddresses {
+			data = appendPublicKey(data, pubKey)
+		}
+	} else if out.Address != nil {
+		data = append(data, outputKindAddress)
+		data = appendPublicKey(data, out.Address)
+	} else {
+		data = append(data, outputKindNone)
+	}
+	return data
+}
+
+func (out *Output) readBinary(r *wireReader) {
+	out.Value = Amount(r.readUint64())
+	out.Address = nil
+	out.MultiSigAddresses = nil
+	out.RequiredSigs = 0
+	out.LockScript = nil
+
+	switch r.readByte() {
+	case outputKindNone:
+	case outputKindScript:
+		out.LockScript = r.readBytes()
+		if len(out.LockScript) == 0 {
+			r.fail(ErrInvalidEncoding)
+		}
+	case outputKindAddress:
+		out.Address = readPublicKey(r)
+	case outputKindMultiSig:
+		count := r.readCount(8)
+		if count == 0 {
+			r.fail(ErrInvalidEncoding)
+		}
+		out.RequiredSigs = int(r.readUint32())
+		for i := 0; i < count && r.err == nil; i++ {
+			out.MultiSigAddresses = append(out.MultiSigAddresses, readPublicKey(r))
+		}
+	default:
+		r.fail(ErrInvalidEncoding)
+	}
+}
+
+// MarshalBinary encodes the output: value, then either a single address or the multisig address list.
+func (out *Output) MarshalBinary() ([]byte, error) {
+	return out.appendBinary(nil), nil
+}
+
+// UnmarshalBinary decodes an output produced by MarshalBinary.
+func (out *Output) UnmarshalBinary(data []byte) error {
+	r := &wireReader{data: data}
+	out.readBinary(r)
+	return r.finish()
+}
+
+func (tx *Transaction) appendBinary(data []byte) []byte {
+	data = append(data, TX_WIRE_VERSION)
+	data = binary.BigEndian.AppendUint64(data, uint64(tx.Timestamp))
+	data = binary.BigEndian.AppendUint32(data, tx.LockTime)
+	if tx.Coinbase {
+		data = append(data, 1)
+	} else {
+		data = append(data, 0)
+	}
+
+	data = binary.BigEndian.AppendUint32(data, uint32(len(tx.Inputs)))
+	for _, in := range tx.Inputs {
+		data = in.appendBinary(data)
+	}
+	data = binary.BigEndian.AppendUint32(data, uint32(len(tx.Outputs)))
+	for _, out := range tx.Outputs {
+		data = out.appendBinary(data)
+	}
+	return data
+}
+
+func (tx *Transaction) readBinary(r *wireReader) {
+	if version := r.readByte(); r.err == nil && version != TX_WIRE_VERSION {
+		r.fail(ErrUnsupportedVersion)
+	}
+	tx.Timestamp = int64(r.readUint64())
+	tx.LockTime = r.readUint32()
+	switch r.readByte() {
+	case 0:
+		tx.Coinbase = false
+	case 1:
+		tx.Coinbase = true
+	default:
+		r.fail(ErrInvalidEncoding)
+	}
+
+	count := r.readCount(16)
+	tx.Inputs = make([]*Input, 0, count)
+	for i := 0; i < count && r.err == nil; i++ {
+		in := &Input{}
+		in.readBinary(r)
+		tx.Inputs = append(tx.Inputs, in)
+	}
+
+	count = r.readCount(9)
+	tx.Outputs = make([]*Output, 0, count)
+	for i := 0; i < count && r.err == nil; i++ {
+		out := &Output{}
+		out.readBinary(r)
+		tx.Outputs = append(tx.Outputs, out)
+	}
+
+	if r.err == nil {
+		tx.Finalize()
+	}
+}
+
+// MarshalBinary encodes the transaction with the current wire version.
+func (tx *Transaction) MarshalBinary() ([]byte, error) {
+	return tx.appendBinary(nil), nil
+}
+
+// Size returns the length of the transaction's wire encoding in bytes, witness included.
+func (tx *Transaction) Size() int {
+	return len(tx.appendBinary(nil))
+}
+
+// UnmarshalBinary decodes a transaction produced by MarshalBinary and recomputes its hash.
+func (tx *Transaction) UnmarshalBinary(data []byte) error {
+	r := &wireReader{data: data}
+	tx.readBinary(r)
+	return r.finish()
+}
+
+// MarshalBinary encodes the block header followed by its transactions, each prefixed with its length.
+func (block *Block) MarshalBinary() ([]byte, error) {
+	data := []byte{BLOCK_WIRE_VERSION}
+	data = binary.BigEndian.AppendUint32(data, block.header.Version)
+	data = appendBytes(data, block.header.PrevBlockHash)
+	data = appendBytes(data, block.header.MerkleRoot)
+	data = appendBytes(data, block.header.WitnessRoot)
+	data = binary.BigEndian.AppendUint64(data, uint64(block.header.Timestamp))
+	data = binary.BigEndian.AppendUint32(data, block.header.Bits)
+	data = binary.BigEndian.AppendUint64(data, block.header.Nonce)
+
+	data = binary.BigEndian.AppendUint32(data, uint32(len(block.txs)))
+	for _, tx := range block.txs {
+		data = appendBytes(data, tx.appendBinary(nil))
+	}
+	return data, nil
+}
+
+// UnmarshalBinary decodes a block produced by MarshalBinary. The first transaction becomes
+// the coinbase when it is marked as one, and the block hash is recomputed from the header.
+func (block *Block) UnmarshalBinary(data []byte) error {
+	r := &wireReader{data: data}
+	if version := r.readByte(); r.err == nil && version != BLOCK_WIRE_VERSION {
+		r.fail(ErrUnsupportedVersion)
+	}
+
+	header := BlockHeader{}
+	header.Version = r.readUint32()
+	header.PrevBlockHash = r.readBytes()
+	header.MerkleRoot = r.readBytes()
+	header.WitnessRoot = r.readBytes()
+	header.Timestamp = int64(r.readUint64())
+	header.Bits = r.readUint32()
+	header.Nonce = r.readUint64()
+
+	count := r.readCount(4)
+	txs := make([]*Transaction, 0, count)
+	for i := 0; i < count && r.err == nil; i++ {
+		txReader := &wireReader{data: r.readBytes()}
+		tx := &Transaction{}
+		tx.readBinary(txReader)
+		r.fail(txReader.finish())
+		txs = append(txs, tx)
+	}
+	if err := r.finish(); err != nil {
+		return err
+	}
+
+	block.header = header
+	block.txs = txs
+	block.coinbase = nil
+	if len(txs) > 0 && txs[0].IsCoinbase() {
+		block.coinbase = txs[0]
+	}
+	block.hash = block.CalculateHash()
+	return nil
+}
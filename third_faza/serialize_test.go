@@ -0,0 +1,160 @@
+package third_faza
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// serializationFixture builds a block with a plain and a multisig transaction.
+func serializationFixture() (*Block, *Transaction) {
+	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyBob := &privateKeyBob.PublicKey
+
+	privateKeyAlice, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyAlice := &privateKeyAlice.PublicKey
+
+	genesisBlock := NewBlock(nil, pubKeyBob)
+	genesisBlock.Finalizee()
+
+	tx1 := NewTransaction()
+	tx1.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
+	tx1.AddOutput(1*COIN, pubKeyAlice)
+	tx1.AddMultisigOutput(NewMultiSigOutput(2.125*COIN, []PublicKey{pubKeyBob, pubKeyAlice}))
+	tx1.SignTx(privateKeyBob, 0)
+	tx1.Inputs[0].AddMultiSignature([]byte{1, 2, 3})
+	tx1.Finalize()
+
+	block1 := NewBlock(genesisBlock.GetHash(), pubKeyAlice)
+	block1.TransactionAdd(tx1)
+	block1.Finalizee()
+	return block1, tx1
+}
+
+func TestSerialize_TransactionRoundTrip(t *testing.T) {
+	_, tx := serializationFixture()
+
+	data, err := tx.MarshalBinary()
+	assert.NoError(t, err)
+
+	decoded := &Transaction{}
+	assert.NoError(t, decoded.UnmarshalBinary(data))
+	assert.Equal(t, tx.GetHash(), decoded.GetHash(), "Decoded transaction should have the same hash")
+	assert.Equal(t, tx.Timestamp, decoded.Timestamp)
+	assert.Equal(t, len(tx.Inputs), len(decoded.Inputs))
+	assert.True(t, tx.Inputs[0].Equals(decoded.Inputs[0]), "Decoded input should equal the original")
+	assert.True(t, tx.Outputs[0].Equals(decoded.Outputs[0]), "Decoded output should equal the original")
+	assert.Equal(t, 2, len(decoded.Outputs[1].MultiSigAddresses), "Multisig addresses should be decoded")
+	assert.True(t, tx.Outputs[1].MultiSigAddresses[1].Equal(decoded.Outputs[1].MultiSigAddresses[1]))
+
+	again, err := decoded.MarshalBinary()
+	assert.NoError(t, err)
+	assert.Equal(t, data, again, "Encoding should be canonical")
+}
+
+func TestSerialize_InputAndOutputRoundTrip(t *testing.T) {
+	_, tx := serializationFixture()
+
+	data, err := tx.Inputs[0].MarshalBinary()
+	assert.NoError(t, err)
+	in := &Input{}
+	assert.NoError(t, in.UnmarshalBinary(data))
+	assert.True(t, tx.Inputs[0].Equals(in))
+
+	data, err = tx.Outputs[1].MarshalBinary()
+	assert.NoError(t, err)
+	out := &Output{}
+	assert.NoError(t, out.UnmarshalBinary(data))
+	assert.Equal(t, tx.Outputs[1].Value, out.Value)
+	assert.Nil(t, out.Address)
+	assert.Equal(t, len(tx.Outputs[1].MultiSigAddresses), len(out.MultiSigAddresses))
+}
+
+func TestSerialize_BlockRoundTrip(t *testing.T) {
+	block, _ := serializationFixture()
+
+	data, err := block.MarshalBinary()
+	assert.NoError(t, err)
+
+	decoded := &Block{}
+	assert.NoError(t, decoded.UnmarshalBinary(data))
+	assert.Equal(t, block.GetHash(), decoded.GetHash(), "Decoded block should have the same hash")
+	assert.Equal(t, block.ComputeMerkleRoot(), decoded.ComputeMerkleRoot(), "Decoded transactions should have the same hashes")
+	assert.Equal(t, block.GetCoinbase().GetHash(), decoded.GetCoinbase().GetHash())
+	assert.Equal(t, len(block.GetTransactions()), len(decoded.GetTransactions()))
+
+	again, err := decoded.MarshalBinary()
+	assert.NoError(t, err)
+	assert.Equal(t, data, again, "Encoding should be canonical")
+}
+
+func TestSerialize_RejectsMalformedData(t *testing.T) {
+	block, tx := serializationFixture()
+
+	data, _ := tx.MarshalBinary()
+	assert.ErrorIs(t, (&Transaction{}).UnmarshalBinary(data[:len(data)-1]), ErrUnexpectedEnd)
+	assert.ErrorIs(t, (&Transaction{}).UnmarshalBinary(append(data, 0)), ErrTrailingData)
+
+	data[0] = TX_WIRE_VERSION + 1
+	assert.ErrorIs(t, (&Transaction{}).UnmarshalBinary(data), ErrUnsupportedVersion)
+
+	data, _ = block.MarshalBinary()
+	assert.ErrorIs(t, (&Block{}).UnmarshalBinary(data[:len(data)/2]), ErrUnexpectedEnd)
+}
+
+func FuzzTransactionUnmarshalBinary(f *testing.F) {
+	_, tx := serializationFixture()
+	data, _ := tx.MarshalBinary()
+	f.Add(data)
+	coinbase, _ := NewCoinbaseTransaction(COINBASE, tx.Outputs[0].Address).MarshalBinary()
+	f.Add(coinbase)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		decoded := &Transaction{}
+		if decoded.UnmarshalBinary(data) != nil {
+			return
+		}
+		encoded, err := decoded.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		again := &Transaction{}
+		if err := again.UnmarshalBinary(encoded); err != nil {
+			t.Fatalf("re-encoded transaction does not decode: %v", err)
+		}
+		reencoded, _ := again.MarshalBinary()
+		assert.Equal(t, encoded, reencoded)
+		assert.Equal(t, decoded.GetHash(), again.GetHash())
+	})
+}
+
+func FuzzBlockUnmarshalBinary(f *testing.F) {
+	block, _ := serializationFixture()
+	data, _ := block.MarshalBinary()
+	f.Add(data)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		decoded := &Block{}
+		if decoded.UnmarshalBinary(data) != nil {
+			return
+		}
+		encoded, err := decoded.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		again := &Block{}
+		if err := again.UnmarshalBinary(encoded); err != nil {
+			t.Fatalf("re-encoded block does not decode: %v", err)
+		}
+		assert.Equal(t, decoded.GetHash(), again.GetHash())
+	})
+}
@@ -0,0 +1,88 @@
+package third_faza
+
+// SigHashType selects which parts of a transaction a signature covers. It is appended to
+// every signature as its last byte.
+type SigHashType byte
+
+const (
+	// SIGHASH_ALL covers all inputs and outputs.
+	SIGHASH_ALL SigHashType = 0x01
+	// SIGHASH_NONE covers the inputs but no outputs, so anyone may decide where the coins go.
+	SIGHASH_NONE SigHashType = 0x02
+	// SIGHASH_SINGLE covers the inputs and only the output at the index of the signed input.
+	SIGHASH_SINGLE SigHashType = 0x03
+	// SIGHASH_ANYONECANPAY can be combined with the types above to cover only the signed input,
+	// so others can add inputs later.
+	SIGHASH_ANYONECANPAY SigHashType = 0x80
+)
+
+// Base returns the hash type without SIGHASH_ANYONECANPAY.
+func (hashType SigHashType) Base() SigHashType {
+	return hashType &^ SIGHASH_ANYONECANPAY
+}
+
+// AnyoneCanPay reports whether the signature covers only its own input.
+func (hashType SigHashType) AnyoneCanPay() bool {
+	return hashType&SIGHASH_ANYONECANPAY != 0
+}
+
+// IsValid reports whether the hash type is one of the defined combinations.
+func (hashType SigHashType) IsValid() bool {
+	base := hashType.Base()
+	return base >= SIGHASH_ALL && base <= SIGHASH_SINGLE
+}
+
+// SplitSigHashType separates a signature stored in a transaction into the signature made by
+// the signature scheme and its hash type. ok is false if the hash type is missing or unknown.
+func SplitSigHashType(signature []byte) (sig []byte, hashType SigHashType, ok bool) {
+	if len(signature) < 2 {
+		return nil, 0, false
+	}
+	hashType = SigHashType(signature[len(signature)-1])
+	return signature[:len(signature)-1], hashType, hashType.IsValid()
+}
+
+// CreateSignatureWithHashType signs the data of the input selected by hashType with sk and
+// returns the signature followed by the hash type. It returns nil if there is nothing to sign,
+// e.g. for SIGHASH_SINGLE on an input without a matching output, or if sk cannot sign, e.g.
+// because no registered scheme owns its key.
+func (tx *Transaction) CreateSignatureWithHashType(sk PrivateKey, input int, hashType SigHashType) []byte {
+	if !hashType.IsValid() {
+		return nil
+	}
+	data := tx.GetDataToSignWithHashType(input, hashType)
+	if data == nil {
+		return nil
+	}
+	sig, err := Sign(sk, data)
+	if err != nil {
+		return nil
+	}
+	return append(sig, byte(hashType))
+}
+
+// SignTxWithHashType signs the input with sk like SignTx, covering only what hashType selects.
+func (tx *Transaction) SignTxWithHashType(sk PrivateKey, input int, hashType SigHashType) {
+	tx.AddSignature(tx.CreateSignatureWithHashType(sk, input, hashType), input)
+	tx.Finalize()
+}
+
+// SignMultiSigTxWithHashType adds a multisig signature like SignMultiSigTx, covering only what
+// hashType selects.
+func (tx *Transaction) SignMultiSigTxWithHashType(sk PrivateKey, input int, hashType SigHashType) {
+	if sig := tx.CreateSignatureWithHashType(sk, input, hashType); sig != nil {
+		tx.Inputs[input].AddMultiSignature(sig)
+	}
+	tx.Finalize()
+}
+
+// VerifyInputSignature reports whether signature, with its trailing hash type, is a signature
+// of the input by pubKey.
+func (tx *Transaction) VerifyInputSignature(input int, signature []byte, pubKey PublicKey) bool {
+	sig, hashType, ok := SplitSigHashType(signature)
+	if !ok {
+		return false
+	}
+	data := tx.GetDataToSignWithHashType(input, hashType)
+	return data != nil && VerifySignature(data, sig, pubKey)
+}
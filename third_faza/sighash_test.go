@@ -0,0 +1,136 @@
+package third_faza
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/rsa"
+	"io"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSigHash_PartialSigning(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
+	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyBob := &privateKeyBob.PublicKey
+
+	privateKeyAlice, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyAlice := &privateKeyAlice.PublicKey
+
+	privateKeyCyril, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyCyril := &privateKeyCyril.PublicKey
+
+	genesisBlock := NewBlock(nil, pubKeyBob)
+	genesisBlock.Finalizee()
+	localBlockchain := NewBlockchain(genesisBlock)
+	HandleBlocks(localBlockchain)
+
+	tx1 := NewTransaction()
+	tx1.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
+	tx1.AddOutput(1*COIN, pubKeyAlice)
+	tx1.AddOutput(2*COIN, pubKeyBob)
+	tx1.SignTx(privateKeyBob, 0)
+	TxProcess(tx1)
+	assert.NotNil(t, BlockCreate(pubKeyBob))
+
+	// Alice signs only her input and her output; Bob adds his own afterwards.
+	shared := NewTransaction()
+	shared.AddInput(tx1.GetHash(), 0)
+	shared.AddOutput(1*COIN, pubKeyCyril)
+	shared.SignTxWithHashType(privateKeyAlice, 0, SIGHASH_SINGLE|SIGHASH_ANYONECANPAY)
+	assert.Equal(t, byte(SIGHASH_SINGLE|SIGHASH_ANYONECANPAY), shared.Inputs[0].Signature[len(shared.Inputs[0].Signature)-1])
+
+	shared.AddInput(tx1.GetHash(), 1)
+	shared.AddOutput(2*COIN, pubKeyAlice)
+	shared.SignTx(privateKeyBob, 1)
+	assert.True(t, TxIsValid(*shared, localBlockchain.UTXOSet), "Alice's signature should survive Bob's input and output")
+
+	// A different output at Alice's index breaks her signature.
+	changed := NewTransactionFromTransaction(shared)
+	changed.Outputs[0].Value = COIN / 2
+	changed.SignTx(privateKeyBob, 1)
+	assert.False(t, TxIsValid(*changed, localBlockchain.UTXOSet))
+
+	// A SIGHASH_ALL signature covers every input and output.
+	all := NewTransaction()
+	all.AddInput(tx1.GetHash(), 0)
+	all.AddOutput(1*COIN, pubKeyCyril)
+	all.SignTx(privateKeyAlice, 0)
+	all.AddInput(tx1.GetHash(), 1)
+	all.AddOutput(2*COIN, pubKeyAlice)
+	all.SignTx(privateKeyBob, 1)
+	assert.False(t, TxIsValid(*all, localBlockchain.UTXOSet), "Adding inputs and outputs should break a SIGHASH_ALL signature")
+
+	// SIGHASH_NONE leaves the outputs to others, but not the inputs.
+	none := NewTransaction()
+	none.AddInput(tx1.GetHash(), 0)
+	none.SignTxWithHashType(privateKeyAlice, 0, SIGHASH_NONE)
+	none.AddOutput(1*COIN, pubKeyCyril)
+	assert.True(t, TxIsValid(*none, localBlockchain.UTXOSet))
+	none.AddInput(tx1.GetHash(), 1)
+	none.SignTx(privateKeyBob, 1)
+	assert.False(t, TxIsValid(*none, localBlockchain.UTXOSet), "SIGHASH_NONE without ANYONECANPAY covers the inputs")
+
+	// The hash type is part of the signed data.
+	tampered := NewTransactionFromTransaction(shared)
+	sig := tampered.Inputs[0].Signature
+	sig[len(sig)-1] = byte(SIGHASH_SINGLE)
+	assert.False(t, TxIsValid(*tampered, localBlockchain.UTXOSet))
+	sig[len(sig)-1] = 0x04
+	assert.False(t, TxIsValid(*tampered, localBlockchain.UTXOSet), "Unknown hash types should be rejected")
+
+	noOutput := NewTransaction()
+	noOutput.AddInput(tx1.GetHash(), 0)
+	assert.Nil(t, noOutput.CreateSignatureWithHashType(privateKeyAlice, 0, SIGHASH_SINGLE), "SIGHASH_SINGLE needs an output at the input's index")
+
+	TxProcess(shared)
+	block := BlockCreate(pubKeyBob)
+	assert.NotNil(t, block)
+	assert.Equal(t, 2, len(block.GetTransactions()))
+}
+
+// foreignSigner is a signer whose key no registered scheme owns.
+type foreignSigner struct {
+	pubKey crypto.PublicKey
+}
+
+func (s foreignSigner) Public() crypto.PublicKey { return s.pubKey }
+
+func (s foreignSigner) Sign(io.Reader, []byte, crypto.SignerOpts) ([]byte, error) {
+	return []byte{1}, nil
+}
+
+func TestSigHash_UnknownKeyTypeDoesNotSign(t *testing.T) {
+	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	x25519, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		log.Fatal(err)
+	}
+	foreign := foreignSigner{pubKey: x25519.PublicKey()}
+
+	tx := NewTransaction()
+	tx.AddInput([]byte("previous transaction"), 0)
+	tx.AddOutput(1*COIN, &privateKeyBob.PublicKey)
+	assert.Nil(t, tx.CreateSignatureWithHashType(foreign, 0, SIGHASH_ALL), "A key without a scheme should not sign")
+
+	tx.SignTx(foreign, 0)
+	assert.Empty(t, tx.Inputs[0].Signature)
+	tx.SignMultiSigTx(foreign, 0)
+	assert.Empty(t, tx.Inputs[0].MultiSigSignature)
+}
@@ -0,0 +1,264 @@
+package third_faza
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"errors"
+)
+
+// KeyType tags the signature scheme of a public key wherever the key is encoded.
+type KeyType byte
+
+const (
+	KEY_TYPE_RSA     KeyType = 1
+	KEY_TYPE_ED25519 KeyType = 2
+	// KEY_TYPE_ECDSA_P256 is ECDSA over NIST P-256. secp256k1 is not available in the
+	// standard library, so P-256 is the ECDSA curve used here.
+	KEY_TYPE_ECDSA_P256 KeyType = 3
+)
+
+// RSA_KEY_BITS is the size of the RSA keys generated by RSAScheme.
+const RSA_KEY_BITS = 2048
+
+var (
+	ErrUnknownKeyType   = errors.New("unknown key type")
+	ErrInvalidPublicKey = errors.New("invalid public key")
+)
+
+// PublicKey is a public key of a registered signature scheme. With the standard schemes it
+// is an *rsa.PublicKey, an ed25519.PublicKey or an *ecdsa.PublicKey.
+type PublicKey interface {
+	Equal(x crypto.PublicKey) bool
+}
+
+// PrivateKey is a private key of a registered signature scheme: *rsa.PrivateKey,
+// ed25519.PrivateKey, *ecdsa.PrivateKey or any other signer whose public key a scheme owns.
+type PrivateKey interface {
+	crypto.Signer
+}
+
+// SignatureScheme signs and verifies transaction data with one type of key.
+type SignatureScheme interface {
+	KeyType() KeyType
+	// Owns reports whether pubKey is a key of the scheme.
+	Owns(pubKey PublicKey) bool
+	GenerateKey() (PrivateKey, error)
+	Sign(sk PrivateKey, message []byte) ([]byte, error)
+	Verify(pubKey PublicKey, message []byte, signature []byte) bool
+	// MarshalPublicKey returns the compact encoding of the key used on the wire and in
+	// the data to sign; UnmarshalPublicKey reverses it.
+	MarshalPublicKey(pubKey PublicKey) []byte
+	UnmarshalPublicKey(data []byte) (PublicKey, error)
+}
+
+var (
+	RSAScheme     SignatureScheme = rsaScheme{}
+	Ed25519Scheme SignatureScheme = ed25519Scheme{}
+	ECDSAScheme   SignatureScheme = ecdsaScheme{}
+)
+
+// DefaultSignatureScheme is the scheme of the keys created by GenerateKey.
+var DefaultSignatureScheme = Ed25519Scheme
+
+var signatureSchemes = map[KeyType]SignatureScheme{}
+
+func init() {
+	RegisterSignatureScheme(RSAScheme)
+	RegisterSignatureScheme(Ed25519Scheme)
+	RegisterSignatureScheme(ECDSAScheme)
+}
+
+// RegisterSignatureScheme makes keys of the scheme usable in outputs, scripts and encodings.
+// A scheme registered with the KeyType of another replaces it.
+func RegisterSignatureScheme(scheme SignatureScheme) {
+	signatureSchemes[scheme.KeyType()] = scheme
+}
+
+// GetSignatureScheme returns the scheme registered for the key type, or nil.
+func GetSignatureScheme(keyType KeyType) SignatureScheme {
+	return signatureSchemes[keyType]
+}
+
+// SchemeOf returns the scheme owning pubKey, or nil for nil and unsupported keys.
+func SchemeOf(pubKey PublicKey) SignatureScheme {
+	if pubKey == nil {
+		return nil
+	}
+	for _, scheme := range signatureSchemes {
+		if scheme.Owns(pubKey) {
+			return scheme
+		}
+	}
+	return nil
+}
+
+// GenerateKey creates a key of DefaultSignatureScheme.
+func GenerateKey() (PrivateKey, error) {
+	return DefaultSignatureScheme.GenerateKey()
+}
+
+// PublicKeyOf returns the public key of sk.
+func PublicKeyOf(sk PrivateKey) PublicKey {
+	pubKey, _ := sk.Public().(PublicKey)
+	return pubKey
+}
+
+// Sign signs message with sk using the scheme of its public key.
+func Sign(sk PrivateKey, message []byte) ([]byte, error) {
+	scheme := SchemeOf(PublicKeyOf(sk))
+	if scheme == nil {
+		return nil, ErrUnknownKeyType
+	}
+	return scheme.Sign(sk, message)
+}
+
+// keysEqual reports whether two keys are the same, treating nil keys as equal to each other.
+func keysEqual(a PublicKey, b PublicKey) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Equal(b)
+}
+
+// marshalTaggedPublicKey returns the key type followed by the compact encoding of the key,
+// or nil if no registered scheme owns it.
+func marshalTaggedPublicKey(pubKey PublicKey) []byte {
+	scheme := SchemeOf(pubKey)
+	if scheme == nil {
+		return nil
+	}
+	return append([]byte{byte(scheme.KeyType())}, scheme.MarshalPublicKey(pubKey)...)
+}
+
+// unmarshalTaggedPublicKey reverses marshalTaggedPublicKey.
+func unmarshalTaggedPublicKey(data []byte) (PublicKey, error) {
+	if len(data) == 0 {
+		return nil, ErrInvalidPublicKey
+	}
+	scheme := GetSignatureScheme(KeyType(data[0]))
+	if scheme == nil {
+		return nil, ErrUnknownKeyType
+	}
+	return scheme.UnmarshalPublicKey(data[1:])
+}
+
+// rsaScheme signs the SHA-256 hash of the message with RSA PKCS #1 v1.5.
+type rsaScheme struct{}
+
+func (rsaScheme) KeyType() KeyType {
+	return KEY_TYPE_RSA
+}
+
+func (rsaScheme) Owns(pubKey PublicKey) bool {
+	key, ok := pubKey.(*rsa.PublicKey)
+	return ok && key != nil
+}
+
+func (rsaScheme) GenerateKey() (PrivateKey, error) {
+	return rsa.GenerateKey(rand.Reader, RSA_KEY_BITS)
+}
+
+func (rsaScheme) Sign(sk PrivateKey, message []byte) ([]byte, error) {
+	hash := sha256.Sum256(message)
+	return sk.Sign(rand.Reader, hash[:], crypto.SHA256)
+}
+
+func (rsaScheme) Verify(pubKey PublicKey, message []byte, signature []byte) bool {
+	hash := sha256.Sum256(message)
+	return rsa.VerifyPKCS1v15(pubKey.(*rsa.PublicKey), crypto.SHA256, hash[:], signature) == nil
+}
+
+func (rsaScheme) MarshalPublicKey(pubKey PublicKey) []byte {
+	return x509.MarshalPKCS1PublicKey(pubKey.(*rsa.PublicKey))
+}
+
+func (rsaScheme) UnmarshalPublicKey(data []byte) (PublicKey, error) {
+	key, err := x509.ParsePKCS1PublicKey(data)
+	if err != nil {
+		return nil, ErrInvalidPublicKey
+	}
+	return key, nil
+}
+
+// ed25519Scheme signs the message itself with Ed25519.
+type ed25519Scheme struct{}
+
+func (ed25519Scheme) KeyType() KeyType {
+	return KEY_TYPE_ED25519
+}
+
+func (ed25519Scheme) Owns(pubKey PublicKey) bool {
+	_, ok := pubKey.(ed25519.PublicKey)
+	return ok
+}
+
+func (ed25519Scheme) GenerateKey() (PrivateKey, error) {
+	_, sk, err := ed25519.GenerateKey(rand.Reader)
+	return sk, err
+}
+
+func (ed25519Scheme) Sign(sk PrivateKey, message []byte) ([]byte, error) {
+	return sk.Sign(rand.Reader, message, crypto.Hash(0))
+}
+
+func (ed25519Scheme) Verify(pubKey PublicKey, message []byte, signature []byte) bool {
+	key := pubKey.(ed25519.PublicKey)
+	return len(key) == ed25519.PublicKeySize && ed25519.Verify(key, message, signature)
+}
+
+func (ed25519Scheme) MarshalPublicKey(pubKey PublicKey) []byte {
+	return append([]byte{}, pubKey.(ed25519.PublicKey)...)
+}
+
+func (ed25519Scheme) UnmarshalPublicKey(data []byte) (PublicKey, error) {
+	if len(data) != ed25519.PublicKeySize {
+		return nil, ErrInvalidPublicKey
+	}
+	return ed25519.PublicKey(append([]byte{}, data...)), nil
+}
+
+// ecdsaScheme signs the SHA-256 hash of the message with ECDSA over P-256. Signatures are
+// ASN.1 encoded and keys are encoded as compressed points.
+type ecdsaScheme struct{}
+
+func (ecdsaScheme) KeyType() KeyType {
+	return KEY_TYPE_ECDSA_P256
+}
+
+func (ecdsaScheme) Owns(pubKey PublicKey) bool {
+	key, ok := pubKey.(*ecdsa.PublicKey)
+	return ok && key != nil && key.Curve == elliptic.P256()
+}
+
+func (ecdsaScheme) GenerateKey() (PrivateKey, error) {
+	return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+}
+
+func (ecdsaScheme) Sign(sk PrivateKey, message []byte) ([]byte, error) {
+	hash := sha256.Sum256(message)
+	return sk.Sign(rand.Reader, hash[:], crypto.SHA256)
+}
+
+func (ecdsaScheme) Verify(pubKey PublicKey, message []byte, signature []byte) bool {
+	hash := sha256.Sum256(message)
+	return ecdsa.VerifyASN1(pubKey.(*ecdsa.PublicKey), hash[:], signature)
+}
+
+func (ecdsaScheme) MarshalPublicKey(pubKey PublicKey) []byte {
+	key := pubKey.(*ecdsa.PublicKey)
+	return elliptic.MarshalCompressed(key.Curve, key.X, key.Y)
+}
+
+func (ecdsaScheme) UnmarshalPublicKey(data []byte) (PublicKey, error) {
+	x, y := elliptic.UnmarshalCompressed(elliptic.P256(), data)
+	if x == nil {
+		return nil, ErrInvalidPublicKey
+	}
+	return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+}
@@ -0,0 +1,131 @@
+package third_faza
+
+import (
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignature_SchemesSignAndVerify(t *testing.T) {
+	for _, scheme := range []SignatureScheme{Ed25519Scheme, ECDSAScheme, RSAScheme} {
+		sk, err := scheme.GenerateKey()
+		if err != nil {
+			log.Fatal(err)
+		}
+		pubKey := PublicKeyOf(sk)
+		assert.Equal(t, scheme, SchemeOf(pubKey))
+
+		sig, err := Sign(sk, []byte("message"))
+		assert.NoError(t, err)
+		assert.True(t, VerifySignature([]byte("message"), sig, pubKey))
+		assert.False(t, VerifySignature([]byte("other message"), sig, pubKey))
+
+		decoded, err := unmarshalTaggedPublicKey(marshalTaggedPublicKey(pubKey))
+		assert.NoError(t, err)
+		assert.True(t, pubKey.Equal(decoded))
+	}
+
+	_, ok := DefaultSignatureScheme.(ed25519Scheme)
+	assert.True(t, ok, "Ed25519 should be the default scheme")
+
+	x25519, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		log.Fatal(err)
+	}
+	assert.Nil(t, SchemeOf(x25519.PublicKey()))
+	assert.Nil(t, EncodePublicKey(x25519.PublicKey()))
+	_, err = unmarshalTaggedPublicKey([]byte{0xff, 1, 2, 3})
+	assert.ErrorIs(t, err, ErrUnknownKeyType)
+}
+
+func TestSignature_MixedKeyTypesOnChain(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
+	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyBob := &privateKeyBob.PublicKey
+
+	privateKeyAlice, err := Ed25519Scheme.GenerateKey()
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyAlice := PublicKeyOf(privateKeyAlice)
+
+	privateKeyCyril, err := ECDSAScheme.GenerateKey()
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyCyril := PublicKeyOf(privateKeyCyril)
+
+	genesisBlock := NewBlock(nil, pubKeyBob)
+	genesisBlock.Finalizee()
+	localBlockchain := NewBlockchain(genesisBlock)
+	HandleBlocks(localBlockchain)
+
+	tx1 := NewTransaction()
+	tx1.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
+	tx1.AddOutput(1*COIN, pubKeyAlice)
+	tx1.AddOutput(1*COIN, pubKeyCyril)
+	tx1.AddMultisigOutput(NewMOfNMultiSigOutput(1*COIN, 2, []PublicKey{pubKeyBob, pubKeyAlice, pubKeyCyril}))
+	assert.NoError(t, tx1.AddAddressOutput(0.125*COIN, AddressOf(pubKeyAlice)))
+	tx1.SignTx(privateKeyBob, 0)
+	TxProcess(tx1)
+	assert.NotNil(t, BlockCreate(pubKeyCyril))
+
+	tx2 := NewTransaction()
+	tx2.AddInput(tx1.GetHash(), 0)
+	tx2.AddInput(tx1.GetHash(), 1)
+	tx2.AddInput(tx1.GetHash(), 2)
+	tx2.AddInput(tx1.GetHash(), 3)
+	tx2.AddOutput(3.125*COIN, pubKeyCyril)
+	tx2.SignTx(privateKeyAlice, 0)
+	tx2.SignTx(privateKeyCyril, 1)
+	tx2.SignMultiSigTx(privateKeyCyril, 2)
+	tx2.SignMultiSigTx(privateKeyAlice, 2)
+	tx2.SignPubKeyHashTx(privateKeyAlice, 3)
+	assert.True(t, TxIsValid(*tx2, localBlockchain.UTXOSet), "Ed25519, ECDSA and mixed multisig inputs should verify")
+
+	wrongKey := NewTransactionFromTransaction(tx2)
+	wrongKey.SignTx(privateKeyCyril, 0)
+	assert.False(t, TxIsValid(*wrongKey, localBlockchain.UTXOSet), "A signature by another key type should be rejected")
+
+	// Outputs keep their key type through the binary and JSON encodings.
+	data, err := tx1.MarshalBinary()
+	assert.NoError(t, err)
+	decoded := &Transaction{}
+	assert.NoError(t, decoded.UnmarshalBinary(data))
+	assert.Equal(t, tx1.GetHash(), decoded.GetHash())
+	assert.IsType(t, ed25519.PublicKey{}, decoded.Outputs[0].Address)
+	assert.True(t, pubKeyCyril.Equal(decoded.Outputs[1].Address))
+	assert.True(t, pubKeyBob.Equal(decoded.Outputs[2].MultiSigAddresses[0]))
+
+	jsonData, err := json.Marshal(tx1)
+	assert.NoError(t, err)
+	fromJSON := &Transaction{}
+	assert.NoError(t, json.Unmarshal(jsonData, fromJSON))
+	assert.Equal(t, tx1.GetHash(), fromJSON.GetHash())
+}
+
+func TestSignature_Ed25519OutputsAreSmaller(t *testing.T) {
+	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	privateKeyAlice, err := GenerateKey()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	rsaOutput, _ := NewOutput(COIN, &privateKeyBob.PublicKey).MarshalBinary()
+	ed25519Output, _ := NewOutput(COIN, PublicKeyOf(privateKeyAlice)).MarshalBinary()
+	assert.Less(t, len(ed25519Output), 50)
+	assert.Less(t, len(ed25519Output), len(rsaOutput))
+}
@@ -0,0 +1,128 @@
+package third_faza
+
+import (
+	"bytes"
+	"errors"
+	"time"
+)
+
+// NewBlockFromHeader creates a body-less block that carries only the given header.
+// Such blocks are used by HeaderChain, which never sees transactions.
+func NewBlockFromHeader(header BlockHeader) *Block {
+	block := &Block{
+		header: header,
+	}
+	block.header.PrevBlockHash = append([]byte{}, header.PrevBlockHash...)
+	block.header.MerkleRoot = append([]byte{}, header.MerkleRoot...)
+	block.header.WitnessRoot = append([]byte{}, header.WitnessRoot...)
+	block.hash = block.CalculateHash()
+	return block
+}
+
+// HeaderChain is a light-client (SPV) view of the chain. It stores block headers only and
+// checks the same header rules as Blockchain.BlockAdd: parent link, proof of work, difficulty
+// and timestamp. Payments are confirmed with Merkle proofs against the stored headers.
+type HeaderChain struct {
+	Headers map[string]*BlockNode
+	Tip     *BlockNode
+}
+
+// NewHeaderChain creates a header chain rooted at the given genesis header.
+func NewHeaderChain(genesis BlockHeader) *HeaderChain {
+	genesisNode := NewBlockNode(NewBlockFromHeader(genesis), nil)
+
+	headerChain := &HeaderChain{
+		Headers: make(map[string]*BlockNode),
+		Tip:     genesisNode,
+	}
+	headerChain.Headers[keyFoBlock(genesisNode.B.GetHash())] = genesisNode
+	return headerChain
+}
+
+// Get returns the header node with the given block hash, or nil if it is unknown.
+func (headerChain *HeaderChain) Get(blockHash []byte) *BlockNode {
+	return headerChain.Headers[keyFoBlock(blockHash)]
+}
+
+// AddHeader validates the header against its parent and stores it.
+// The tip moves to the new header when its chain has more work than the current one, or as
+// much work and a lower block hash, the same rule BlockAdd follows.
+func (headerChain *HeaderChain) AddHeader(header BlockHeader) error {
+	block := NewBlockFromHeader(header)
+	if headerChain.Get(block.GetHash()) != nil {
+		return nil
+	}
+
+	parent := headerChain.Get(header.PrevBlockHash)
+	if parent == nil {
+		return errors.New("unknown parent header")
+	}
+	if header.Version < 1 {
+		return errors.New("invalid header version")
+	}
+	if header.Bits != NextWorkRequired(parent) || !HashMeetsTarget(block.GetHash(), header.Bits) {
+		return errors.New("header does not meet the required proof of work")
+	}
+	if header.Timestamp < MedianTimePast(parent) || header.Timestamp > time.Now().Unix()+MAX_FUTURE_BLOCK_TIME {
+		return errors.New("header timestamp out of range")
+	}
+
+	node := NewBlockNode(block, parent)
+	parent.Children = append(parent.Children, node)
+	headerChain.Headers[keyFoBlock(block.GetHash())] = node
+	switch node.ChainWork.Cmp(headerChain.Tip.ChainWork) {
+	case 1:
+		headerChain.Tip = node
+	case 0:
+		if bytes.Compare(block.GetHash(), headerChain.Tip.B.GetHash()) < 0 {
+			headerChain.Tip = node
+		}
+	}
+	return nil
+}
+
+// Confirmations returns how many headers of the best chain bury the given block, counting the block itself.
+// Blocks that are unknown or not on the best chain have zero confirmations.
+func (headerChain *HeaderChain) Confirmations(blockHash []byte) uint {
+	node := headerChain.Get(blockHash)
+	if node == nil {
+		return 0
+	}
+	for current := headerChain.Tip; current != nil; current = current.Parent {
+		if current == node {
+			return headerChain.Tip.Height - node.Height + 1
+		}
+		if current.Height < node.Height {
+			break
+		}
+	}
+	return 0
+}
+
+// VerifyTransaction checks that the proof links the transaction to a header on the best chain
+// that is buried under at least minConfirmations headers.
+func (headerChain *HeaderChain) VerifyTransaction(proof *MerkleProof, minConfirmations uint) error {
+	if proof == nil {
+		return errors.New("missing proof")
+	}
+	node := headerChain.Get(proof.BlockHash)
+	if node == nil {
+		return errors.New("unknown block header")
+	}
+	if !VerifyMerkleProof(node.B.GetMerkleRoot(), proof) {
+		return errors.New("merkle proof does not match the header")
+	}
+	if confirmations := headerChain.Confirmations(proof.BlockHash); confirmations < minConfirmations || confirmations == 0 {
+		return errors.New("not enough confirmations")
+	}
+	return nil
+}
+
+// GetMerkleProof serves an inclusion proof for a transaction in a block the chain still keeps in memory.
+func (blockChain *Blockchain) GetMerkleProof(blockHash []byte, txHash []byte) (*MerkleProof, error) {
+	node := blockChain.Get(blockHash)
+	if node == nil {
+		return nil, errors.New("unknown block")
+	}
+	return node.B.GetMerkleProof(txHash)
+}
@@ -0,0 +1,144 @@
+package third_faza
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"fmt"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSPV_MerkleProofForEveryLeaf(t *testing.T) {
+	for count := 1; count <= 7; count++ {
+		hashes := make([][]byte, count)
+		for i := range hashes {
+			hash := sha256.Sum256([]byte{byte(count), byte(i)})
+			hashes[i] = hash[:]
+		}
+		root := MerkleRoot(hashes)
+
+		for i := range hashes {
+			proof := &MerkleProof{TxHash: hashes[i], Index: i, Branch: MerkleBranch(hashes, i)}
+			assert.True(t, VerifyMerkleProof(root, proof), fmt.Sprintf("Leaf %d of %d should be proven", i, count))
+
+			proof.Index = (i + 1) % count
+			if count > 1 {
+				assert.False(t, VerifyMerkleProof(root, proof), fmt.Sprintf("Leaf %d of %d should fail at a wrong position", i, count))
+			}
+		}
+	}
+}
+
+func TestSPV_LightClientVerifiesPayment(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
+	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyBob := &privateKeyBob.PublicKey
+
+	privateKeyAlice, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyAlice := &privateKeyAlice.PublicKey
+
+	genesisBlock := NewBlock(nil, pubKeyBob)
+	genesisBlock.Finalizee()
+	localBlockchain := NewBlockchain(genesisBlock)
+	HandleBlocks(localBlockchain)
+
+	tx1 := NewTransaction()
+	tx1.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
+	tx1.AddOutput(1*COIN, pubKeyAlice)
+	tx1.AddOutput(2.125*COIN, pubKeyBob)
+	tx1.SignTx(privateKeyBob, 0)
+
+	block1 := NewBlock(genesisBlock.GetHash(), pubKeyAlice)
+	block1.TransactionAdd(tx1)
+	block1.Finalizee()
+	assert.True(t, BlockProcess(block1), "Block with payment should be accepted")
+
+	block2 := BlockCreate(pubKeyBob)
+	assert.NotNil(t, block2, "Block on top of the payment should be created")
+
+	lightClient := NewHeaderChain(genesisBlock.GetHeader())
+	assert.NoError(t, lightClient.AddHeader(block1.GetHeader()))
+	assert.NoError(t, lightClient.AddHeader(block2.GetHeader()))
+
+	proof, err := localBlockchain.GetMerkleProof(block1.GetHash(), tx1.GetHash())
+	assert.NoError(t, err)
+	assert.NoError(t, lightClient.VerifyTransaction(proof, 2), "Payment buried under two headers should be confirmed")
+	assert.Error(t, lightClient.VerifyTransaction(proof, 3), "Payment should not have three confirmations")
+
+	proof.TxHash = block1.GetCoinbase().GetHash()
+	assert.Error(t, lightClient.VerifyTransaction(proof, 1), "Proof for another transaction should fail")
+
+	_, err = block2.GetMerkleProof(tx1.GetHash())
+	assert.Error(t, err, "Block without the transaction cannot prove it")
+}
+
+func TestSPV_LightClientRejectsInvalidHeaders(t *testing.T) {
+	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyBob := &privateKeyBob.PublicKey
+
+	genesisBlock := NewBlock(nil, pubKeyBob)
+	genesisBlock.Finalizee()
+	localBlockchain := NewBlockchain(genesisBlock)
+	HandleBlocks(localBlockchain)
+
+	lightClient := NewHeaderChain(genesisBlock.GetHeader())
+
+	orphan := NewBlock([]byte("unknown parent"), pubKeyBob)
+	orphan.Finalizee()
+	assert.Error(t, lightClient.AddHeader(orphan.GetHeader()), "Header with an unknown parent should be rejected")
+
+	block1 := NewBlock(genesisBlock.GetHash(), pubKeyBob)
+	block1.Finalizee()
+	header := block1.GetHeader()
+	for HashMeetsTarget(header.Hash(), header.Bits) {
+		header.Nonce++
+	}
+	assert.Error(t, lightClient.AddHeader(header), "Header without proof of work should be rejected")
+	assert.NoError(t, lightClient.AddHeader(block1.GetHeader()))
+	assert.Equal(t, uint(2), lightClient.Tip.Height)
+}
+
+func TestSPV_EqualWorkTieGoesToLowerHash(t *testing.T) {
+	keys := make([]PublicKey, 3)
+	for i := range keys {
+		privateKey, err := rsa.GenerateKey(rand.Reader, 1024)
+		if err != nil {
+			log.Fatal(err)
+		}
+		keys[i] = &privateKey.PublicKey
+	}
+
+	genesisBlock := NewBlock(nil, keys[0])
+	genesisBlock.Finalizee()
+	localBlockchain := NewBlockchain(genesisBlock)
+	HandleBlocks(localBlockchain)
+
+	blockA := NewBlock(genesisBlock.GetHash(), keys[1])
+	blockA.Finalizee()
+	blockB := NewBlock(genesisBlock.GetHash(), keys[2])
+	blockB.Finalizee()
+	assert.True(t, BlockProcess(blockA))
+	assert.True(t, BlockProcess(blockB))
+
+	// Both orders of arrival end on the tip the full node picked.
+	for i, order := range [][]*Block{{blockA, blockB}, {blockB, blockA}} {
+		lightClient := NewHeaderChain(genesisBlock.GetHeader())
+		for _, block := range order {
+			assert.NoError(t, lightClient.AddHeader(block.GetHeader()))
+		}
+		assert.Equal(t, localBlockchain.GetBlockAtMaxHeight().GetHash(), lightClient.Tip.B.GetHash(), fmt.Sprintf("Order #%d should pick the lower hash", i))
+	}
+}
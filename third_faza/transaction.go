@@ -1,23 +1,33 @@
 package third_faza
 
 import (
-	"crypto"
-	"crypto/rand"
-	"crypto/rsa"
+	"bytes"
 	"crypto/sha256"
 	"encoding/binary"
 	"encoding/hex"
-	"math"
 	"time"
 )
 
+// Output kinds tag every output in the transaction ID and in the signed data. They start
+// above the KeyType values, so a kind byte is never read as the tag of a public key.
+const (
+	OUTPUT_KIND_NONE     byte = 0x10
+	OUTPUT_KIND_KEY      byte = 0x11
+	OUTPUT_KIND_MULTISIG byte = 0x12
+	OUTPUT_KIND_SCRIPT   byte = 0x13
+)
+
 // Input represents a transaction input.
 // It refers to a previous transaction's output that is being spent.
+// Outputs locked by a custom script are unlocked with UnlockScript instead of signatures.
+// Sequence holds the relative lock of the input (see SequenceLockBlocks and SequenceLockTime).
 type Input struct {
 	PrevTxHash        []byte
 	OutputIndex       int
 	Signature         []byte
 	MultiSigSignature [][]byte
+	UnlockScript      Script
+	Sequence          uint32
 }
 
 // NewInput creates a new Input instance with a copy of the previous transaction hash and a specified output index.
@@ -40,6 +50,18 @@ func (in *Input) AddMultiSignature(sig []byte) {
 	in.MultiSigSignature = append(in.MultiSigSignature, sig)
 }
 
+// UnlockingScript returns the script run before the locking script of the spent output:
+// UnlockScript if set, otherwise a push of the multisig signatures or of the signature.
+func (in *Input) UnlockingScript() Script {
+	if len(in.UnlockScript) > 0 {
+		return in.UnlockScript
+	}
+	if len(in.MultiSigSignature) > 0 {
+		return SignatureScript(in.MultiSigSignature...)
+	}
+	return SignatureScript(in.Signature)
+}
+
 func (in *Input) Equals(other *Input) bool {
 	if other == nil {
 		return false
@@ -52,7 +74,7 @@ func (in *Input) Equals(other *Input) bool {
 			return false
 		}
 	}
-	if in.OutputIndex != other.OutputIndex {
+	if in.OutputIndex != other.OutputIndex || in.Sequence != other.Sequence {
 		return false
 	}
 
@@ -80,30 +102,72 @@ func (in *Input) Equals(other *Input) bool {
 			}
 		}
 	}
-	return true
+	return bytes.Equal(in.UnlockScript, other.UnlockScript)
 }
 
 // Output represents a transaction output.
-// It includes a value (in coins) and a recipient's RSA public key (serving as an address).
+// It includes a value (in base units, see Amount) and a recipient's public key of any registered
+// SignatureScheme (serving as an address).
+// A multisig output lists MultiSigAddresses instead and can be spent with signatures of
+// exactly RequiredSigs distinct keys among them. An output with a LockScript is locked by
+// that script alone.
 type Output struct {
-	Value             float64
-	Address           *rsa.PublicKey
-	MultiSigAddresses []*rsa.PublicKey
+	Value             Amount
+	Address           PublicKey
+	MultiSigAddresses []PublicKey
+	RequiredSigs      int
+	LockScript        Script
 }
 
 // NewOutput creates a new Output with the specified value and recipient address.
-func NewOutput(value float64, address *rsa.PublicKey) *Output {
+func NewOutput(value Amount, address PublicKey) *Output {
 	return &Output{
 		Value:   value,
 		Address: address,
 	}
 }
 
-func NewMultiSigOutput(value float64, addresses []*rsa.PublicKey) *Output {
+// NewMultiSigOutput creates a multisig output that needs NEED_SIGN signatures,
+// or all of them when fewer addresses are listed.
+func NewMultiSigOutput(value Amount, addresses []PublicKey) *Output {
+	return NewMOfNMultiSigOutput(value, min(NEED_SIGN, len(addresses)), addresses)
+}
+
+// NewMOfNMultiSigOutput creates a multisig output that needs required of the listed addresses to sign.
+func NewMOfNMultiSigOutput(value Amount, required int, addresses []PublicKey) *Output {
 	return &Output{
 		Value:             value,
 		MultiSigAddresses: addresses,
+		RequiredSigs:      required,
+	}
+}
+
+// NewScriptOutput creates an output locked by a custom script.
+func NewScriptOutput(value Amount, script Script) *Output {
+	return &Output{
+		Value:      value,
+		LockScript: script,
+	}
+}
+
+// IsMultiSig reports whether the output is locked to a set of keys.
+func (out *Output) IsMultiSig() bool {
+	return len(out.LockScript) == 0 && len(out.MultiSigAddresses) > 0
+}
+
+// LockingScript returns the script that must succeed to spend the output: LockScript if set,
+// otherwise the standard multisig or pay-to-public-key script. It is nil for an output without
+// a recipient, which cannot be spent.
+func (out *Output) LockingScript() Script {
+	switch {
+	case len(out.LockScript) > 0:
+		return out.LockScript
+	case len(out.MultiSigAddresses) > 0:
+		return MultiSigScript(out.RequiredSigs, out.MultiSigAddresses)
+	case out.Address != nil:
+		return PayToPubKeyScript(out.Address)
 	}
+	return nil
 }
 
 // Equals checks if two outputs are identical by comparing both the value and the recipient's address.
@@ -112,23 +176,26 @@ func (out *Output) Equals(other *Output) bool {
 		return false
 	}
 
-	if out.Value != other.Value {
+	if out.Value != other.Value || out.RequiredSigs != other.RequiredSigs {
 		return false
 	}
-	if !out.Address.Equal(other.Address) {
+	if !keysEqual(out.Address, other.Address) {
 		return false
 	}
-	return true
+	return bytes.Equal(out.LockScript, other.LockScript)
 }
 
 // Transaction represents a blockchain transaction.
 // It contains a unique hash, a list of inputs, and a list of outputs.
+// A non-zero LockTime is the first block height (or, from LOCKTIME_THRESHOLD on, the first
+// median time past) at which the transaction can be included in a block.
 type Transaction struct {
 	Hash      []byte
 	Inputs    []*Input
 	Outputs   []*Output
 	Coinbase  bool
 	Timestamp int64
+	LockTime  uint32
 }
 
 // NewTransaction creates a new transaction with empty slices for inputs and outputs.
@@ -150,6 +217,7 @@ func NewTransactionFromTransaction(tx *Transaction) *Transaction {
 		Outputs:   make([]*Output, len(tx.Outputs)),
 		Coinbase:  false,
 		Timestamp: tx.Timestamp,
+		LockTime:  tx.LockTime,
 	}
 	copy(newTx.Hash, tx.Hash)
 
@@ -161,6 +229,8 @@ func NewTransactionFromTransaction(tx *Transaction) *Transaction {
 			OutputIndex:       in.OutputIndex,
 			Signature:         newSig,
 			MultiSigSignature: in.MultiSigSignature,
+			UnlockScript:      in.UnlockScript,
+			Sequence:          in.Sequence,
 		}
 	}
 
@@ -169,13 +239,15 @@ func NewTransactionFromTransaction(tx *Transaction) *Transaction {
 			Value:             op.Value,
 			Address:           op.Address,
 			MultiSigAddresses: op.MultiSigAddresses,
+			RequiredSigs:      op.RequiredSigs,
+			LockScript:        op.LockScript,
 		}
 	}
 
 	return newTx
 }
 
-func NewCoinbaseTransaction(coin float64, address *rsa.PublicKey) *Transaction {
+func NewCoinbaseTransaction(coin Amount, address PublicKey) *Transaction {
 	newTx := &Transaction{
 		Inputs:    make([]*Input, 0),
 		Outputs:   make([]*Output, 0),
@@ -198,12 +270,17 @@ func (tx *Transaction) AddInput(prevTxHash []byte, outputIndex int) {
 }
 
 // AddOutput appends a new output to the transaction with the given value and recipient address.
-func (tx *Transaction) AddOutput(value float64, address *rsa.PublicKey) {
+func (tx *Transaction) AddOutput(value Amount, address PublicKey) {
 	tx.Outputs = append(tx.Outputs, &Output{Value: value, Address: address})
 }
 
 func (tx *Transaction) AddMultisigOutput(multisig *Output) {
-	tx.Outputs = append(tx.Outputs, &Output{Value: multisig.Value, MultiSigAddresses: multisig.MultiSigAddresses})
+	tx.Outputs = append(tx.Outputs, &Output{Value: multisig.Value, MultiSigAddresses: multisig.MultiSigAddresses, RequiredSigs: multisig.RequiredSigs})
+}
+
+// AddScriptOutput appends a new output locked by the given script.
+func (tx *Transaction) AddScriptOutput(value Amount, script Script) {
+	tx.Outputs = append(tx.Outputs, NewScriptOutput(value, script))
 }
 
 // RemoveInput removes the input at the specified index, if the index is valid.
@@ -225,53 +302,90 @@ func (tx *Transaction) RemoveInputFromUTXO(ut UTXO) {
 	}
 }
 
-// GetDataToSign returns a byte slice containing the data needed for signing an input.
-// It includes the previous transaction hash, the output index of that input,
-// and the details of all outputs (value and recipient public key information).
+// GetDataToSign returns the data signed by a SIGHASH_ALL signature of an input.
 func (tx *Transaction) GetDataToSign(index int) []byte {
-	if index >= len(tx.Inputs) {
+	return tx.GetDataToSignWithHashType(index, SIGHASH_ALL)
+}
+
+// GetDataToSignWithHashType returns a byte slice containing the data needed for signing an input
+// with the given signature hash type. It includes the hash type, the timestamp and lock time and
+// the previous transaction hash, output index and sequence of that input. Without
+// SIGHASH_ANYONECANPAY it adds the outpoints of the other inputs, and their sequences under
+// SIGHASH_ALL. SIGHASH_ALL then covers the details of all outputs (value and recipient public key
+// information), SIGHASH_SINGLE only the output at the index of the input and SIGHASH_NONE none.
+// It returns nil if the input does not exist, or the output does not exist for SIGHASH_SINGLE.
+func (tx *Transaction) GetDataToSignWithHashType(index int, hashType SigHashType) []byte {
+	if index < 0 || index >= len(tx.Inputs) {
+		return nil
+	}
+	baseType := hashType.Base()
+	if baseType == SIGHASH_SINGLE && index >= len(tx.Outputs) {
 		return nil
 	}
 
 	in := tx.Inputs[index]
-	data := make([]byte, 0)
+	data := []byte{byte(hashType)}
 
 	timestampBytes := make([]byte, 8)
 	binary.BigEndian.PutUint64(timestampBytes, uint64(tx.Timestamp))
 	data = append(data, timestampBytes...)
+	data = binary.BigEndian.AppendUint32(data, tx.LockTime)
 
 	data = append(data, in.PrevTxHash...)
 	buf := make([]byte, 4)
 	binary.BigEndian.PutUint32(buf, uint32(in.OutputIndex))
 	data = append(data, buf...)
+	data = binary.BigEndian.AppendUint32(data, in.Sequence)
 
-	for _, op := range tx.Outputs {
-		valBuf := make([]byte, 8)
-		binary.BigEndian.PutUint64(valBuf, math.Float64bits(op.Value))
-		data = append(data, valBuf...)
-
-		if op.MultiSigAddresses != nil && len(op.MultiSigAddresses) > 0 {
-			data = append(data, byte(1))
-			countBuf := make([]byte, 4)
-			binary.BigEndian.PutUint32(countBuf, uint32(len(op.MultiSigAddresses)))
-			data = append(data, countBuf...)
-
-			for _, pubKey := range op.MultiSigAddresses {
-				expBuf := make([]byte, 4)
-				binary.BigEndian.PutUint32(expBuf, uint32(pubKey.E))
-				data = append(data, expBuf...)
-				data = append(data, pubKey.N.Bytes()...)
+	if !hashType.AnyoneCanPay() {
+		data = binary.BigEndian.AppendUint32(data, uint32(index))
+		for i, other := range tx.Inputs {
+			if i == index {
+				continue
+			}
+			data = appendBytes(data, other.PrevTxHash)
+			data = binary.BigEndian.AppendUint32(data, uint32(other.OutputIndex))
+			if baseType == SIGHASH_ALL {
+				data = binary.BigEndian.AppendUint32(data, other.Sequence)
 			}
-		} else if op.Address != nil {
-			expBuf := make([]byte, 4)
-			binary.BigEndian.PutUint32(expBuf, uint32(op.Address.E))
-			data = append(data, expBuf...)
-			data = append(data, op.Address.N.Bytes()...)
-		} else {
-			continue
 		}
 	}
 
+	switch baseType {
+	case SIGHASH_ALL:
+		data = binary.BigEndian.AppendUint32(data, uint32(len(tx.Outputs)))
+		for _, op := range tx.Outputs {
+			data = op.appendDataToSign(data)
+		}
+	case SIGHASH_SINGLE:
+		data = binary.BigEndian.AppendUint32(data, uint32(index))
+		data = tx.Outputs[index].appendDataToSign(data)
+	}
+
+	return data
+}
+
+// appendDataToSign appends the value of the output, its kind and what locks it: the
+// locking script, the multisig threshold and keys, or the single key.
+func (op *Output) appendDataToSign(data []byte) []byte {
+	data = binary.BigEndian.AppendUint64(data, uint64(op.Value))
+
+	if len(op.LockScript) > 0 {
+		data = append(data, OUTPUT_KIND_SCRIPT)
+		data = appendBytes(data, op.LockScript)
+	} else if len(op.MultiSigAddresses) > 0 {
+		data = append(data, OUTPUT_KIND_MULTISIG)
+		data = binary.BigEndian.AppendUint32(data, uint32(len(op.MultiSigAddresses)))
+		data = binary.BigEndian.AppendUint32(data, uint32(op.RequiredSigs))
+		for _, pubKey := range op.MultiSigAddresses {
+			data = appendBytes(data, marshalTaggedPublicKey(pubKey))
+		}
+	} else if op.Address != nil {
+		data = append(data, OUTPUT_KIND_KEY)
+		data = appendBytes(data, marshalTaggedPublicKey(op.Address))
+	} else {
+		data = append(data, OUTPUT_KIND_NONE)
+	}
 	return data
 }
 
@@ -282,53 +396,27 @@ func (tx *Transaction) AddSignature(signature []byte, index int) {
 	}
 }
 
-// GetTx aggregates all transaction data into a single byte slice.
-// It concatenates the data from all inputs and outputs, including signatures,
-// which is later used to compute the transaction hash.
+// GetTx aggregates all transaction data into a single byte slice, which is later used to
+// compute the transaction hash (its ID). Inputs and outputs are preceded by their counts and
+// every output by its kind, so no two transactions share the same data. Signatures and
+// unlocking scripts are witness data and left out, so signing a transaction or re-encoding
+// its signatures never changes the ID; GetWitnessTx covers them.
 func (tx *Transaction) GetTx() []byte {
-	// Add here timestamp
 	data := make([]byte, 0)
+	data = binary.BigEndian.AppendUint64(data, uint64(tx.Timestamp))
+	data = binary.BigEndian.AppendUint32(data, tx.LockTime)
 
-	timestampBytes := make([]byte, 8)
-	binary.BigEndian.PutUint64(timestampBytes, uint64(tx.Timestamp))
-	data = append(data, timestampBytes...)
-
+	data = binary.BigEndian.AppendUint32(data, uint32(len(tx.Inputs)))
 	for _, in := range tx.Inputs {
-		data = append(data, in.PrevTxHash...)
-		buf := make([]byte, 4)
-		binary.BigEndian.PutUint32(buf, uint32(in.OutputIndex))
-		data = append(data, buf...)
-		if in.Signature != nil {
-			data = append(data, in.Signature...)
-		}
+		data = appendBytes(data, in.PrevTxHash)
+		data = binary.BigEndian.AppendUint32(data, uint32(in.OutputIndex))
+		data = binary.BigEndian.AppendUint32(data, in.Sequence)
 	}
 
+	data = binary.BigEndian.AppendUint32(data, uint32(len(tx.Outputs)))
 	for _, op := range tx.Outputs {
-		valBuf := make([]byte, 8)
-		binary.BigEndian.PutUint64(valBuf, math.Float64bits(op.Value))
-		data = append(data, valBuf...)
-
-		if op.MultiSigAddresses != nil && len(op.MultiSigAddresses) > 0 {
-			countBuf := make([]byte, 4)
-			binary.BigEndian.PutUint32(countBuf, uint32(len(op.MultiSigAddresses)))
-			data = append(data, countBuf...)
-
-			for _, pubKey := range op.MultiSigAddresses {
-				expBuf := make([]byte, 4)
-				binary.BigEndian.PutUint32(expBuf, uint32((pubKey.E)))
-				data = append(data, expBuf...)
-				data = append(data, pubKey.N.Bytes()...)
-			}
-		} else if op.Address != nil {
-			expBuf := make([]byte, 4)
-			binary.BigEndian.PutUint32(expBuf, uint32(op.Address.E))
-			data = append(data, expBuf...)
-			data = append(data, op.Address.N.Bytes()...)
-		} else {
-			continue
-		}
+		data = op.appendDataToSign(data)
 	}
-
 	return data
 }
 
@@ -339,6 +427,28 @@ func (tx *Transaction) Finalize() {
 	tx.Hash = hash[:]
 }
 
+// GetWitnessTx returns GetTx followed by the witness data of every input: its signature,
+// multisig signatures and unlocking script.
+func (tx *Transaction) GetWitnessTx() []byte {
+	data := tx.GetTx()
+	for _, in := range tx.Inputs {
+		data = appendBytes(data, in.Signature)
+		data = binary.BigEndian.AppendUint32(data, uint32(len(in.MultiSigSignature)))
+		for _, sig := range in.MultiSigSignature {
+			data = appendBytes(data, sig)
+		}
+		data = appendBytes(data, in.UnlockScript)
+	}
+	return data
+}
+
+// WitnessHash returns the SHA-256 hash of GetWitnessTx. Unlike the ID it changes with
+// every signature, so blocks commit to it through their witness root.
+func (tx *Transaction) WitnessHash() []byte {
+	hash := sha256.Sum256(tx.GetWitnessTx())
+	return hash[:]
+}
+
 // SetHash assigns a given hash to the transaction after making a copy of it.
 func (tx *Transaction) SetHash(h []byte) {
 	tx.Hash = make([]byte, len(h))
@@ -395,24 +505,26 @@ func (transaction *Transaction) Key() string {
 	return hex.EncodeToString(transaction.Hash)
 }
 
-func (tx *Transaction) SignTx(sk *rsa.PrivateKey, input int) {
-	dataToSign := tx.GetDataToSign(input)
-	hashData1 := sha256.Sum256(dataToSign)
-	sig1, err := rsa.SignPKCS1v15(rand.Reader, sk, crypto.SHA256, hashData1[:])
-	if err != nil {
-		panic(err)
-	}
-	tx.AddSignature(sig1, input)
+// CreateSignature signs the data of the input with sk under SIGHASH_ALL without attaching the
+// signature, for use in custom unlocking scripts.
+func (tx *Transaction) CreateSignature(sk PrivateKey, input int) []byte {
+	return tx.CreateSignatureWithHashType(sk, input, SIGHASH_ALL)
+}
+
+func (tx *Transaction) SignTx(sk PrivateKey, input int) {
+	tx.AddSignature(tx.CreateSignature(sk, input), input)
 	tx.Finalize()
 }
 
-func (tx *Transaction) SignMultiSigTx(privKey *rsa.PrivateKey, inputIndex int) {
-	dataToSign := tx.GetDataToSign(inputIndex)
-	hashData1 := sha256.Sum256(dataToSign)
-	sig, err := rsa.SignPKCS1v15(rand.Reader, privKey, crypto.SHA256, hashData1[:])
-	if err != nil {
-		panic(err)
+func (tx *Transaction) SignMultiSigTx(privKey PrivateKey, inputIndex int) {
+	tx.SignMultiSigTxWithHashType(privKey, inputIndex, SIGHASH_ALL)
+}
+
+// SetUnlockScript sets the unlocking script of the input, e.g. built with NewScriptBuilder
+// from signatures returned by CreateSignature.
+func (tx *Transaction) SetUnlockScript(input int, script Script) {
+	if input >= 0 && input < len(tx.Inputs) {
+		tx.Inputs[input].UnlockScript = script
+		tx.Finalize()
 	}
-	tx.Inputs[inputIndex].AddMultiSignature(sig)
-	tx.Finalize()
 }
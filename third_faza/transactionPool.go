@@ -1,28 +1,111 @@
 package third_faza
 
 import (
+	"container/heap"
 	"encoding/hex"
+	"sort"
+	"time"
 )
 
+const (
+	// MAX_MEMPOOL_SIZE caps the total encoded size of the pooled transactions in bytes.
+	MAX_MEMPOOL_SIZE = 5000000
+	// MEMPOOL_EXPIRY is how many seconds a transaction may wait in the pool before it is dropped.
+	MEMPOOL_EXPIRY = 14 * 24 * 60 * 60
+)
+
+// MempoolEntry is a pooled transaction together with the fee it pays, its encoded size and
+// the UNIX time it entered the pool. It is linked to the pooled transactions whose outputs it
+// spends and to those spending its outputs.
+// AncestorFee and AncestorSize add up the entry and every pooled transaction it depends on,
+// DescendantFee and DescendantSize the entry and every pooled transaction depending on it.
+// The pool keeps them up to date as transactions come and go.
+type MempoolEntry struct {
+	Tx   *Transaction
+	Fee  Amount
+	Size int
+	Time int64
+
+	AncestorFee    Amount
+	AncestorSize   int
+	DescendantFee  Amount
+	DescendantSize int
+
+	key      string
+	parents  map[string]*MempoolEntry
+	children map[string]*MempoolEntry
+}
+
+// FeeRate returns the fee of the entry per 1000 bytes.
+func (entry *MempoolEntry) FeeRate() Amount {
+	return poolFeeRate(entry.Fee, entry.Size)
+}
+
+// AncestorFeeRate returns the fee rate of the entry together with its pooled ancestors.
+func (entry *MempoolEntry) AncestorFeeRate() Amount {
+	return poolFeeRate(entry.AncestorFee, entry.AncestorSize)
+}
+
+// DescendantFeeRate returns the fee rate of the entry together with its pooled descendants.
+func (entry *MempoolEntry) DescendantFeeRate() Amount {
+	return poolFeeRate(entry.DescendantFee, entry.DescendantSize)
+}
+
+// FeeRate returns fee per 1000 bytes of a transaction of the given size, and false if
+// the rate cannot be computed because fee * 1000 overflows an Amount.
+func FeeRate(fee Amount, size int) (Amount, bool) {
+	if size <= 0 {
+		return 0, true
+	}
+	scaled, ok := fee.MulInt(1000)
+	if !ok {
+		return 0, false
+	}
+	return scaled / Amount(size), true
+}
+
+// poolFeeRate is FeeRate for ranking pooled transactions. A rate that overflows counts as
+// zero, so such a transaction is never preferred and goes first on eviction.
+func poolFeeRate(fee Amount, size int) Amount {
+	rate, _ := FeeRate(fee, size)
+	return rate
+}
+
 // TransactionPool represents a pool of transactions, keyed by the hex-encoded transaction hash.
+// Transactions may spend outputs of other pooled transactions; such chains are kept together,
+// so removing a transaction for any reason other than its confirmation removes its descendants too.
+// Every output is spent by at most one pooled transaction; a conflicting transaction has to
+// replace the ones it conflicts with, see CanReplace.
+// The pool keeps at most MaxSize bytes of transactions, evicting those with the lowest fee rate
+// first, and drops transactions that waited longer than Expiry seconds.
 type TransactionPool struct {
-	H map[string]*Transaction
+	H       map[string]*MempoolEntry
+	MaxSize int
+	Expiry  int64
+	size    int
+	spends  map[string]*MempoolEntry
 }
 
-// NewTransactionPool creates a new empty TransactionPool.
+// NewTransactionPool creates a new empty TransactionPool with the default limits.
 func NewTransactionPool() *TransactionPool {
 	return &TransactionPool{
-		H: make(map[string]*Transaction),
+		H:       make(map[string]*MempoolEntry),
+		MaxSize: MAX_MEMPOOL_SIZE,
+		Expiry:  MEMPOOL_EXPIRY,
+		spends:  make(map[string]*MempoolEntry),
 	}
 }
 
 // NewTransactionPoolFromPool creates a new TransactionPool that is a copy of an existing one.
 func NewTransactionPoolFromPool(tp *TransactionPool) *TransactionPool {
 	newPool := &TransactionPool{
-		H: make(map[string]*Transaction),
+		H:       make(map[string]*MempoolEntry),
+		MaxSize: tp.MaxSize,
+		Expiry:  tp.Expiry,
+		spends:  make(map[string]*MempoolEntry),
 	}
-	for k, tx := range tp.H {
-		newPool.H[k] = tx
+	for _, entry := range tp.ordered(tp.H) {
+		newPool.insert(&MempoolEntry{Tx: entry.Tx, Fee: entry.Fee, Size: entry.Size, Time: entry.Time})
 	}
 	return newPool
 }
@@ -32,29 +115,504 @@ func keyFor(txHash []byte) string {
 	return hex.EncodeToString(wrapper.contents)
 }
 
-// AddTransaction adds the given transaction to the pool, using its hash as the key.
-func (tp *TransactionPool) AddTransaction(tx *Transaction) {
-	key := keyFor(tx.GetHash())
-	tp.H[key] = tx
+// AddTransaction adds the given transaction paying fee to the pool, using its hash as the key.
+// If the pool grows beyond MaxSize, the entries with the lowest fee rate are evicted together
+// with their descendants and returned; this can include the new transaction itself.
+// A transaction already in the pool is left as it is, and one whose fee rate overflows is
+// not added but returned as evicted. Transactions conflicting with tx have to be removed
+// before, see Conflicts.
+func (tp *TransactionPool) AddTransaction(tx *Transaction, fee Amount) []*Transaction {
+	if tp.GetEntry(tx.GetHash()) != nil {
+		return nil
+	}
+	if _, ok := FeeRate(fee, tx.Size()); !ok {
+		return []*Transaction{tx}
+	}
+	tp.insert(&MempoolEntry{Tx: tx, Fee: fee, Size: tx.Size(), Time: time.Now().Unix()})
+	return entryTransactions(tp.trim())
+}
+
+// Replace removes the pooled transactions in replaced and adds tx paying fee in their place.
+// It returns the transactions evicted to make room. If tx itself would be evicted, the pool is
+// left as it was and false is returned, so nothing is replaced by a transaction that does not stay.
+// A transaction whose fee rate overflows is rejected the same way.
+func (tp *TransactionPool) Replace(tx *Transaction, fee Amount, replaced []*Transaction) ([]*Transaction, bool) {
+	if tp.GetEntry(tx.GetHash()) != nil {
+		return nil, false
+	}
+	if _, ok := FeeRate(fee, tx.Size()); !ok {
+		return nil, false
+	}
+	removed := make([]*MempoolEntry, 0, len(replaced))
+	for _, conflict := range replaced {
+		if entry := tp.GetEntry(conflict.GetHash()); entry != nil {
+			removed = append(removed, entry)
+			tp.RemoveTransaction(conflict.GetHash())
+		}
+	}
+	entry := &MempoolEntry{Tx: tx, Fee: fee, Size: tx.Size(), Time: time.Now().Unix()}
+	tp.insert(entry)
+	evicted := tp.trim()
+	if tp.H[entry.key] == entry {
+		return entryTransactions(evicted), true
+	}
+
+	// insert links the entries again in any order, so the pool ends up as before.
+	for _, e := range append(evicted, removed...) {
+		if e != entry {
+			tp.insert(e)
+		}
+	}
+	return nil, false
+}
+
+// insert adds entry to the pool, links it to its pooled parents and children and updates the
+// ancestor and descendant totals.
+func (tp *TransactionPool) insert(entry *MempoolEntry) {
+	entry.key = keyFor(entry.Tx.GetHash())
+	entry.parents = make(map[string]*MempoolEntry)
+	entry.children = make(map[string]*MempoolEntry)
+	for _, in := range entry.Tx.Inputs {
+		tp.spends[NewUTXO(in.PrevTxHash, in.OutputIndex).Key()] = entry
+		if parent, ok := tp.H[keyFor(in.PrevTxHash)]; ok {
+			entry.parents[parent.key] = parent
+			parent.children[entry.key] = entry
+		}
+	}
+	// Children are only pooled already when a parent returns to the pool in a reorg.
+	for i := range entry.Tx.Outputs {
+		if child, ok := tp.spends[NewUTXO(entry.Tx.GetHash(), i).Key()]; ok {
+			child.parents[entry.key] = entry
+			entry.children[child.key] = child
+		}
+	}
+	tp.H[entry.key] = entry
+	tp.size += entry.Size
+
+	if len(entry.children) > 0 {
+		for _, ancestor := range tp.ancestorsOf(entry) {
+			tp.updateTotals(ancestor)
+		}
+		for _, descendant := range tp.descendantsOf(entry) {
+			tp.updateTotals(descendant)
+		}
+		return
+	}
+	entry.DescendantFee, entry.DescendantSize = entry.Fee, entry.Size
+	entry.AncestorFee, entry.AncestorSize = 0, 0
+	for _, ancestor := range tp.ancestorsOf(entry) {
+		entry.AncestorFee += ancestor.Fee
+		entry.AncestorSize += ancestor.Size
+		if ancestor != entry {
+			ancestor.DescendantFee += entry.Fee
+			ancestor.DescendantSize += entry.Size
+		}
+	}
+}
+
+// updateTotals recomputes the ancestor and descendant totals of entry from the graph.
+func (tp *TransactionPool) updateTotals(entry *MempoolEntry) {
+	entry.AncestorFee, entry.AncestorSize = 0, 0
+	for _, ancestor := range tp.ancestorsOf(entry) {
+		entry.AncestorFee += ancestor.Fee
+		entry.AncestorSize += ancestor.Size
+	}
+	entry.DescendantFee, entry.DescendantSize = 0, 0
+	for _, descendant := range tp.descendantsOf(entry) {
+		entry.DescendantFee += descendant.Fee
+		entry.DescendantSize += descendant.Size
+	}
+}
+
+// trim evicts the entries with the lowest fee rate until the pool fits into MaxSize.
+// An entry is rated by the better of its own fee rate and its descendant fee rate, so a parent
+// is kept as long as a child pays enough for both.
+func (tp *TransactionPool) trim() []*MempoolEntry {
+	evicted := make([]*MempoolEntry, 0)
+	for tp.size > tp.MaxSize {
+		var worst *MempoolEntry
+		for _, entry := range tp.H {
+			if worst == nil || evictsBefore(entry, worst) {
+				worst = entry
+			}
+		}
+		evicted = append(evicted, tp.removeWithDescendants(worst)...)
+	}
+	return evicted
+}
+
+// evictsBefore reports whether a is evicted before b: it has the lower eviction rate or, at
+// the same rate, arrived later.
+func evictsBefore(a, b *MempoolEntry) bool {
+	rateA, rateB := a.FeeRate(), b.FeeRate()
+	if rate := a.DescendantFeeRate(); rate > rateA {
+		rateA = rate
+	}
+	if rate := b.DescendantFeeRate(); rate > rateB {
+		rateB = rate
+	}
+	if rateA != rateB {
+		return rateA < rateB
+	}
+	if a.Time != b.Time {
+		return a.Time > b.Time
+	}
+	return a.key > b.key
+}
+
+// Expire removes the transactions that entered the pool more than Expiry seconds before now,
+// together with their descendants, and returns them.
+func (tp *TransactionPool) Expire(now int64) []*Transaction {
+	expired := make([]*Transaction, 0)
+	old := make(map[string]*MempoolEntry)
+	for key, entry := range tp.H {
+		if entry.Time+tp.Expiry < now {
+			old[key] = entry
+		}
+	}
+	if len(old) == 0 {
+		return expired
+	}
+	for _, entry := range tp.ordered(old) {
+		if tp.H[entry.key] == entry {
+			expired = append(expired, tp.RemoveTransactionWithDescendants(entry.Tx.GetHash())...)
+		}
+	}
+	return expired
 }
 
 // RemoveTransaction removes the transaction with the given hash from the pool.
+// Its descendants stay, which is what a confirmed transaction needs.
 func (tp *TransactionPool) RemoveTransaction(txHash []byte) {
 	key := keyFor(txHash)
+	entry, ok := tp.H[key]
+	if !ok {
+		return
+	}
+	for _, ancestor := range tp.ancestorsOf(entry) {
+		if ancestor != entry {
+			ancestor.DescendantFee -= entry.Fee
+			ancestor.DescendantSize -= entry.Size
+		}
+	}
+	for _, descendant := range tp.descendantsOf(entry) {
+		if descendant != entry {
+			descendant.AncestorFee -= entry.Fee
+			descendant.AncestorSize -= entry.Size
+		}
+	}
+
+	for _, in := range entry.Tx.Inputs {
+		utxoKey := NewUTXO(in.PrevTxHash, in.OutputIndex).Key()
+		if tp.spends[utxoKey] == entry {
+			delete(tp.spends, utxoKey)
+		}
+	}
+	for _, parent := range entry.parents {
+		delete(parent.children, key)
+	}
+	for _, child := range entry.children {
+		delete(child.parents, key)
+	}
+	tp.size -= entry.Size
 	delete(tp.H, key)
 }
 
+// RemoveTransactionWithDescendants removes the transaction with the given hash and every pooled
+// transaction depending on it, and returns them with parents before children.
+func (tp *TransactionPool) RemoveTransactionWithDescendants(txHash []byte) []*Transaction {
+	entry := tp.GetEntry(txHash)
+	if entry == nil {
+		return nil
+	}
+	return entryTransactions(tp.removeWithDescendants(entry))
+}
+
+// removeWithDescendants removes entry and its descendants, children first, and returns them
+// with parents before children.
+func (tp *TransactionPool) removeWithDescendants(entry *MempoolEntry) []*MempoolEntry {
+	removed := tp.ordered(tp.descendantsOf(entry))
+	for i := len(removed) - 1; i >= 0; i-- {
+		tp.RemoveTransaction(removed[i].Tx.GetHash())
+	}
+	return removed
+}
+
+// Conflicts returns the pooled transactions that spend an output tx spends, together with their
+// descendants, with parents before children. These are the transactions tx would replace.
+// A pooled transaction with the same ID as tx is not a conflict.
+func (tp *TransactionPool) Conflicts(tx *Transaction) []*Transaction {
+	key := keyFor(tx.GetHash())
+	conflicts := make(map[string]*MempoolEntry)
+	for _, in := range tx.Inputs {
+		spender, ok := tp.spends[NewUTXO(in.PrevTxHash, in.OutputIndex).Key()]
+		if !ok || spender.key == key {
+			continue
+		}
+		for k, entry := range tp.descendantsOf(spender) {
+			conflicts[k] = entry
+		}
+	}
+	return entryTransactions(tp.ordered(conflicts))
+}
+
+// CanReplace reports whether a transaction paying fee with the given size may replace the
+// pooled transactions in replaced. It has to pay strictly more than all of them together and
+// a strictly higher fee rate than each of them. Without conflicts there is nothing to outbid.
+// A fee rate that overflows never replaces anything.
+func (tp *TransactionPool) CanReplace(fee Amount, size int, replaced []*Transaction) bool {
+	rate, ok := FeeRate(fee, size)
+	if !ok {
+		return false
+	}
+	if len(replaced) == 0 {
+		return true
+	}
+	total := Amount(0)
+	for _, tx := range replaced {
+		entry := tp.GetEntry(tx.GetHash())
+		if entry == nil {
+			continue
+		}
+		if rate <= entry.FeeRate() {
+			return false
+		}
+		sum, err := SumAmounts(total, entry.Fee)
+		if err != nil {
+			return false
+		}
+		total = sum
+	}
+	return fee > total
+}
+
 // GetTransaction returns the transaction associated with the given hash, or nil if not found.
 func (tp *TransactionPool) GetTransaction(txHash []byte) *Transaction {
-	key := keyFor(txHash)
-	return tp.H[key]
+	if entry := tp.GetEntry(txHash); entry != nil {
+		return entry.Tx
+	}
+	return nil
 }
 
-// GetTransactions returns a slice containing all transactions in the pool.
+// GetEntry returns the pool entry of the transaction with the given hash, or nil if not found.
+func (tp *TransactionPool) GetEntry(txHash []byte) *MempoolEntry {
+	return tp.H[keyFor(txHash)]
+}
+
+// GetTransactions returns all transactions in the pool, parents before their children and
+// otherwise highest fee rate first.
 func (tp *TransactionPool) GetTransactions() []*Transaction {
-	txs := make([]*Transaction, 0, len(tp.H))
-	for _, tx := range tp.H {
-		txs = append(txs, tx)
+	return entryTransactions(tp.ordered(tp.H))
+}
+
+// Ancestors returns the pooled transactions the one with the given hash depends on, itself
+// included, with parents before children.
+func (tp *TransactionPool) Ancestors(txHash []byte) []*Transaction {
+	entry := tp.GetEntry(txHash)
+	if entry == nil {
+		return nil
+	}
+	return entryTransactions(tp.ordered(tp.ancestorsOf(entry)))
+}
+
+// Descendants returns the pooled transactions depending on the one with the given hash, itself
+// included, with parents before children.
+func (tp *TransactionPool) Descendants(txHash []byte) []*Transaction {
+	entry := tp.GetEntry(txHash)
+	if entry == nil {
+		return nil
+	}
+	return entryTransactions(tp.ordered(tp.descendantsOf(entry)))
+}
+
+// SelectPackages returns the transactions to put into a block of at most maxSize bytes, in an
+// order a block can contain them. Each transaction is selected together with its not yet
+// selected ancestors, best ancestor fee rate first, so a child paying a high fee pulls in a
+// parent paying little. Packages that do not fit are skipped.
+func (tp *TransactionPool) SelectPackages(maxSize int) []*Transaction {
+	// fees and sizes hold the ancestor totals without the ancestors selected so far.
+	fees := make(map[string]Amount, len(tp.H))
+	sizes := make(map[string]int, len(tp.H))
+	queue := make(packageQueue, 0, len(tp.H))
+	for key, entry := range tp.H {
+		fees[key], sizes[key] = entry.AncestorFee, entry.AncestorSize
+		queue = append(queue, &packageCandidate{entry: entry, fee: entry.AncestorFee, size: entry.AncestorSize})
+	}
+	heap.Init(&queue)
+
+	selected := make(map[string]*MempoolEntry)
+	skipped := make(map[string]bool)
+	txs := make([]*Transaction, 0)
+	size := 0
+	for queue.Len() > 0 {
+		candidate := heap.Pop(&queue).(*packageCandidate)
+		key := candidate.entry.key
+		if selected[key] != nil || skipped[key] || candidate.fee != fees[key] || candidate.size != sizes[key] {
+			continue
+		}
+		if size+candidate.size > maxSize {
+			skipped[key] = true
+			continue
+		}
+
+		for _, entry := range tp.ancestorPackage(candidate.entry, selected) {
+			selected[entry.key] = entry
+			txs = append(txs, entry.Tx)
+			for _, descendant := range tp.descendantsOf(entry) {
+				if selected[descendant.key] != nil || descendant == entry {
+					continue
+				}
+				fees[descendant.key] -= entry.Fee
+				sizes[descendant.key] -= entry.Size
+				heap.Push(&queue, &packageCandidate{entry: descendant, fee: fees[descendant.key], size: sizes[descendant.key]})
+			}
+		}
+		size += candidate.size
+	}
+	return txs
+}
+
+// Size returns the total encoded size of the pooled transactions in bytes.
+func (tp *TransactionPool) Size() int {
+	return tp.size
+}
+
+// ancestorsOf returns entry and every pooled transaction it depends on.
+func (tp *TransactionPool) ancestorsOf(entry *MempoolEntry) map[string]*MempoolEntry {
+	set := make(map[string]*MempoolEntry)
+	var visit func(e *MempoolEntry)
+	visit = func(e *MempoolEntry) {
+		if set[e.key] != nil {
+			return
+		}
+		set[e.key] = e
+		for _, parent := range e.parents {
+			visit(parent)
+		}
+	}
+	visit(entry)
+	return set
+}
+
+// descendantsOf returns entry and every pooled transaction depending on it.
+func (tp *TransactionPool) descendantsOf(entry *MempoolEntry) map[string]*MempoolEntry {
+	set := make(map[string]*MempoolEntry)
+	var visit func(e *MempoolEntry)
+	visit = func(e *MempoolEntry) {
+		if set[e.key] != nil {
+			return
+		}
+		set[e.key] = e
+		for _, child := range e.children {
+			visit(child)
+		}
+	}
+	visit(entry)
+	return set
+}
+
+// ancestorPackage returns entry and its ancestors that are not in exclude, parents first.
+func (tp *TransactionPool) ancestorPackage(entry *MempoolEntry, exclude map[string]*MempoolEntry) []*MempoolEntry {
+	pkg := make([]*MempoolEntry, 0)
+	visited := make(map[string]bool)
+	var visit func(e *MempoolEntry)
+	visit = func(e *MempoolEntry) {
+		if visited[e.key] || exclude[e.key] != nil {
+			return
+		}
+		visited[e.key] = true
+		for _, in := range e.Tx.Inputs {
+			if parent, ok := e.parents[keyFor(in.PrevTxHash)]; ok {
+				visit(parent)
+			}
+		}
+		pkg = append(pkg, e)
+	}
+	visit(entry)
+	return pkg
+}
+
+// ordered returns the entries of set with parents before their children and otherwise by
+// descending fee rate. Ties go to the transaction that arrived first and then to the lower
+// hash, so the order is deterministic.
+func (tp *TransactionPool) ordered(set map[string]*MempoolEntry) []*MempoolEntry {
+	entries := make([]*MempoolEntry, 0, len(set))
+	for _, entry := range set {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if rateA, rateB := a.FeeRate(), b.FeeRate(); rateA != rateB {
+			return rateA > rateB
+		}
+		if a.Time != b.Time {
+			return a.Time < b.Time
+		}
+		return a.key < b.key
+	})
+
+	order := make([]*MempoolEntry, 0, len(set))
+	visited := make(map[string]bool)
+	var visit func(e *MempoolEntry)
+	visit = func(e *MempoolEntry) {
+		if visited[e.key] {
+			return
+		}
+		visited[e.key] = true
+		for _, in := range e.Tx.Inputs {
+			if parent, ok := e.parents[keyFor(in.PrevTxHash)]; ok {
+				visit(parent)
+			}
+		}
+		if set[e.key] != nil {
+			order = append(order, e)
+		}
+	}
+	for _, entry := range entries {
+		visit(entry)
+	}
+	return order
+}
+
+// packageCandidate is an entry queued for SelectPackages with the ancestor totals it was
+// queued with; it is stale once they changed.
+type packageCandidate struct {
+	entry *MempoolEntry
+	fee   Amount
+	size  int
+}
+
+// packageQueue is a heap of candidates with the best ancestor fee rate on top.
+type packageQueue []*packageCandidate
+
+func (q packageQueue) Len() int { return len(q) }
+
+func (q packageQueue) Less(i, j int) bool {
+	a, b := q[i], q[j]
+	if rateA, rateB := poolFeeRate(a.fee, a.size), poolFeeRate(b.fee, b.size); rateA != rateB {
+		return rateA > rateB
+	}
+	if a.entry.Time != b.entry.Time {
+		return a.entry.Time < b.entry.Time
+	}
+	return a.entry.key < b.entry.key
+}
+
+func (q packageQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *packageQueue) Push(x any) { *q = append(*q, x.(*packageCandidate)) }
+
+func (q *packageQueue) Pop() any {
+	old := *q
+	candidate := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return candidate
+}
+
+func entryTransactions(entries []*MempoolEntry) []*Transaction {
+	txs := make([]*Transaction, len(entries))
+	for i, entry := range entries {
+		txs[i] = entry.Tx
 	}
 	return txs
 }
@@ -0,0 +1,136 @@
+package third_faza
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTxID_StableUnderSigning(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
+	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyBob := &privateKeyBob.PublicKey
+
+	privateKeyAlice, err := ECDSAScheme.GenerateKey()
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyAlice := PublicKeyOf(privateKeyAlice)
+
+	genesisBlock := NewBlock(nil, pubKeyBob)
+	genesisBlock.Finalizee()
+	localBlockchain := NewBlockchain(genesisBlock)
+	HandleBlocks(localBlockchain)
+
+	tx1 := NewTransaction()
+	tx1.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
+	tx1.AddOutput(3*COIN, pubKeyAlice)
+	tx1.Finalize()
+	unsignedID := tx1.GetHash()
+	unsignedWitness := tx1.WitnessHash()
+
+	// A child can reference the parent before the parent is signed.
+	tx2 := NewTransaction()
+	tx2.AddInput(unsignedID, 0)
+	tx2.AddOutput(3*COIN, pubKeyBob)
+
+	tx1.SignTx(privateKeyBob, 0)
+	assert.Equal(t, unsignedID, tx1.GetHash(), "Signing should not change the ID")
+	assert.NotEqual(t, unsignedWitness, tx1.WitnessHash(), "Signing should change the witness hash")
+
+	TxProcess(tx1)
+	block2 := BlockCreate(pubKeyBob)
+	assert.NotNil(t, block2)
+	assert.Equal(t, 2, len(block2.GetTransactions()))
+
+	// ECDSA signatures are randomized, so a second signature is a different valid encoding.
+	tx2.SignTx(privateKeyAlice, 0)
+	first := tx2.Inputs[0].Signature
+	malleated := NewTransactionFromTransaction(tx2)
+	malleated.SignTx(privateKeyAlice, 0)
+	assert.False(t, bytes.Equal(first, malleated.Inputs[0].Signature))
+	assert.Equal(t, tx2.GetHash(), malleated.GetHash(), "Re-encoding a signature should not change the ID")
+	assert.NotEqual(t, tx2.WitnessHash(), malleated.WitnessHash())
+	assert.True(t, TxIsValid(*tx2, localBlockchain.UTXOSet))
+	assert.True(t, TxIsValid(*malleated, localBlockchain.UTXOSet))
+
+	TxProcess(tx2)
+	TxProcess(malleated)
+	assert.Equal(t, 1, len(localBlockchain.GetTransactionPool().GetTransactions()), "Both encodings share one pool entry")
+}
+
+func TestTxID_BlockCommitsToWitnesses(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
+	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyBob := &privateKeyBob.PublicKey
+
+	privateKeyAlice, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyAlice := &privateKeyAlice.PublicKey
+
+	genesisBlock := NewBlock(nil, pubKeyBob)
+	genesisBlock.Finalizee()
+	localBlockchain := NewBlockchain(genesisBlock)
+	HandleBlocks(localBlockchain)
+
+	tx1 := NewTransaction()
+	tx1.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
+	tx1.AddOutput(3*COIN, pubKeyAlice)
+	tx1.SignTx(privateKeyBob, 0)
+
+	block := NewBlock(genesisBlock.GetHash(), pubKeyAlice)
+	block.TransactionAdd(tx1)
+	block.Finalizee()
+	merkleRoot := block.GetMerkleRoot()
+
+	// Swapping the signature keeps the Merkle root but not the witness root.
+	tx1.Inputs[0].Signature = tx1.CreateSignature(privateKeyAlice, 0)
+	assert.Equal(t, merkleRoot, block.ComputeMerkleRoot())
+	assert.NotEqual(t, block.GetWitnessRoot(), block.ComputeWitnessRoot())
+	assert.False(t, BlockProcess(block), "A block whose witnesses changed should be rejected")
+
+	tx1.SignTx(privateKeyBob, 0)
+	block.Finalizee()
+	assert.True(t, BlockProcess(block))
+}
+
+func TestTxID_PreimageIsUnambiguous(t *testing.T) {
+	// Without counts, an output worth 1<<32|2 and an input with index 1 and sequence 2
+	// would contribute the same bytes.
+	withOutput := NewTransaction()
+	withOutput.Outputs = append(withOutput.Outputs, &Output{Value: 1<<32 | 2})
+	withInput := NewTransaction()
+	withInput.Timestamp = withOutput.Timestamp
+	withInput.Inputs = append(withInput.Inputs, &Input{OutputIndex: 1, Sequence: 2})
+	withOutput.Finalize()
+	withInput.Finalize()
+	assert.NotEqual(t, withOutput.GetHash(), withInput.GetHash())
+
+	// The output kind is committed, so a script cannot pass for a key.
+	privateKey, err := GenerateKey()
+	if err != nil {
+		log.Fatal(err)
+	}
+	toKey := NewTransaction()
+	toKey.AddOutput(COIN, PublicKeyOf(privateKey))
+	toScript := NewTransaction()
+	toScript.Timestamp = toKey.Timestamp
+	toScript.Outputs = append(toScript.Outputs, &Output{Value: COIN, LockScript: Script(marshalTaggedPublicKey(PublicKeyOf(privateKey)))})
+	toKey.Finalize()
+	toScript.Finalize()
+	assert.NotEqual(t, toKey.GetHash(), toScript.GetHash())
+}
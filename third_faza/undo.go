@@ -0,0 +1,126 @@
+package third_faza
+
+// UndoEntry remembers an output that a block removed from the UTXO set.
+type UndoEntry struct {
+	UTXO  UTXO
+	Entry UTXOEntry
+}
+
+// BlockUndo holds what is needed to disconnect a block from the UTXO set:
+// the outputs that existed before the block and were spent (or overwritten) by it,
+// and the outputs the block created. Outputs both created and spent inside the
+// block never reach the set and are not recorded.
+type BlockUndo struct {
+	Spent   []UndoEntry
+	Created []UTXO
+}
+
+// utxoJournal tracks the state of every UTXO touched while a block is being connected,
+// so the net change can be turned into a BlockUndo at any point.
+type utxoJournal struct {
+	order  []UTXO
+	before map[string]*UTXOEntry
+}
+
+func newUTXOJournal() *utxoJournal {
+	return &utxoJournal{
+		order:  make([]UTXO, 0),
+		before: make(map[string]*UTXOEntry),
+	}
+}
+
+// touch records the state of utxo before its first change.
+func (journal *utxoJournal) touch(pool *UTXOPool, utxo UTXO) {
+	key := utxo.Key()
+	if _, seen := journal.before[key]; seen {
+		return
+	}
+	journal.order = append(journal.order, utxo)
+	journal.before[key] = pool.GetEntry(utxo)
+}
+
+// undo compares the recorded states with the current pool and returns the net change.
+func (journal *utxoJournal) undo(pool *UTXOPool) *BlockUndo {
+	undo := &BlockUndo{
+		Spent:   make([]UndoEntry, 0),
+		Created: make([]UTXO, 0),
+	}
+	for _, utxo := range journal.order {
+		before := journal.before[utxo.Key()]
+		after := pool.GetEntry(utxo)
+		if after != nil {
+			undo.Created = append(undo.Created, utxo)
+		}
+		if before != nil {
+			undo.Spent = append(undo.Spent, UndoEntry{UTXO: utxo, Entry: *before})
+		}
+	}
+	return undo
+}
+
+// applyTransaction spends the inputs of tx and adds its outputs to the pool
+// as created by the block at pool.Height+1 on top of a parent with median time pool.MedianTime.
+func applyTransaction(pool *UTXOPool, tx *Transaction, journal *utxoJournal) {
+	for _, input := range tx.GetInputs() {
+		utxo := UTXO{txHash: input.PrevTxHash, index: input.OutputIndex}
+		journal.touch(pool, utxo)
+		pool.RemoveUTXO(utxo)
+	}
+	for i, output := range tx.GetOutputs() {
+		utxo := UTXO{txHash: tx.GetHash(), index: i}
+		journal.touch(pool, utxo)
+		pool.PutEntry(utxo, UTXOEntry{Output: *output, Height: pool.Height + 1, Coinbase: tx.IsCoinbase(), Time: pool.MedianTime})
+	}
+}
+
+// ConnectBlock validates the transactions of block in order against pool and applies them
+// as the block at pool.Height+1.
+// On success the pool reflects the state after the block, the returned undo data reverts it
+// and the total fee paid by the non-coinbase transactions is returned.
+// On failure the pool is left unchanged and false is returned.
+func ConnectBlock(pool *UTXOPool, block *Block) (*BlockUndo, Amount, bool) {
+	journal := newUTXOJournal()
+	fees := Amount(0)
+	for _, tx := range block.GetTransactions() {
+		if !TxIsValid(*tx, pool) {
+			revertUndo(pool, journal.undo(pool))
+			return nil, 0, false
+		}
+		if !tx.IsCoinbase() {
+			sum, err := SumAmounts(fees, GetFee(tx, pool))
+			if err != nil {
+				revertUndo(pool, journal.undo(pool))
+				return nil, 0, false
+			}
+			fees = sum
+		}
+		applyTransaction(pool, tx, journal)
+	}
+	pool.Height++
+	return journal.undo(pool), fees, true
+}
+
+// reconnectBlock applies a block that has already been validated on the same branch.
+func reconnectBlock(pool *UTXOPool, block *Block) *BlockUndo {
+	journal := newUTXOJournal()
+	for _, tx := range block.GetTransactions() {
+		applyTransaction(pool, tx, journal)
+	}
+	pool.Height++
+	return journal.undo(pool)
+}
+
+// DisconnectBlock reverts the changes described by undo, moving the pool back to the parent block.
+func DisconnectBlock(pool *UTXOPool, undo *BlockUndo) {
+	revertUndo(pool, undo)
+	pool.Height--
+}
+
+func revertUndo(pool *UTXOPool, undo *BlockUndo) {
+	for _, utxo := range undo.Created {
+		pool.RemoveUTXO(utxo)
+	}
+	for _, entry := range undo.Spent {
+		pool.PutEntry(entry.UTXO, entry.Entry)
+	}
+}
@@ -0,0 +1,198 @@
+package third_faza
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"log"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUndo_DisconnectRestoresPool(t *testing.T) {
+	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyBob := &privateKeyBob.PublicKey
+
+	privateKeyAlice, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyAlice := &privateKeyAlice.PublicKey
+
+	genesisBlock := NewBlock(nil, pubKeyBob)
+	genesisBlock.Finalizee()
+
+	pool := NewUTXOPool()
+	pool.Put(*NewUTXO(genesisBlock.GetCoinbase().GetHash(), 0), *genesisBlock.GetCoinbase().GetOutput(0))
+	before := NewUTXOPoolWithPool(pool)
+
+	// tx2 spends an output created by tx1 in the same block.
+	tx1 := NewTransaction()
+	tx1.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
+	tx1.AddOutput(1*COIN, pubKeyBob)
+	tx1.AddOutput(2.125*COIN, pubKeyBob)
+	tx1.SignTx(privateKeyBob, 0)
+
+	tx2 := NewTransaction()
+	tx2.AddInput(tx1.GetHash(), 1)
+	tx2.AddOutput(2.125*COIN, pubKeyBob)
+	tx2.SignTx(privateKeyBob, 0)
+
+	block1 := NewBlock(genesisBlock.GetHash(), pubKeyAlice)
+	block1.TransactionAdd(tx1)
+	block1.TransactionAdd(tx2)
+	block1.Finalizee()
+
+	undo, fees, ok := ConnectBlock(pool, block1)
+	assert.True(t, ok)
+	assert.Equal(t, Amount(0), fees)
+	assert.Equal(t, 1, len(undo.Spent), "Only the genesis coinbase output existed before the block")
+	assert.Equal(t, 3, len(undo.Created), "Coinbase, tx1 output 0 and tx2 output 0 should remain")
+	assert.False(t, pool.Contains(*NewUTXO(tx1.GetHash(), 1)))
+
+	DisconnectBlock(pool, undo)
+	assert.Equal(t, before.H, pool.H, "Disconnecting should restore the previous pool")
+
+	invalid := NewBlock(genesisBlock.GetHash(), pubKeyAlice)
+	invalid.TransactionAdd(tx1)
+	invalid.TransactionAdd(tx1)
+	invalid.Finalizee()
+	_, _, ok = ConnectBlock(pool, invalid)
+	assert.False(t, ok, "Double spend inside a block should fail")
+	assert.Equal(t, before.H, pool.H, "Failed connect should leave the pool unchanged")
+}
+
+func TestUndo_SwitchingBranchesMovesTheUTXOSet(t *testing.T) {
+	setCoinbaseMaturity(t, 0)
+
+	privateKeyBob, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyBob := &privateKeyBob.PublicKey
+
+	privateKeyAlice, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKeyAlice := &privateKeyAlice.PublicKey
+
+	genesisBlock := NewBlock(nil, pubKeyBob)
+	genesisBlock.Finalizee()
+	localBlockchain := NewBlockchain(genesisBlock)
+	HandleBlocks(localBlockchain)
+
+	txA := NewTransaction()
+	txA.AddInput(genesisBlock.GetCoinbase().GetHash(), 0)
+	txA.AddOutput(3.125*COIN, pubKeyAlice)
+	txA.SignTx(privateKeyBob, 0)
+
+	blockA := NewBlock(genesisBlock.GetHash(), pubKeyAlice)
+	blockA.TransactionAdd(txA)
+	blockA.Finalizee()
+	assert.True(t, BlockProcess(blockA))
+
+	blockB := NewBlock(genesisBlock.GetHash(), pubKeyBob)
+	blockB.Finalizee()
+	assert.True(t, BlockProcess(blockB))
+	tieWinner := blockA
+	if bytes.Compare(blockB.GetHash(), blockA.GetHash()) < 0 {
+		tieWinner = blockB
+	}
+	assert.Equal(t, tieWinner.GetHash(), localBlockchain.GetBlockAtMaxHeight().GetHash(), "Equal heights go to the lower hash")
+	assert.Equal(t, tieWinner == blockA, localBlockchain.UTXOSet.Contains(*NewUTXO(txA.GetHash(), 0)))
+
+	blockB2 := NewBlock(blockB.GetHash(), pubKeyBob)
+	blockB2.Finalizee()
+	assert.True(t, BlockProcess(blockB2))
+
+	assert.Equal(t, blockB2.GetHash(), localBlockchain.GetBlockAtMaxHeight().GetHash())
+	assert.False(t, localBlockchain.UTXOSet.Contains(*NewUTXO(txA.GetHash(), 0)), "Branch A outputs should be disconnected")
+	assert.True(t, localBlockchain.UTXOSet.Contains(*NewUTXO(genesisBlock.GetCoinbase().GetHash(), 0)), "Genesis output is unspent on branch B")
+
+	poolA := localBlockchain.GetUTXOPoolAt(localBlockchain.Get(blockA.GetHash()))
+	assert.True(t, poolA.Contains(*NewUTXO(txA.GetHash(), 0)), "Snapshot of branch A should contain its outputs")
+	assert.False(t, poolA.Contains(*NewUTXO(blockB2.GetCoinbase().GetHash(), 0)))
+}
+
+const benchmarkUTXOCount = 10000
+
+// newBenchmarkChain creates a chain whose UTXO set holds benchmarkUTXOCount outputs.
+// The genesis block is dated far enough in the past for n blocks spaced TARGET_BLOCK_SPACING apart.
+func newBenchmarkChain(n int) (*Blockchain, *rsa.PublicKey) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubKey := &privateKey.PublicKey
+
+	genesisBlock := NewBlock(nil, pubKey)
+	genesisBlock.SetTimestamp(time.Now().Unix() - int64(n+1)*TARGET_BLOCK_SPACING)
+	genesisBlock.Finalizee()
+	localBlockchain := NewBlockchain(genesisBlock)
+	HandleBlocks(localBlockchain)
+
+	for i := 0; i < benchmarkUTXOCount; i++ {
+		hash := sha256.Sum256(binary.BigEndian.AppendUint32(nil, uint32(i)))
+		localBlockchain.UTXOSet.Put(*NewUTXO(hash[:], 0), Output{Value: 1, Address: pubKey})
+	}
+	return localBlockchain, pubKey
+}
+
+// benchmarkBlockAdd adds b.N blocks on top of the tip and reports the heap retained per block.
+// afterAdd runs after every accepted block.
+func benchmarkBlockAdd(b *testing.B, afterAdd func(localBlockchain *Blockchain, block *Block)) {
+	localBlockchain, pubKey := newBenchmarkChain(b.N)
+	genesisTime := localBlockchain.GetBlockAtMaxHeight().GetTimestamp()
+
+	var before runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		block := NewBlock(localBlockchain.GetBlockAtMaxHeight().GetHash(), pubKey)
+		block.SetTimestamp(genesisTime + int64(i+1)*TARGET_BLOCK_SPACING)
+		block.Finalizee()
+		b.StartTimer()
+
+		if !localBlockchain.BlockAdd(block) {
+			b.Fatal("block rejected")
+		}
+		afterAdd(localBlockchain, block)
+	}
+	b.StopTimer()
+
+	var after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	b.ReportMetric(float64(int64(after.HeapAlloc)-int64(before.HeapAlloc))/float64(b.N), "retained-B/block")
+	runtime.KeepAlive(localBlockchain)
+}
+
+// BenchmarkBlockAdd_UndoRecords measures BlockAdd with the single UTXO set and per-block undo data.
+func BenchmarkBlockAdd_UndoRecords(b *testing.B) {
+	benchmarkBlockAdd(b, func(*Blockchain, *Block) {})
+}
+
+// BenchmarkBlockAdd_PoolCopyPerNode reproduces the former design on top of BlockAdd: every block
+// copied its parent's pool three times (node copy, HandleTxs, Handler) and kept one copy in its node.
+func BenchmarkBlockAdd_PoolCopyPerNode(b *testing.B) {
+	retained := make([]*UTXOPool, 0)
+	benchmarkBlockAdd(b, func(localBlockchain *Blockchain, block *Block) {
+		HandleTxs(NewUTXOPoolWithPool(localBlockchain.UTXOSet))
+		Handler(block.GetTransactions())
+		retained = append(retained, UTXOPoolGet())
+	})
+	runtime.KeepAlive(retained)
+}
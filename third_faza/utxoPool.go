@@ -1,43 +1,86 @@
 package third_faza
 
 import (
-	"crypto/rsa"
 	"encoding/hex"
 	"errors"
 	"strconv"
 	"strings"
 )
 
-// UTXOPool represents a UTXO pool that maps individual UTXOs to their corresponding transaction outputs
+// UTXOEntry is a transaction output in the pool together with the height of the block
+// that created it and whether it was created by a coinbase transaction.
+// Time is the median time past of the parent of that block, from which time-based
+// relative locks count.
+type UTXOEntry struct {
+	Output
+	Height   uint
+	Coinbase bool
+	Time     int64
+}
+
+// UTXOPool represents a UTXO pool that maps individual UTXOs to their corresponding transaction outputs.
+// Height is the height of the last block applied to the pool; transactions validated against
+// the pool are treated as part of the block at Height+1. MedianTime is the median time past of
+// that last block, which time locks are checked against. It is kept up to date by the Blockchain,
+// ConnectBlock and DisconnectBlock leave it alone.
 type UTXOPool struct {
-	H map[string]Output
+	H          map[string]UTXOEntry
+	Height     uint
+	MedianTime int64
 }
 
 // NewUTXOPool creates a new empty UTXOPool.
 func NewUTXOPool() *UTXOPool {
-	return &UTXOPool{H: make(map[string]Output)}
+	return &UTXOPool{H: make(map[string]UTXOEntry)}
 }
 
 // NewUTXOPoolWithPool creates a new UTXOPool that is a copy of the provided pool.
 func NewUTXOPoolWithPool(pool *UTXOPool) *UTXOPool {
-	newPool := &UTXOPool{H: make(map[string]Output)}
+	newPool := &UTXOPool{H: make(map[string]UTXOEntry), Height: pool.Height, MedianTime: pool.MedianTime}
 	for k, v := range pool.H {
-		var multiSigCopy []*rsa.PublicKey
+		var multiSigCopy []PublicKey
 		if v.MultiSigAddresses != nil {
-			multiSigCopy = append([]*rsa.PublicKey(nil), v.MultiSigAddresses...)
+			multiSigCopy = append([]PublicKey(nil), v.MultiSigAddresses...)
 		}
-		newPool.H[k] = Output{
-			Value:             v.Value,
-			Address:           v.Address,
-			MultiSigAddresses: multiSigCopy,
+		newPool.H[k] = UTXOEntry{
+			Output: Output{
+				Value:             v.Value,
+				Address:           v.Address,
+				MultiSigAddresses: multiSigCopy,
+				RequiredSigs:      v.RequiredSigs,
+				LockScript:        v.LockScript,
+			},
+			Height:   v.Height,
+			Coinbase: v.Coinbase,
+			Time:     v.Time,
 		}
 	}
 	return newPool
 }
 
 // Put adds a mapping from UTXO utxo to the transaction output txOut in the pool.
+// The output is recorded as a non-coinbase output created at height 0.
 func (utxoPool *UTXOPool) Put(utxo UTXO, txOut Output) {
-	utxoPool.H[utxo.Key()] = txOut
+	utxoPool.H[utxo.Key()] = UTXOEntry{Output: txOut}
+}
+
+// PutEntry adds a mapping from UTXO utxo to entry in the pool.
+func (utxoPool *UTXOPool) PutEntry(utxo UTXO, entry UTXOEntry) {
+	utxoPool.H[utxo.Key()] = entry
+}
+
+// GetEntry returns the pool entry of UTXO utxo, or nil if the utxo is not in the pool.
+func (utxoPool *UTXOPool) GetEntry(utxo UTXO) *UTXOEntry {
+	if entry, exists := utxoPool.H[utxo.Key()]; exists {
+		return &entry
+	}
+	return nil
+}
+
+// IsMature reports whether entry may be spent by a transaction in the block at height.
+// Coinbase outputs have to be buried under CoinbaseMaturity blocks first.
+func (entry *UTXOEntry) IsMature(height uint) bool {
+	return !entry.Coinbase || height >= entry.Height+CoinbaseMaturity
 }
 
 // RemoveUTXO removes the UTXO utxo from the pool.
@@ -47,8 +90,8 @@ func (utxoPool *UTXOPool) RemoveUTXO(utxo UTXO) {
 
 // GetTxOutput returns the transaction output corresponding to UTXO utxo, or null if the utxo is not in the pool.
 func (utxoPool *UTXOPool) GetTxOutput(ut UTXO) *Output {
-	if txOut, exists := utxoPool.H[ut.Key()]; exists {
-		return &txOut
+	if entry, exists := utxoPool.H[ut.Key()]; exists {
+		return &entry.Output
 	}
 	return nil
 }
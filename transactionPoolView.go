@@ -34,12 +34,12 @@ func showTxDialog(tx *third_faza.Transaction, parent fyne.Window) {
 		outputsContainer.Add(widget.NewLabel("No outputs available."))
 	} else {
 		for i, output := range outputs {
-			// Format the address by showing only the first 6 characters.
-			addr := fmt.Sprintf("%v", output.Address)
-			if len(addr) > 6 {
-				addr = addr[:6]
+			// Show the address the output pays to, if it has a single owner.
+			if hash := output.PubKeyHash(); hash != nil {
+				outputsContainer.Add(widget.NewLabel(fmt.Sprintf("Output %d: Value: %s, To: %s", i, output.Value, third_faza.EncodeAddress(hash))))
+			} else {
+				outputsContainer.Add(widget.NewLabel(fmt.Sprintf("Output %d: Value: %s", i, output.Value)))
 			}
-			outputsContainer.Add(widget.NewLabel(fmt.Sprintf("Output %d: Value: %.2f", i, output.Value)))
 		}
 	}
 
@@ -50,6 +50,10 @@ func showTxDialog(tx *third_faza.Transaction, parent fyne.Window) {
 		widget.NewLabel("Outputs:"),
 		outputsContainer,
 	)
+	// Pool transactions also show what they pay for their place in a block.
+	if entry := blockchain.GetTransactionPool().GetEntry(tx.GetHash()); entry != nil {
+		content.Add(widget.NewLabel(fmt.Sprintf("Fee: %s, Size: %d bytes, Fee rate: %s per kB", entry.Fee, entry.Size, entry.FeeRate())))
+	}
 
 	dialog.ShowCustom("Transaction Details", "Close", content, parent)
 }